@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"github.com/subdialia/fiat-ramp-service/pkg/onramper"
+)
+
+// serverSettings holds the structured settings validateConfig parsed.
+type serverSettings struct {
+	endpointPaths         map[rmp.Endpoint]string
+	prefetchMode          string
+	partnerKeys           map[string]string
+	responseCase          onramper.JSONCase
+	kycMapping            onramper.KYCStatusMapping
+	defaultPaymentMethods onramper.DefaultPaymentMethods
+	amountPrecision       onramper.AmountPrecision
+}
+
+// validateConfig checks the server configuration and reports every missing or invalid
+// setting at once, so operators can fix them all before the next restart. The structured
+// settings it parses along the way are returned for the server to use.
+func validateConfig() (settings serverSettings, err error) {
+	var errs []error
+	required := func(key string) string {
+		value := viper.GetString(key)
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+		return value
+	}
+
+	if baseURL := required("ONRAMPER_BASE_URL"); baseURL != "" {
+		errs = append(errs, checkURL("ONRAMPER_BASE_URL", baseURL))
+	}
+	for _, fallback := range splitList(viper.GetString("ONRAMPER_FALLBACK_BASE_URLS")) {
+		errs = append(errs, checkURL("ONRAMPER_FALLBACK_BASE_URLS", fallback))
+	}
+	required("ONRAMPER_API_KEY")
+	required("ONRAMPER_WEBHOOK_SECRET")
+	for _, key := range []string{"API_PORT", "METRICS_PORT"} {
+		if port := required(key); port != "" {
+			errs = append(errs, checkPort(key, port))
+		}
+	}
+	if viper.GetBool("DB_ENABLED") {
+		if endpoint := required("HASURA_GRAPHQL_ENDPOINT"); endpoint != "" {
+			errs = append(errs, checkURL("HASURA_GRAPHQL_ENDPOINT", endpoint))
+		}
+		required("HASURA_GRAPHQL_ADMIN_SECRET")
+	}
+	// viper reads an unparsable duration as zero, which would silently disable the setting.
+	for _, key := range []string{
+		"ONRAMPER_QUOTES_TIMEOUT",
+		"ONRAMPER_FLIGHT_TIMEOUT",
+		"ONRAMPER_BREAKER_COOLDOWN",
+		"ONRAMPER_CURRENCY_REFRESH_INTERVAL",
+		"API_QUOTE_STREAM_INTERVAL",
+		"API_QUOTE_STREAM_MAX_DURATION",
+		"HASURA_GRAPHQL_TIMEOUT",
+	} {
+		if value := viper.GetString(key); value != "" {
+			if _, err := time.ParseDuration(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration such as 5s, got %q", key, value))
+			}
+		}
+	}
+	// Structured settings, each reported with the variable it came from.
+	parsed := func(key string, parseErr error) {
+		if parseErr != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", key, parseErr))
+		}
+	}
+	settings.endpointPaths, err = rmp.ParseEndpointPaths(viper.GetString("ONRAMPER_ENDPOINT_PATHS"))
+	parsed("ONRAMPER_ENDPOINT_PATHS", err)
+	settings.prefetchMode, err = parsePrefetchMode(viper.GetString("ONRAMPER_PREFETCH"))
+	parsed("ONRAMPER_PREFETCH", err)
+	settings.partnerKeys, err = rmp.ParsePartnerAPIKeys(viper.GetString("ONRAMPER_PARTNER_API_KEYS"))
+	parsed("ONRAMPER_PARTNER_API_KEYS", err)
+	settings.responseCase, err = onramper.ParseJSONCase(viper.GetString("API_RESPONSE_CASE"))
+	parsed("API_RESPONSE_CASE", err)
+	settings.kycMapping, err = onramper.ParseKYCStatusMapping(viper.GetString("KYC_STATUS_MAPPING"))
+	parsed("KYC_STATUS_MAPPING", err)
+	settings.defaultPaymentMethods, err = onramper.ParseDefaultPaymentMethods(viper.GetString("API_DEFAULT_PAYMENT_METHODS"))
+	parsed("API_DEFAULT_PAYMENT_METHODS", err)
+	settings.amountPrecision, err = onramper.ParseAmountPrecision(viper.GetString("API_AMOUNT_PRECISION"))
+	parsed("API_AMOUNT_PRECISION", err)
+	return settings, errors.Join(errs...)
+}
+
+// checkURL returns an error unless value is an absolute http or https URL.
+func checkURL(key, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s must be an http(s) URL, got %q", key, value)
+	}
+	return nil
+}
+
+// checkPort returns an error unless value is a TCP port number.
+func checkPort(key, value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%s must be a port number between 1 and 65535, got %q", key, value)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := map[string]interface{}{
+		"ONRAMPER_BASE_URL":           "https://api.onramper.com",
+		"ONRAMPER_API_KEY":            "pk_test",
+		"ONRAMPER_WEBHOOK_SECRET":     "secret",
+		"API_PORT":                    "9999",
+		"METRICS_PORT":                "8080",
+		"DB_ENABLED":                  true,
+		"HASURA_GRAPHQL_ENDPOINT":     "https://hasura.example.com/v1/graphql",
+		"HASURA_GRAPHQL_ADMIN_SECRET": "admin",
+	}
+	setConfig := func(t *testing.T, overrides map[string]interface{}) {
+		t.Helper()
+		t.Cleanup(viper.Reset)
+		for key, value := range valid {
+			viper.Set(key, value)
+		}
+		for key, value := range overrides {
+			viper.Set(key, value)
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		setConfig(t, map[string]interface{}{
+			"KYC_STATUS_MAPPING":   "completed=APPROVED",
+			"API_AMOUNT_PRECISION": "usd=2",
+		})
+		settings, err := validateConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "APPROVED", settings.kycMapping[models.TransactionStatusCompleted])
+		assert.Equal(t, 2, settings.amountPrecision["usd"])
+	})
+	t.Run("reports every problem", func(t *testing.T) {
+		setConfig(t, map[string]interface{}{
+			"ONRAMPER_BASE_URL":           "api.onramper.com",
+			"ONRAMPER_API_KEY":            "",
+			"API_PORT":                    "http",
+			"METRICS_PORT":                "70000",
+			"HASURA_GRAPHQL_ENDPOINT":     "://hasura",
+			"ONRAMPER_QUOTES_TIMEOUT":     "5",
+			"HASURA_GRAPHQL_TIMEOUT":      "10 seconds",
+			"ONRAMPER_FALLBACK_BASE_URLS": "https://api-eu.onramper.com,ftp://mirror",
+			"ONRAMPER_ENDPOINT_PATHS":     "nope",
+			"ONRAMPER_PARTNER_API_KEYS":   "partnerA",
+			"ONRAMPER_PREFETCH":           "sometimes",
+			"API_RESPONSE_CASE":           "kebab",
+			"KYC_STATUS_MAPPING":          "completed",
+			"API_DEFAULT_PAYMENT_METHODS": "ng",
+			"API_AMOUNT_PRECISION":        "usd=two",
+		})
+		_, err := validateConfig()
+		require.Error(t, err)
+		for _, expected := range []string{
+			`ONRAMPER_BASE_URL must be an http(s) URL, got "api.onramper.com"`,
+			`ONRAMPER_FALLBACK_BASE_URLS must be an http(s) URL, got "ftp://mirror"`,
+			"ONRAMPER_API_KEY is required",
+			`API_PORT must be a port number`,
+			`METRICS_PORT must be a port number`,
+			`HASURA_GRAPHQL_ENDPOINT must be an http(s) URL`,
+			`ONRAMPER_QUOTES_TIMEOUT must be a duration`,
+			`HASURA_GRAPHQL_TIMEOUT must be a duration`,
+			"invalid ONRAMPER_ENDPOINT_PATHS",
+			"invalid ONRAMPER_PARTNER_API_KEYS",
+			"invalid ONRAMPER_PREFETCH",
+			"invalid API_RESPONSE_CASE",
+			"invalid KYC_STATUS_MAPPING",
+			"invalid API_DEFAULT_PAYMENT_METHODS",
+			"invalid API_AMOUNT_PRECISION",
+		} {
+			assert.Contains(t, err.Error(), expected)
+		}
+		assert.NotContains(t, err.Error(), "ONRAMPER_WEBHOOK_SECRET")
+	})
+	t.Run("hasura not needed without database", func(t *testing.T) {
+		setConfig(t, map[string]interface{}{
+			"DB_ENABLED":                  false,
+			"HASURA_GRAPHQL_ENDPOINT":     "",
+			"HASURA_GRAPHQL_ADMIN_SECRET": "",
+		})
+		_, err := validateConfig()
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+)
+
+// Startup prefetch modes, set through ONRAMPER_PREFETCH.
+const (
+	prefetchOff      = "off"
+	prefetchWarn     = "warn"
+	prefetchRequired = "required"
+)
+
+// prefetchTimeout bounds how long startup waits on the prefetch calls.
+const prefetchTimeout = 10 * time.Second
+
+// parsePrefetchMode validates ONRAMPER_PREFETCH; an empty value disables prefetching.
+func parsePrefetchMode(value string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(value))
+	switch mode {
+	case "":
+		return prefetchOff, nil
+	case prefetchOff, prefetchWarn, prefetchRequired:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown prefetch mode %q (want off, warn or required)", value)
+	}
+}
+
+// prefetch concurrently fetches currencies and onramp metadata to warm caches and
+// surface bad credentials before the server accepts traffic. Failures are logged in
+// warn mode and returned in required mode.
+func prefetch(ctx context.Context, mode string, client rmp.OnRamperClient, logger *zap.Logger) error {
+	if mode == prefetchOff {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, prefetchTimeout)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		currencyErr error
+		metadataErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, currencyErr = client.GetCurrencies(ctx, "", "", "buy")
+	}()
+	go func() {
+		defer wg.Done()
+		_, metadataErr = client.GetOnrampMetadata(ctx, "buy")
+	}()
+	wg.Wait()
+
+	err := errors.Join(currencyErr, metadataErr)
+	if err == nil {
+		logger.Info("Startup prefetch succeeded")
+		return nil
+	}
+	if mode == prefetchRequired {
+		return fmt.Errorf("startup prefetch failed: %w", err)
+	}
+	logger.Warn("Startup prefetch failed", zap.Error(err))
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+)
+
+func TestPrefetch(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		status        int
+		expectErr     bool
+		expectedCalls int32
+	}{
+		{name: "off", mode: prefetchOff, status: http.StatusUnauthorized, expectedCalls: 0},
+		{name: "warn on failure", mode: prefetchWarn, status: http.StatusUnauthorized, expectedCalls: 2},
+		{name: "required on failure", mode: prefetchRequired, status: http.StatusUnauthorized, expectErr: true, expectedCalls: 2},
+		{name: "required on success", mode: prefetchRequired, status: http.StatusOK, expectedCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+				if strings.HasPrefix(r.URL.Path, "/supported/onramps") {
+					_, _ = w.Write([]byte(`{"message":[]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"message":{}}`))
+			}))
+			t.Cleanup(server.Close)
+			client := rmp.NewClient(server.URL, "bad-key", "secret", zap.NewNop())
+
+			err := prefetch(context.Background(), tt.mode, client, zap.NewNop())
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func TestParsePrefetchMode(t *testing.T) {
+	mode, err := parsePrefetchMode("")
+	require.NoError(t, err)
+	assert.Equal(t, prefetchOff, mode)
+
+	mode, err = parsePrefetchMode(" Required ")
+	require.NoError(t, err)
+	assert.Equal(t, prefetchRequired, mode)
+
+	_, err = parsePrefetchMode("always")
+	require.Error(t, err)
+}
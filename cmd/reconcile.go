@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"github.com/subdialia/fiat-ramp-service/pkg/onramper"
+)
+
+//nolint:gochecknoglobals // Command-line flags, conventionally global for Cobra.
+var (
+	reconcileOlderThan time.Duration
+	reconcileInterval  time.Duration
+)
+
+//nolint:gochecknoglobals // reconcileCmd is a Cobra subcommand.
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconcile transactions stuck in pending",
+	Long:  "Re-fetches pending transactions from Onramper and stores status changes missed by webhooks.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := zap.NewProduction()
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer func(l *zap.Logger) {
+			_ = l.Sync()
+		}(logger)
+		zap.ReplaceGlobals(logger)
+
+		required := map[string]string{}
+		for _, key := range []string{
+			"ONRAMPER_BASE_URL",
+			"ONRAMPER_API_KEY",
+			"ONRAMPER_WEBHOOK_SECRET",
+			"HASURA_GRAPHQL_ENDPOINT",
+			"HASURA_GRAPHQL_ADMIN_SECRET",
+		} {
+			value := viper.GetString(key)
+			if value == "" {
+				return fmt.Errorf("%s is required", key)
+			}
+			required[key] = value
+		}
+
+		graphQLClient := database.NewGraphQLClient(required["HASURA_GRAPHQL_ENDPOINT"], required["HASURA_GRAPHQL_ADMIN_SECRET"], logger)
+		client := rmp.NewClient(required["ONRAMPER_BASE_URL"], required["ONRAMPER_API_KEY"], required["ONRAMPER_WEBHOOK_SECRET"], logger)
+		onramperAPIClient, ok := client.(*rmp.Client)
+		if !ok {
+			return fmt.Errorf("internal error: failed to assert OnRamper client type: expected *rmp.Client, got %T", client)
+		}
+		// Transactions initiated for a partner are fetched with that partner's API key
+		partnerKeys, err := rmp.ParsePartnerAPIKeys(viper.GetString("ONRAMPER_PARTNER_API_KEYS"))
+		if err != nil {
+			return fmt.Errorf("invalid ONRAMPER_PARTNER_API_KEYS: %w", err)
+		}
+		registry := partnerRegistry(onramperAPIClient, partnerKeys)
+		manager := onramper.NewOnramperManager(onramperAPIClient, graphQLClient, logger, required["ONRAMPER_WEBHOOK_SECRET"], client)
+		onramper.WithClientRegistry(registry)(manager)
+		manager.PendingReconcileAge = reconcileOlderThan
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Run once, then keep going on the interval if one was given.
+		_, err = manager.ReconcilePendingTransactions(ctx)
+		if err != nil {
+			return fmt.Errorf("reconciliation failed: %w", err)
+		}
+		if reconcileInterval > 0 {
+			manager.RunReconciler(ctx, reconcileInterval)
+		}
+		return nil
+	},
+}
+
+//nolint:gochecknoinits // Cobra's recommended way to register subcommands.
+func init() {
+	reconcileCmd.Flags().DurationVar(&reconcileOlderThan, "older-than", onramper.DefaultPendingReconcileAge, "minimum age of a pending transaction before it is reconciled")
+	reconcileCmd.Flags().DurationVar(&reconcileInterval, "interval", 0, "repeat reconciliation on this interval (0 runs once)")
+	rootCmd.AddCommand(reconcileCmd)
+}
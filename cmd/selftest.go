@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+)
+
+// selfTestTimeout bounds how long startup waits on the self-test calls.
+const selfTestTimeout = 10 * time.Second
+
+// selfTest checks the API key of every client in registry, the default's and each
+// partner's, and Onramper's reachability before the server accepts traffic, enabled
+// through SELFTEST_ON_START. A rejected key (401 or 403) fails startup; any other failure
+// is logged, as Onramper may recover before the first request.
+func selfTest(ctx context.Context, registry *rmp.ClientRegistry, logger *zap.Logger) error {
+	clients := registry.Clients()
+	var errs []error
+	for _, partnerID := range slices.Sorted(maps.Keys(clients)) {
+		partnerLogger := logger
+		if partnerID != "" {
+			partnerLogger = logger.With(zap.String("partner_id", partnerID))
+		}
+		err := selfTestClient(ctx, clients[partnerID], partnerLogger)
+		if err != nil && partnerID != "" {
+			err = fmt.Errorf("partner %s: %w", partnerID, err)
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// selfTestClient runs the self-test checks against one client.
+func selfTestClient(ctx context.Context, client rmp.OnRamperClient, logger *zap.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	checks := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "currencies", run: func() error {
+			_, err := client.GetCurrencies(ctx, "", "", "buy")
+			return err
+		}},
+		{name: "quotes", run: func() error {
+			_, err := client.GetQuotes(ctx, "usd", "btc", &models.QuoteQueryParams{Amount: 100, Type: "buy"})
+			return err
+		}},
+	}
+	for _, check := range checks {
+		err := check.run()
+		if errors.Is(err, rmp.ErrUnauthorized) {
+			return fmt.Errorf("startup self-test failed: %w", err)
+		}
+		if err != nil {
+			logger.Warn("Self-test check failed", zap.String("check", check.name), zap.Error(err))
+			continue
+		}
+		logger.Info("Self-test check passed", zap.String("check", check.name))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+)
+
+func TestSelfTest(t *testing.T) {
+	newClient := func(t *testing.T, status int) *rmp.ClientRegistry {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			if strings.HasPrefix(r.URL.Path, "/quotes") {
+				_, _ = w.Write([]byte(`[{"ramp":"moonpay","payout":0.001}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"message":{}}`))
+		}))
+		t.Cleanup(server.Close)
+		return rmp.NewClientRegistry(rmp.NewClient(server.URL, "pk_test", "secret", zap.NewNop()))
+	}
+
+	t.Run("rejected key fails startup", func(t *testing.T) {
+		err := selfTest(context.Background(), newClient(t, http.StatusUnauthorized), zap.NewNop())
+		require.ErrorIs(t, err, rmp.ErrUnauthorized)
+	})
+
+	t.Run("forbidden key fails startup", func(t *testing.T) {
+		err := selfTest(context.Background(), newClient(t, http.StatusForbidden), zap.NewNop())
+		require.ErrorIs(t, err, rmp.ErrUnauthorized)
+	})
+
+	t.Run("unreachable upstream is only logged", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		err := selfTest(context.Background(), newClient(t, http.StatusBadGateway), zap.New(core))
+		require.NoError(t, err)
+		assert.Equal(t, 2, logs.FilterMessage("Self-test check failed").Len())
+	})
+
+	t.Run("healthy upstream passes", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		err := selfTest(context.Background(), newClient(t, http.StatusOK), zap.New(core))
+		require.NoError(t, err)
+		assert.Equal(t, 2, logs.FilterMessage("Self-test check passed").Len())
+	})
+
+	t.Run("rejected partner key fails startup", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "pk_revoked" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if strings.HasPrefix(r.URL.Path, "/quotes") {
+				_, _ = w.Write([]byte(`[{"ramp":"moonpay","payout":0.001}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"message":{}}`))
+		}))
+		t.Cleanup(server.Close)
+		client, ok := rmp.NewClient(server.URL, "pk_test", "secret", zap.NewNop()).(*rmp.Client)
+		require.True(t, ok)
+		registry := rmp.NewClientRegistry(client)
+		registry.Register("partnerA", client.WithAPIKey("pk_partner"))
+		registry.Register("partnerB", client.WithAPIKey("pk_revoked"))
+
+		core, logs := observer.New(zapcore.InfoLevel)
+		err := selfTest(context.Background(), registry, zap.New(core))
+		require.ErrorIs(t, err, rmp.ErrUnauthorized)
+		assert.Contains(t, err.Error(), "partner partnerB")
+		assert.NotContains(t, err.Error(), "partnerA")
+		assert.Equal(t, 4, logs.FilterMessage("Self-test check passed").Len(), "the default and partnerA keys pass")
+	})
+}
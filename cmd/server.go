@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -50,60 +51,21 @@ var rootCmd = &cobra.Command{
 
 		logger.Info("Starting fiat-ramp-service API server")
 
-		// Load Config from environment variables
-		baseURL := viper.GetString("ONRAMPER_BASE_URL")
-		if baseURL == "" {
-			logger.Fatal("ONRAMPER_BASE_URL is required")
+		// Load Config from environment variables, reporting every problem at once
+		settings, err := validateConfig()
+		if err != nil {
+			logger.Fatal("Invalid configuration", zap.Error(err))
 		}
-
+		baseURL := viper.GetString("ONRAMPER_BASE_URL")
 		apiKey := viper.GetString("ONRAMPER_API_KEY")
-		if apiKey == "" {
-			logger.Fatal("ONRAMPER_API_KEY is required")
-		}
-
 		webhookSecret := viper.GetString("ONRAMPER_WEBHOOK_SECRET")
-		if webhookSecret == "" {
-			logger.Fatal("ONRAMPER_WEBHOOK_SECRET is required")
-		}
-
 		apiPort := viper.GetString("API_PORT")
-		if apiPort == "" {
-			logger.Fatal("API_PORT is required")
-		}
-
 		metricsPort := viper.GetString("METRICS_PORT")
-		if metricsPort == "" {
-			logger.Fatal("METRICS_PORT is required")
-		}
-		// Initialize Hasura GraphQL Client
-		hasuraEndpoint := viper.GetString("HASURA_GRAPHQL_ENDPOINT")
-		if hasuraEndpoint == "" {
-			logger.Fatal("HASURA_ENDPOINT is required")
-		}
-		// Initialize Hasura GraphQL Client
-		hasuraSecret := viper.GetString("HASURA_GRAPHQL_ADMIN_SECRET")
-		if hasuraSecret == "" {
-			logger.Fatal("HASURA_GRAPHQL_ADMIN_SECRET is required")
-		}
-		graphQLClient := database.NewGraphQLClient(hasuraEndpoint, hasuraSecret, logger)
-
-		// Test Hasura Client with a Simple Query
-		testQuery := `
-			query TestHasuraAccess {
-				__typename
-			}
-		`
-		var result struct {
-			Data struct {
-				Typename string `json:"__typename"`
-			} `json:"data"`
-		}
 
-		err = graphQLClient.ExecuteQuery(context.Background(), testQuery, nil, &result)
+		// Initialize Hasura GraphQL Client, unless this is a read-only deployment
+		graphQLClient, err := newDatabaseClient(context.Background(), logger)
 		if err != nil {
-			logger.Error("Failed to execute Hasura query", zap.Error(err))
-		} else {
-			logger.Info("Hasura query executed successfully", zap.String("__typename", result.Data.Typename))
+			return err
 		}
 
 		// Initialize Onramper Client
@@ -113,19 +75,83 @@ var rootCmd = &cobra.Command{
 		if !ok {
 			return fmt.Errorf("internal error: failed to assert OnRamper client type: expected *rmp.Client, got %T", client)
 		}
-		// Setup router (Pass webhookSecret)
-		router, err := onramper.SetupRouter(onramperAPIClient, graphQLClient, webhookSecret)
+		// Optional icon proxy host
+		onramperAPIClient.IconBaseURL = viper.GetString("ONRAMPER_ICON_BASE_URL")
+
+		// Optional cap on upstream response bodies (bytes)
+		onramperAPIClient.MaxResponseBytes = viper.GetInt64("ONRAMPER_MAX_RESPONSE_BYTES")
+
+		// Optional fallback base URLs and retries
+		onramperAPIClient.BaseURLs = append([]string{baseURL}, splitList(viper.GetString("ONRAMPER_FALLBACK_BASE_URLS"))...)
+		onramperAPIClient.MaxRetries = viper.GetInt("ONRAMPER_MAX_RETRIES")
+
+		// Optional deadline for quote requests, shorter than other calls
+		onramperAPIClient.QuotesTimeout = viper.GetDuration("ONRAMPER_QUOTES_TIMEOUT")
+
+		// Optional cap on a GET shared by concurrent identical requests
+		onramperAPIClient.FlightTimeout = viper.GetDuration("ONRAMPER_FLIGHT_TIMEOUT")
+
+		// Optional check that quote fiat and crypto are not swapped
+		onramperAPIClient.ValidateQuoteAssets = viper.GetBool("ONRAMPER_VALIDATE_QUOTE_ASSETS")
+
+		// Optional per-endpoint circuit breakers
+		onramperAPIClient.BreakerThreshold = viper.GetInt("ONRAMPER_BREAKER_THRESHOLD")
+		onramperAPIClient.BreakerCooldown = viper.GetDuration("ONRAMPER_BREAKER_COOLDOWN")
+
+		// Optional default locale for currency, payment method and onramp names
+		onramperAPIClient.Locale = viper.GetString("ONRAMPER_LOCALE")
+
+		// Optional HTTP/1.1-only transport for proxies that mishandle HTTP/2
+		if viper.GetBool("ONRAMPER_DISABLE_HTTP2") {
+			onramperAPIClient.DisableHTTP2()
+		}
+
+		// Optional endpoint path overrides
+		onramperAPIClient.Paths = settings.endpointPaths
+
+		// Optional in-memory currencies and onramp metadata, refreshed in the background;
+		// started once the client is fully configured because the refresher keeps its own copy
+		onramperAPIClient.StartCurrencyRefresher(viper.GetDuration("ONRAMPER_CURRENCY_REFRESH_INTERVAL"))
+
+		// Optional startup prefetch (off, warn or required)
+		err = prefetch(context.Background(), settings.prefetchMode, onramperAPIClient, logger)
+		if err != nil {
+			return err
+		}
+
+		// Optional per-partner API keys for multi-tenant deployments
+		registry := partnerRegistry(onramperAPIClient, settings.partnerKeys)
+
+		// Optional self-test that fails startup when Onramper rejects any of the API keys
+		if viper.GetBool("SELFTEST_ON_START") {
+			err = selfTest(context.Background(), registry, logger)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Setup router (Pass webhookSecret) with the optional response key casing, KYC
+		// status mapping, default payment methods and amount rounding
+		router, err := onramper.SetupRouter(onramperAPIClient, graphQLClient, webhookSecret,
+			onramper.WithResponseCase(settings.responseCase),
+			onramper.WithUnwrappedMessages(viper.GetBool("API_UNWRAP_MESSAGE")),
+			onramper.WithOperatorToken(viper.GetString("OPERATOR_TOKEN")),
+			onramper.WithMaxListLimit(viper.GetInt("API_MAX_LIST_LIMIT")),
+			onramper.WithQuoteStreamInterval(viper.GetDuration("API_QUOTE_STREAM_INTERVAL")),
+			onramper.WithQuoteStreamMaxDuration(viper.GetDuration("API_QUOTE_STREAM_MAX_DURATION")),
+			onramper.WithKYCStatusMapping(settings.kycMapping, viper.GetBool("KYC_SKIP_UNMAPPED_STATUSES")),
+			onramper.WithClientRegistry(registry),
+			onramper.WithDefaultPaymentMethods(settings.defaultPaymentMethods),
+			onramper.WithAmountPrecision(settings.amountPrecision),
+			onramper.WithWebhookPaths(splitList(viper.GetString("API_WEBHOOK_PATHS"))...),
+			onramper.WithTrustedProxies(splitList(viper.GetString("API_TRUSTED_PROXIES"))...),
+			onramper.WithPartnerRateLimit(viper.GetFloat64("API_PARTNER_RATE_LIMIT"), viper.GetInt("API_PARTNER_RATE_BURST")))
 		if err != nil { // This checks the error from SetupRouter
 			return fmt.Errorf("failed to setup router: %w", err)
 		}
 
 		// Start the API server on port 9999
-		apiServer := &http.Server{
-			Addr:         ":" + apiPort,
-			Handler:      router,
-			ReadTimeout:  15 * time.Second,
-			WriteTimeout: 15 * time.Second,
-		}
+		apiServer := newAPIServer(apiPort, router)
 
 		// Start the Metrics server on port 8080
 		metricsServer := &http.Server{
@@ -171,6 +197,12 @@ var rootCmd = &cobra.Command{
 			logger.Error("Failed to shut down API server properly", zap.Error(err))
 		}
 
+		// Cancel any Onramper calls still in flight
+		err = onramperAPIClient.Close()
+		if err != nil {
+			logger.Error("Failed to close Onramper client", zap.Error(err))
+		}
+
 		// Shutdown Metric Server
 		err = metricsServer.Shutdown(ctx)
 		if err != nil {
@@ -197,9 +229,84 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "logging level (debug, info, warn, error, dpanic, panic, fatal)")
 }
 
+// newDatabaseClient connects to Hasura, or returns a nil client when DB_ENABLED is false
+// so the service can run as a read-only proxy.
+func newDatabaseClient(ctx context.Context, logger *zap.Logger) (*database.GraphQLClient, error) {
+	if !viper.GetBool("DB_ENABLED") {
+		logger.Warn("Database disabled; write endpoints will return 501")
+		return nil, nil
+	}
+	hasuraEndpoint := viper.GetString("HASURA_GRAPHQL_ENDPOINT")
+	if hasuraEndpoint == "" {
+		return nil, errors.New("HASURA_GRAPHQL_ENDPOINT is required when DB_ENABLED is true")
+	}
+	hasuraSecret := viper.GetString("HASURA_GRAPHQL_ADMIN_SECRET")
+	if hasuraSecret == "" {
+		return nil, errors.New("HASURA_GRAPHQL_ADMIN_SECRET is required when DB_ENABLED is true")
+	}
+	graphQLClient := database.NewGraphQLClient(hasuraEndpoint, hasuraSecret, logger)
+	if timeout := viper.GetDuration("HASURA_GRAPHQL_TIMEOUT"); timeout > 0 {
+		graphQLClient.Timeout = timeout
+	}
+
+	// Test Hasura Client with a Simple Query
+	testQuery := `
+		query TestHasuraAccess {
+			__typename
+		}
+	`
+	var result struct {
+		Data struct {
+			Typename string `json:"__typename"`
+		} `json:"data"`
+	}
+
+	err := graphQLClient.ExecuteQuery(ctx, testQuery, nil, &result)
+	if err != nil {
+		logger.Error("Failed to execute Hasura query", zap.Error(err))
+	} else {
+		logger.Info("Hasura query executed successfully", zap.String("__typename", result.Data.Typename))
+	}
+	return graphQLClient, nil
+}
+
+// partnerRegistry returns a registry falling back to client, with a copy of client for
+// each partner's API key.
+func partnerRegistry(client *rmp.Client, partnerKeys map[string]string) *rmp.ClientRegistry {
+	registry := rmp.NewClientRegistry(client)
+	for partnerID, partnerKey := range partnerKeys {
+		registry.Register(partnerID, client.WithAPIKey(partnerKey))
+	}
+	return registry
+}
+
+// newAPIServer returns the API server listening on port. Quote streams and transaction
+// exports extend their own write deadline, so WriteTimeout only bounds ordinary responses.
+func newAPIServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + port,
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+}
+
+// splitList splits a comma-separated config value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func initConfig() {
 	viper.SetConfigFile(cfgFile)
 	viper.AutomaticEnv()
+	viper.SetDefault("DB_ENABLED", true)
 
 	if err := viper.ReadInConfig(); err == nil {
 		_, _ = fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"github.com/subdialia/fiat-ramp-service/pkg/onramper"
+	"go.uber.org/zap"
+)
+
+func TestNewDatabaseClient(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		t.Cleanup(viper.Reset)
+		viper.Set("DB_ENABLED", false)
+
+		client, err := newDatabaseClient(context.Background(), zap.NewNop())
+		require.NoError(t, err)
+		assert.Nil(t, client)
+	})
+	t.Run("enabled without hasura config", func(t *testing.T) {
+		t.Cleanup(viper.Reset)
+		viper.Set("DB_ENABLED", true)
+
+		_, err := newDatabaseClient(context.Background(), zap.NewNop())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "HASURA_GRAPHQL_ENDPOINT")
+	})
+}
+
+func TestAPIServerKeepsQuoteStreamOpen(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams past the API server's write timeout")
+	}
+	gin.SetMode(gin.TestMode)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"ramp":"moonpay","payout":0.0015}]`))
+	}))
+	defer upstream.Close()
+	client, ok := rmp.NewClient(upstream.URL, "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+	router, err := onramper.SetupRouter(client, nil, "test-secret", onramper.WithQuoteStreamInterval(2*time.Second))
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config = newAPIServer("0", router)
+	server.Start()
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/quotes/USD/BTC/stream?amount=100")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	start := time.Now()
+	events := 0
+	for time.Since(start) <= server.Config.WriteTimeout+2*time.Second {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "stream closed after %s and %d events", time.Since(start), events)
+		if line == "event:quotes\n" {
+			events++
+		}
+	}
+	assert.Greater(t, events, 8)
+}
@@ -7,16 +7,35 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/hasura/go-graphql-client"
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
 	"go.uber.org/zap"
 )
 
+// ErrTransactionNotFound is returned when no stored transaction matches a lookup.
+var ErrTransactionNotFound = errors.New("no transaction found")
+
+// ErrAlreadyReconciled is returned when a transaction was marked reconciled before.
+var ErrAlreadyReconciled = errors.New("transaction already reconciled")
+
+// ErrAlreadySuperseded is returned when a transaction was superseded before.
+var ErrAlreadySuperseded = errors.New("transaction already superseded")
+
+// ErrWebhookAckNotFound is returned when no ack is stored for a webhook event.
+var ErrWebhookAckNotFound = errors.New("no webhook ack found")
+
+// DefaultTimeout bounds each GraphQL call made by a client from NewGraphQLClient.
+const DefaultTimeout = 10 * time.Second
+
 // GraphQLClient represents a client for database operations.
 type GraphQLClient struct {
 	client *graphql.Client
 	logger *zap.Logger
+	// Timeout bounds each call on top of its context's deadline, so a hung Hasura does
+	// not hold a request or webhook forever. Zero leaves calls bounded by their context only.
+	Timeout time.Duration
 }
 
 // NewGraphQLClient creates a new GraphQL client with the provided endpoint and admin secret.
@@ -31,8 +50,9 @@ func NewGraphQLClient(endpoint, adminSecret string, logger *zap.Logger) *GraphQL
 	// Initialize the GraphQL client with the custom HTTP client.
 	client := graphql.NewClient(endpoint, httpClient)
 	return &GraphQLClient{
-		client: client,
-		logger: logger,
+		client:  client,
+		logger:  logger,
+		Timeout: DefaultTimeout,
 	}
 }
 
@@ -47,13 +67,32 @@ func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return http.DefaultTransport.RoundTrip(req)
 }
 
+// withTimeout applies the client's Timeout to ctx.
+func (c *GraphQLClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+// execRaw runs query within the client's Timeout and returns the raw data.
+func (c *GraphQLClient) execRaw(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.client.ExecRaw(ctx, query, variables)
+}
+
 // ExecuteQuery executes a GraphQL query and returns the result.
 func (c *GraphQLClient) ExecuteQuery(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 	return c.client.Query(ctx, result, variables)
 }
 
 // ExecuteMutation executes a GraphQL mutation and returns the result.
 func (c *GraphQLClient) ExecuteMutation(ctx context.Context, mutation string, variables map[string]interface{}, result interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 	return c.client.Exec(ctx, mutation, result, variables)
 }
 
@@ -62,45 +101,88 @@ func (c *GraphQLClient) UpsertOnramperTransaction(
 	onrampTx *models.WebhookPayload,
 	userID string,
 ) (updatedUserID string, err error) {
+	object := map[string]interface{}{
+		"user_id":               userID,
+		"country":               onrampTx.Country,
+		"in_amount":             onrampTx.InAmount,
+		"out_amount":            onrampTx.OutAmount,
+		"onramp":                onrampTx.Onramp,
+		"payment_method":        onrampTx.PaymentMethod,
+		"source_currency":       onrampTx.SourceCurrency,
+		"target_currency":       onrampTx.TargetCurrency,
+		"transaction_type":      strings.ToUpper(onrampTx.TransactionType),
+		"transaction_status":    onrampTx.Status,
+		"transaction_hash":      onrampTx.TransactionHash,
+		"partner_context":       onrampTx.PartnerContext,
+		"wallet_address":        onrampTx.WalletAddress,
+		"onramp_transaction_id": onrampTx.OnrampTransactionID,
+		"transaction_id":        onrampTx.TransactionID,
+	}
+	updateColumns := []string{
+		"country",
+		"in_amount",
+		"out_amount",
+		"source_currency",
+		"target_currency",
+		"transaction_status",
+		"transaction_type",
+	}
+	// Identifiers often arrive after the first webhook (e.g. the hash once the transfer is
+	// broadcast), the partner context is only sent by some callers, and the onramp and
+	// payment method stored at initiation are missing from some webhooks; a status-only
+	// update must not clear a stored value.
+	for _, optional := range []struct {
+		column string
+		value  string
+	}{
+		{"transaction_hash", onrampTx.TransactionHash},
+		{"wallet_address", onrampTx.WalletAddress},
+		{"onramp_transaction_id", onrampTx.OnrampTransactionID},
+		{"partner_context", onrampTx.PartnerContext},
+		{"onramp", onrampTx.Onramp},
+		{"payment_method", onrampTx.PaymentMethod},
+	} {
+		if optional.value != "" {
+			updateColumns = append(updateColumns, optional.column)
+		}
+	}
+	// Only overwrite the stored raw payload when this update carries one.
+	if len(onrampTx.RawPayload) > 0 {
+		object["raw_payload"] = onrampTx.RawPayload
+		updateColumns = append(updateColumns, "raw_payload")
+	}
+	// Checkout details are only known at initiation; webhooks must not clear them.
+	if onrampTx.RedirectURL != "" {
+		object["redirect_url"] = onrampTx.RedirectURL
+		updateColumns = append(updateColumns, "redirect_url")
+	}
+	if onrampTx.SessionExpiresAt != nil {
+		object["session_expires_at"] = onrampTx.SessionExpiresAt.UTC().Format(time.RFC3339)
+		updateColumns = append(updateColumns, "session_expires_at")
+	}
+	if onrampTx.InitiateParams != nil {
+		object["initiate_params"] = onrampTx.InitiateParams
+		updateColumns = append(updateColumns, "initiate_params")
+	}
+	if onrampTx.PartnerID != "" {
+		object["partner_id"] = onrampTx.PartnerID
+		updateColumns = append(updateColumns, "partner_id")
+	}
 	// Prepare variables
 	variables := map[string]interface{}{
-		"object": map[string]interface{}{
-			"user_id":               userID,
-			"country":               onrampTx.Country,
-			"in_amount":             onrampTx.InAmount,
-			"out_amount":            onrampTx.OutAmount,
-			"payment_method":        onrampTx.PaymentMethod,
-			"source_currency":       onrampTx.SourceCurrency,
-			"target_currency":       onrampTx.TargetCurrency,
-			"transaction_type":      strings.ToUpper(onrampTx.TransactionType),
-			"transaction_status":    onrampTx.Status,
-			"transaction_hash":      onrampTx.TransactionHash,
-			"partner_context":       onrampTx.PartnerContext,
-			"wallet_address":        onrampTx.WalletAddress,
-			"onramp_transaction_id": onrampTx.OnrampTransactionID,
-			"transaction_id":        onrampTx.TransactionID,
-		},
+		"object":         object,
+		"update_columns": updateColumns,
 	}
 	// GraphQL mutation.
-	query := `mutation UpsertFiatTransaction($object: terrace_schema_fiat_transactions_insert_input!) {
+	query := `mutation UpsertFiatTransaction(
+    $object: terrace_schema_fiat_transactions_insert_input!,
+    $update_columns: [terrace_schema_fiat_transactions_update_column!]!
+  ) {
   	insert_terrace_schema_fiat_transactions_one(
     object: $object
     on_conflict: {
       constraint: fiat_transactions_uk_transaction_id
-      update_columns: [
-        country
-        in_amount
-        out_amount
-        payment_method
-        source_currency
-        target_currency
-        transaction_status
-        transaction_type
-        transaction_hash
-        partner_context
-        wallet_address
-        onramp_transaction_id
-      ]
+      update_columns: $update_columns
     }
   ) {
     user_id
@@ -120,7 +202,7 @@ func (c *GraphQLClient) UpsertOnramperTransaction(
 	// Use a map to store the raw result.
 	result := resultResponse{}
 	// Execute with proper query and result handling
-	raw, err := c.client.ExecRaw(ctx, query, variables)
+	raw, err := c.execRaw(ctx, query, variables)
 
 	if err != nil {
 		err = fmt.Errorf("failed to execute mutation: %w", err)
@@ -169,12 +251,12 @@ func (c *GraphQLClient) GetUserIDFromTransaction(
         }
     }`
 	type resultResponse struct {
-		TerraceSchemaFiatTransactions struct {
+		TerraceSchemaFiatTransactions []struct {
 			UserID string `json:"user_id"`
 		} `json:"terrace_schema_fiat_transactions"`
 	}
 	result := resultResponse{}
-	raw, err = c.client.ExecRaw(ctx, query, variables)
+	raw, err = c.execRaw(ctx, query, variables)
 	if err != nil {
 		err = errors.New("failed to query the database")
 		return detail, err
@@ -184,11 +266,11 @@ func (c *GraphQLClient) GetUserIDFromTransaction(
 		err = errors.New("unable to execute Query")
 		return detail, err
 	}
-	if result.TerraceSchemaFiatTransactions.UserID == "" {
-		err = errors.New("no transaction found")
+	if len(result.TerraceSchemaFiatTransactions) == 0 || result.TerraceSchemaFiatTransactions[0].UserID == "" {
+		err = ErrTransactionNotFound
 		return detail, err
 	}
-	return result.TerraceSchemaFiatTransactions.UserID, nil
+	return result.TerraceSchemaFiatTransactions[0].UserID, nil
 }
 
 func (c *GraphQLClient) UpdateKYCStatus(
@@ -233,7 +315,7 @@ func (c *GraphQLClient) UpdateKYCStatus(
 		result resultResponse
 		raw    []byte
 	)
-	raw, err = c.client.ExecRaw(ctx, query, variables)
+	raw, err = c.execRaw(ctx, query, variables)
 	if err != nil {
 		err = fmt.Errorf("graphql execution failed: %w", err)
 		return newStatus, err
@@ -254,3 +336,586 @@ func (c *GraphQLClient) UpdateKYCStatus(
 
 	return result.InsertSession.Status, nil
 }
+
+// GetPendingTransactions returns transactions last updated before olderThan that are
+// still pending, or are sells whose payout is processing.
+func (c *GraphQLClient) GetPendingTransactions(
+	ctx context.Context,
+	olderThan time.Time,
+) (transactions []models.FiatTransaction, err error) {
+	variables := map[string]interface{}{
+		"statuses": []string{
+			string(models.TransactionStatusPending),
+			string(models.TransactionStatusProcessingPayout),
+		},
+		"older_than": olderThan.UTC().Format(time.RFC3339),
+	}
+	query := `query GetPendingTransactions($statuses: [String!]!, $older_than: timestamptz!) {
+        terrace_schema_fiat_transactions(
+            where: {
+                transaction_status: {_in: $statuses}
+                updated_at: {_lt: $older_than}
+            }
+            order_by: {updated_at: asc}
+        ) {
+            user_id
+            transaction_id
+            onramp_transaction_id
+            transaction_status
+            transaction_type
+            updated_at
+            reconciled_at
+            partner_id
+        }
+    }`
+	type resultResponse struct {
+		TerraceSchemaFiatTransactions []models.FiatTransaction `json:"terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query pending transactions: %w", err)
+		return transactions, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return transactions, err
+	}
+	return result.TerraceSchemaFiatTransactions, nil
+}
+
+// MarkTransactionReconciled stores the final status found by reconciliation and stamps
+// reconciled_at. A transaction reconciled before is left untouched and
+// ErrAlreadyReconciled is returned.
+func (c *GraphQLClient) MarkTransactionReconciled(ctx context.Context, transactionID string, status string) (err error) {
+	variables := map[string]interface{}{
+		"transaction_id": transactionID,
+		"status":         status,
+		"reconciled_at":  time.Now().UTC().Format(time.RFC3339),
+	}
+	query := `mutation MarkTransactionReconciled($transaction_id: String!, $status: String!, $reconciled_at: timestamptz!) {
+        update_terrace_schema_fiat_transactions(
+            where: {
+                transaction_id: {_eq: $transaction_id}
+                reconciled_at: {_is_null: true}
+            }
+            _set: {transaction_status: $status, reconciled_at: $reconciled_at}
+        ) {
+            affected_rows
+        }
+    }`
+	type resultResponse struct {
+		UpdateTerraceSchemaFiatTransactions struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to mark transaction reconciled: %w", err)
+		return err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return err
+	}
+	if result.UpdateTerraceSchemaFiatTransactions.AffectedRows == 0 {
+		err = fmt.Errorf("%w: %s", ErrAlreadyReconciled, transactionID)
+		return err
+	}
+	return nil
+}
+
+// SupersedeTransaction marks transactionID superseded and links it to supersededBy, the
+// transaction that replaced it, so it is no longer reconciled as pending. A transaction
+// superseded before keeps its link, and ErrAlreadySuperseded is returned; so is it for a
+// transaction that does not exist.
+func (c *GraphQLClient) SupersedeTransaction(ctx context.Context, transactionID, supersededBy string) (err error) {
+	variables := map[string]interface{}{
+		"transaction_id": transactionID,
+		"status":         string(models.TransactionStatusSuperseded),
+		"superseded_by":  supersededBy,
+	}
+	query := `mutation SupersedeTransaction($transaction_id: String!, $status: String!, $superseded_by: String!) {
+        update_terrace_schema_fiat_transactions(
+            where: {
+                transaction_id: {_eq: $transaction_id}
+                superseded_by: {_is_null: true}
+            }
+            _set: {transaction_status: $status, superseded_by: $superseded_by}
+        ) {
+            affected_rows
+        }
+    }`
+	type resultResponse struct {
+		UpdateTerraceSchemaFiatTransactions struct {
+			AffectedRows int `json:"affected_rows"`
+		} `json:"update_terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to supersede transaction: %w", err)
+		return err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return err
+	}
+	if result.UpdateTerraceSchemaFiatTransactions.AffectedRows == 0 {
+		err = fmt.Errorf("%w: %s", ErrAlreadySuperseded, transactionID)
+		return err
+	}
+	return nil
+}
+
+// GetTransactionIDByOnrampID resolves the internal transaction_id for a provider's onramp_transaction_id.
+func (c *GraphQLClient) GetTransactionIDByOnrampID(
+	ctx context.Context,
+	onrampTxID string,
+) (transactionID string, err error) {
+	variables := map[string]interface{}{
+		"onramp_tx_id": onrampTxID,
+	}
+	query := `query GetTransactionIDByOnrampID($onramp_tx_id: String!) {
+        terrace_schema_fiat_transactions(
+            where: {onramp_transaction_id: {_eq: $onramp_tx_id}}
+            limit: 1
+        ) {
+            transaction_id
+        }
+    }`
+	type resultResponse struct {
+		TerraceSchemaFiatTransactions []struct {
+			TransactionID string `json:"transaction_id"`
+		} `json:"terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query transaction by onramp id: %w", err)
+		return transactionID, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return transactionID, err
+	}
+	if len(result.TerraceSchemaFiatTransactions) == 0 || result.TerraceSchemaFiatTransactions[0].TransactionID == "" {
+		err = ErrTransactionNotFound
+		return transactionID, err
+	}
+	return result.TerraceSchemaFiatTransactions[0].TransactionID, nil
+}
+
+// GetTransactionByID returns the stored row for transactionID.
+func (c *GraphQLClient) GetTransactionByID(
+	ctx context.Context,
+	transactionID string,
+) (transaction models.FiatTransaction, err error) {
+	variables := map[string]interface{}{
+		"transaction_id": transactionID,
+	}
+	query := `query GetTransactionByID($transaction_id: String!) {
+        terrace_schema_fiat_transactions(
+            where: {transaction_id: {_eq: $transaction_id}}
+            limit: 1
+        ) {
+            user_id
+            transaction_id
+            onramp_transaction_id
+            transaction_status
+            transaction_type
+            source_currency
+            target_currency
+            in_amount
+            onramp
+            payment_method
+            updated_at
+            redirect_url
+            session_expires_at
+            initiate_params
+            superseded_by
+            partner_id
+        }
+    }`
+	type resultResponse struct {
+		TerraceSchemaFiatTransactions []models.FiatTransaction `json:"terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query transaction: %w", err)
+		return transaction, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return transaction, err
+	}
+	if len(result.TerraceSchemaFiatTransactions) == 0 {
+		err = ErrTransactionNotFound
+		return transaction, err
+	}
+	return result.TerraceSchemaFiatTransactions[0], nil
+}
+
+// GetTransactionsByUser returns up to limit of the user's stored transactions, newest first.
+func (c *GraphQLClient) GetTransactionsByUser(
+	ctx context.Context,
+	userID string,
+	limit int,
+) (transactions []models.FiatTransaction, err error) {
+	variables := map[string]interface{}{
+		"user_id": userID,
+		"limit":   limit,
+	}
+	query := `query GetTransactionsByUser($user_id: uuid!, $limit: Int!) {
+        terrace_schema_fiat_transactions(
+            where: {user_id: {_eq: $user_id}}
+            order_by: {updated_at: desc}
+            limit: $limit
+        ) {
+            user_id
+            transaction_id
+            onramp_transaction_id
+            transaction_status
+            transaction_type
+            source_currency
+            target_currency
+            in_amount
+            out_amount
+            onramp
+            payment_method
+            wallet_address
+            updated_at
+        }
+    }`
+	type resultResponse struct {
+		TerraceSchemaFiatTransactions []models.FiatTransaction `json:"terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query user transactions: %w", err)
+		return transactions, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return transactions, err
+	}
+	return result.TerraceSchemaFiatTransactions, nil
+}
+
+// GetTransactionsByWallet returns every stored transaction for walletAddress, newest first.
+func (c *GraphQLClient) GetTransactionsByWallet(
+	ctx context.Context,
+	walletAddress string,
+) (transactions []models.FiatTransaction, err error) {
+	variables := map[string]interface{}{
+		"wallet_address": walletAddress,
+	}
+	query := `query GetTransactionsByWallet($wallet_address: String!) {
+        terrace_schema_fiat_transactions(
+            where: {wallet_address: {_eq: $wallet_address}}
+            order_by: {updated_at: desc}
+        ) {
+            user_id
+            transaction_id
+            onramp_transaction_id
+            transaction_status
+            transaction_type
+            source_currency
+            target_currency
+            in_amount
+            out_amount
+            onramp
+            payment_method
+            wallet_address
+            updated_at
+        }
+    }`
+	type resultResponse struct {
+		TerraceSchemaFiatTransactions []models.FiatTransaction `json:"terrace_schema_fiat_transactions"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query wallet transactions: %w", err)
+		return transactions, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return transactions, err
+	}
+	return result.TerraceSchemaFiatTransactions, nil
+}
+
+// EnqueueFailedKYCUpdate stores a KYC status change that could not be applied, for retry.
+func (c *GraphQLClient) EnqueueFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) (err error) {
+	variables := map[string]interface{}{
+		"object": map[string]interface{}{
+			"user_id":            update.UserID,
+			"kyc_status":         update.KYCStatus,
+			"transaction_id":     update.TransactionID,
+			"transaction_status": update.TransactionStatus,
+			"attempts":           update.Attempts,
+			"last_error":         update.LastError,
+		},
+	}
+	query := `mutation EnqueueFailedKYCUpdate($object: terrace_schema_failed_kyc_updates_insert_input!) {
+        insert_terrace_schema_failed_kyc_updates_one(object: $object) {
+            id
+        }
+    }`
+	_, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to enqueue KYC update: %w", err)
+		return err
+	}
+	return nil
+}
+
+// GetFailedKYCUpdates returns up to limit failed KYC updates tried fewer than
+// maxAttempts times, oldest first. Updates that reached maxAttempts stay stored for an
+// operator but are not returned again.
+func (c *GraphQLClient) GetFailedKYCUpdates(ctx context.Context, limit, maxAttempts int) (updates []models.FailedKYCUpdate, err error) {
+	variables := map[string]interface{}{
+		"limit":        limit,
+		"max_attempts": maxAttempts,
+	}
+	query := `query GetFailedKYCUpdates($limit: Int!, $max_attempts: Int!) {
+        terrace_schema_failed_kyc_updates(
+            where: {attempts: {_lt: $max_attempts}}
+            order_by: {created_at: asc}
+            limit: $limit
+        ) {
+            id
+            user_id
+            kyc_status
+            transaction_id
+            transaction_status
+            attempts
+            last_error
+            created_at
+        }
+    }`
+	type resultResponse struct {
+		Updates []models.FailedKYCUpdate `json:"terrace_schema_failed_kyc_updates"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query failed KYC updates: %w", err)
+		return updates, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return updates, err
+	}
+	return result.Updates, nil
+}
+
+// ApplyFailedKYCUpdate stores the KYC status of a queued update, stamped with the time
+// it was queued, unless the user's KYC status was updated at or after that time or is
+// APPROVED. applied is false when the update was skipped because a newer status is
+// stored.
+func (c *GraphQLClient) ApplyFailedKYCUpdate(
+	ctx context.Context,
+	update models.FailedKYCUpdate,
+) (status string, applied bool, err error) {
+	variables := map[string]interface{}{
+		"user_id":    update.UserID,
+		"new_status": update.KYCStatus,
+		"queued_at":  update.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	query := `mutation ApplyFailedKYCUpdate($user_id: uuid!, $new_status: String!, $queued_at: timestamptz!) {
+        insert_terrace_schema_id_verification_sessions_one(
+            object: {
+                user_id: $user_id
+                status: $new_status
+                updated_at: $queued_at
+            }
+            on_conflict: {
+                constraint: id_verification_sessions_user_id_key
+                update_columns: [status, updated_at]
+                where: {
+                    status: {_neq: "APPROVED"}
+                    updated_at: {_lt: $queued_at}
+                }
+            }
+        ) {
+            verification_session_id
+            status
+        }
+    }`
+	type resultResponse struct {
+		InsertSession *struct {
+			SessionID string `json:"verification_session_id"`
+			Status    string `json:"status"`
+		} `json:"insert_terrace_schema_id_verification_sessions_one"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("graphql execution failed: %w", err)
+		return status, applied, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return status, applied, err
+	}
+	// The conflict's where clause left the stored row alone.
+	if result.InsertSession == nil {
+		return status, applied, nil
+	}
+	return result.InsertSession.Status, true, nil
+}
+
+// ResolveFailedKYCUpdate removes a failed KYC update once it has been applied.
+func (c *GraphQLClient) ResolveFailedKYCUpdate(ctx context.Context, id string) (err error) {
+	variables := map[string]interface{}{
+		"id": id,
+	}
+	query := `mutation ResolveFailedKYCUpdate($id: uuid!) {
+        delete_terrace_schema_failed_kyc_updates_by_pk(id: $id) {
+            id
+        }
+    }`
+	_, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve KYC update: %w", err)
+		return err
+	}
+	return nil
+}
+
+// RecordFailedKYCRetry counts another failed attempt at a queued KYC update.
+func (c *GraphQLClient) RecordFailedKYCRetry(ctx context.Context, id string, lastError string) (err error) {
+	variables := map[string]interface{}{
+		"id":         id,
+		"last_error": lastError,
+	}
+	query := `mutation RecordFailedKYCRetry($id: uuid!, $last_error: String!) {
+        update_terrace_schema_failed_kyc_updates_by_pk(
+            pk_columns: {id: $id}
+            _inc: {attempts: 1}
+            _set: {last_error: $last_error}
+        ) {
+            id
+        }
+    }`
+	_, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to record KYC retry: %w", err)
+		return err
+	}
+	return nil
+}
+
+// GetWebhookAck returns the stored result of processing the webhook event eventID.
+func (c *GraphQLClient) GetWebhookAck(ctx context.Context, eventID string) (ack models.WebhookAck, err error) {
+	variables := map[string]interface{}{
+		"event_id": eventID,
+	}
+	query := `query GetWebhookAck($event_id: String!) {
+        terrace_schema_webhook_acks_by_pk(event_id: $event_id) {
+            event_id
+            user_id
+            kyc_status
+            created_at
+        }
+    }`
+	type resultResponse struct {
+		Ack *models.WebhookAck `json:"terrace_schema_webhook_acks_by_pk"`
+	}
+
+	var (
+		result resultResponse
+		raw    []byte
+	)
+	raw, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to query webhook ack: %w", err)
+		return ack, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		err = fmt.Errorf("response parsing failed: %w", err)
+		return ack, err
+	}
+	if result.Ack == nil {
+		err = ErrWebhookAckNotFound
+		return ack, err
+	}
+	return *result.Ack, nil
+}
+
+// StoreWebhookAck stores the result of processing a webhook event. An ack already
+// stored for the event is kept.
+func (c *GraphQLClient) StoreWebhookAck(ctx context.Context, ack models.WebhookAck) (err error) {
+	variables := map[string]interface{}{
+		"object": map[string]interface{}{
+			"event_id":   ack.EventID,
+			"user_id":    ack.UserID,
+			"kyc_status": ack.KYCStatus,
+		},
+	}
+	query := `mutation StoreWebhookAck($object: terrace_schema_webhook_acks_insert_input!) {
+        insert_terrace_schema_webhook_acks_one(
+            object: $object
+            on_conflict: {constraint: webhook_acks_pkey, update_columns: []}
+        ) {
+            event_id
+        }
+    }`
+	_, err = c.execRaw(ctx, query, variables)
+	if err != nil {
+		err = fmt.Errorf("failed to store webhook ack: %w", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// graphQLRequest is the body sent by the GraphQL client.
+type graphQLRequest struct {
+	Query     string                     `json:"query"`
+	Variables map[string]json.RawMessage `json:"variables"`
+}
+
+// newTestGraphQLClient returns a client whose requests are captured and answered with response.
+func newTestGraphQLClient(t *testing.T, response string, captured *graphQLRequest) *GraphQLClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(body, captured))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return NewGraphQLClient(server.URL, "test-admin-secret", zap.NewNop())
+}
+
+func TestUpsertOnramperTransactionRawPayload(t *testing.T) {
+	rawWebhook := `{"country":"us","inAmount":68,"onramp":"transfi","status":"completed","transactionId":"01H6DQWMRC8FA9MBM0HS5NABCD"}`
+	response := `{"data":{"insert_terrace_schema_fiat_transactions_one":{"user_id":"user123","transaction_id":"01H6DQWMRC8FA9MBM0HS5NABCD","transaction_status":"completed"}}}`
+
+	var captured graphQLRequest
+	client := newTestGraphQLClient(t, response, &captured)
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal([]byte(rawWebhook), &payload))
+	payload.RawPayload = []byte(rawWebhook)
+
+	userID, err := client.UpsertOnramperTransaction(context.Background(), &payload, "user123")
+	require.NoError(t, err)
+	assert.Equal(t, "user123", userID)
+
+	var object map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+	assert.Equal(t, rawWebhook, string(object["raw_payload"]))
+
+	var updateColumns []string
+	require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+	assert.Contains(t, updateColumns, "raw_payload")
+}
+
+func TestMutationDeadline(t *testing.T) {
+	// hung never answers, like a Hasura that accepted the connection and stalled.
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+	payload := &models.WebhookPayload{TransactionID: "01H6DQWMRC8FA9MBM0HS5NABCD", Status: "completed"}
+
+	t.Run("cancelled context aborts the mutation", func(t *testing.T) {
+		client := NewGraphQLClient(hung.URL, "test-admin-secret", zap.NewNop())
+		client.Timeout = 0
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := client.UpsertOnramperTransaction(ctx, payload, "user123")
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("client timeout bounds calls without a deadline", func(t *testing.T) {
+		client := NewGraphQLClient(hung.URL, "test-admin-secret", zap.NewNop())
+		client.Timeout = 20 * time.Millisecond
+
+		start := time.Now()
+		_, err := client.UpdateKYCStatus(context.Background(), "user123", "APPROVED")
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestGetTransactionIDByOnrampID(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_fiat_transactions":[{"transaction_id":"01H6DQWMRC8FA9MBM0HS5NABCD"}]}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		transactionID, err := client.GetTransactionIDByOnrampID(context.Background(), "OR-2123428075629314")
+		require.NoError(t, err)
+		assert.Equal(t, "01H6DQWMRC8FA9MBM0HS5NABCD", transactionID)
+		assert.JSONEq(t, `"OR-2123428075629314"`, string(captured.Variables["onramp_tx_id"]))
+	})
+	t.Run("not found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_fiat_transactions":[]}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.GetTransactionIDByOnrampID(context.Background(), "OR-missing")
+		require.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
+
+func TestGetUserIDFromTransaction(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_fiat_transactions":[{"user_id":"user123"}]}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		userID, err := client.GetUserIDFromTransaction(context.Background(), "tx_123", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", userID)
+	})
+	t.Run("not found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_fiat_transactions":[]}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.GetUserIDFromTransaction(context.Background(), "tx_missing", "", "")
+		require.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
+
+func TestCheckoutDetailsRoundTrip(t *testing.T) {
+	expiresAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	t.Run("upsert stores checkout details", func(t *testing.T) {
+		response := `{"data":{"insert_terrace_schema_fiat_transactions_one":{"user_id":"user123","transaction_id":"tx_123","transaction_status":"pending"}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID:    "tx_123",
+			Status:           "pending",
+			RedirectURL:      "https://buy.moonpay.com/checkout",
+			SessionExpiresAt: &expiresAt,
+			InitiateParams:   &models.InitiateTransactionRequest{Onramp: "moonpay", Source: "usd", Destination: "btc", Amount: 100},
+		}, "user123")
+		require.NoError(t, err)
+
+		var object map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+		assert.JSONEq(t, `"https://buy.moonpay.com/checkout"`, string(object["redirect_url"]))
+		assert.JSONEq(t, `"2024-05-01T12:30:00Z"`, string(object["session_expires_at"]))
+		var params models.InitiateTransactionRequest
+		require.NoError(t, json.Unmarshal(object["initiate_params"], &params))
+		assert.Equal(t, "moonpay", params.Onramp)
+
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.Contains(t, updateColumns, "redirect_url")
+		assert.Contains(t, updateColumns, "session_expires_at")
+		assert.Contains(t, updateColumns, "initiate_params")
+	})
+
+	t.Run("webhook updates keep stored details", func(t *testing.T) {
+		response := `{"data":{"insert_terrace_schema_fiat_transactions_one":{"user_id":"user123","transaction_id":"tx_123","transaction_status":"completed"}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID: "tx_123",
+			Status:        "completed",
+		}, "user123")
+		require.NoError(t, err)
+
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.NotContains(t, updateColumns, "redirect_url")
+		assert.NotContains(t, updateColumns, "session_expires_at")
+		assert.NotContains(t, updateColumns, "initiate_params")
+	})
+
+	t.Run("lookup returns checkout details", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_fiat_transactions":[{
+			"user_id":"user123","transaction_id":"tx_123","transaction_status":"pending",
+			"updated_at":"2024-05-01T12:00:00Z","onramp":"moonpay","payment_method":"creditcard",
+			"redirect_url":"https://buy.moonpay.com/checkout",
+			"session_expires_at":"2024-05-01T12:30:00+00:00",
+			"initiate_params":{"onramp":"moonpay","source":"usd","destination":"btc","amount":100}}]}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		tx, err := client.GetTransactionByID(context.Background(), "tx_123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://buy.moonpay.com/checkout", tx.RedirectURL)
+		assert.Equal(t, "moonpay", tx.Onramp)
+		assert.Equal(t, "creditcard", tx.PaymentMethod)
+		assert.Contains(t, captured.Query, "onramp\n")
+		require.NotNil(t, tx.SessionExpiresAt)
+		assert.True(t, tx.SessionExpiresAt.Equal(expiresAt))
+		require.NotNil(t, tx.InitiateParams)
+		assert.Equal(t, "btc", tx.InitiateParams.Destination)
+	})
+}
+
+func TestGetTransactionsByUser(t *testing.T) {
+	response := `{"data":{"terrace_schema_fiat_transactions":[` +
+		`{"user_id":"user123","transaction_id":"tx-2","transaction_status":"COMPLETED","in_amount":100.5},` +
+		`{"user_id":"user123","transaction_id":"tx-1","transaction_status":"PENDING"}]}}`
+	var captured graphQLRequest
+	client := newTestGraphQLClient(t, response, &captured)
+
+	transactions, err := client.GetTransactionsByUser(context.Background(), "user123", 25)
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "tx-2", transactions[0].TransactionID)
+	require.NotNil(t, transactions[0].InAmount)
+	assert.Equal(t, "100.5", transactions[0].InAmount.String())
+	assert.Nil(t, transactions[1].InAmount)
+
+	assert.JSONEq(t, `"user123"`, string(captured.Variables["user_id"]))
+	assert.JSONEq(t, `25`, string(captured.Variables["limit"]))
+	assert.Contains(t, captured.Query, "order_by: {updated_at: desc}")
+}
+
+func TestGetTransactionsByWallet(t *testing.T) {
+	response := `{"data":{"terrace_schema_fiat_transactions":[` +
+		`{"user_id":"user123","transaction_id":"tx-2","transaction_status":"COMPLETED","wallet_address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},` +
+		`{"user_id":"user456","transaction_id":"tx-1","transaction_status":"PENDING","wallet_address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}]}}`
+	var captured graphQLRequest
+	client := newTestGraphQLClient(t, response, &captured)
+
+	transactions, err := client.GetTransactionsByWallet(context.Background(), "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "tx-2", transactions[0].TransactionID)
+	assert.Equal(t, "user456", transactions[1].UserID)
+
+	assert.JSONEq(t, `"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"`, string(captured.Variables["wallet_address"]))
+	assert.Contains(t, captured.Query, "where: {wallet_address: {_eq: $wallet_address}}")
+	assert.Contains(t, captured.Query, "order_by: {updated_at: desc}")
+	assert.NotContains(t, captured.Query, "limit")
+}
+
+func TestUpsertOnramperTransactionPreservesHash(t *testing.T) {
+	response := `{"data":{"insert_terrace_schema_fiat_transactions_one":{"user_id":"user123","transaction_id":"tx_123","transaction_status":"completed"}}}`
+
+	t.Run("status-only update keeps stored identifiers", func(t *testing.T) {
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID: "tx_123",
+			Status:        "completed",
+		}, "user123")
+		require.NoError(t, err)
+
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.Contains(t, updateColumns, "transaction_status")
+		assert.NotContains(t, updateColumns, "transaction_hash")
+		assert.NotContains(t, updateColumns, "wallet_address")
+		assert.NotContains(t, updateColumns, "onramp_transaction_id")
+		assert.NotContains(t, updateColumns, "partner_context")
+		assert.NotContains(t, updateColumns, "onramp")
+		assert.NotContains(t, updateColumns, "payment_method")
+	})
+
+	t.Run("update with hash stores it", func(t *testing.T) {
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID:   "tx_123",
+			Status:          "completed",
+			TransactionHash: "0xabc",
+			PartnerContext:  "order-42",
+		}, "user123")
+		require.NoError(t, err)
+
+		var object map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+		assert.JSONEq(t, `"0xabc"`, string(object["transaction_hash"]))
+
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.Contains(t, updateColumns, "transaction_hash")
+		assert.Contains(t, updateColumns, "partner_context")
+		assert.NotContains(t, updateColumns, "wallet_address")
+	})
+
+	t.Run("partner is stored only when known", func(t *testing.T) {
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID: "tx_123",
+			Status:        "pending",
+			PartnerID:     "acme",
+		}, "user123")
+		require.NoError(t, err)
+		var object map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+		assert.JSONEq(t, `"acme"`, string(object["partner_id"]))
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.Contains(t, updateColumns, "partner_id")
+
+		_, err = client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID: "tx_123",
+			Status:        "completed",
+		}, "user123")
+		require.NoError(t, err)
+		object = nil
+		require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+		assert.NotContains(t, object, "partner_id")
+		updateColumns = nil
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.NotContains(t, updateColumns, "partner_id", "a webhook must not clear the partner")
+	})
+
+	t.Run("update with onramp and payment method stores them", func(t *testing.T) {
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.UpsertOnramperTransaction(context.Background(), &models.WebhookPayload{
+			TransactionID: "tx_123",
+			Status:        "pending",
+			Onramp:        "moonpay",
+			PaymentMethod: "creditcard",
+		}, "user123")
+		require.NoError(t, err)
+
+		var object map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(captured.Variables["object"], &object))
+		assert.JSONEq(t, `"moonpay"`, string(object["onramp"]))
+		assert.JSONEq(t, `"creditcard"`, string(object["payment_method"]))
+
+		var updateColumns []string
+		require.NoError(t, json.Unmarshal(captured.Variables["update_columns"], &updateColumns))
+		assert.Contains(t, updateColumns, "onramp")
+		assert.Contains(t, updateColumns, "payment_method")
+	})
+}
+
+func TestGetFailedKYCUpdates(t *testing.T) {
+	response := `{"data":{"terrace_schema_failed_kyc_updates":[{
+		"id":"retry-1","user_id":"user123","kyc_status":"APPROVED","transaction_id":"tx_123",
+		"transaction_status":"completed","attempts":2,"last_error":"timeout",
+		"created_at":"2024-05-01T12:00:00Z"}]}}`
+	var captured graphQLRequest
+	client := newTestGraphQLClient(t, response, &captured)
+
+	updates, err := client.GetFailedKYCUpdates(context.Background(), 100, 10)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, "retry-1", updates[0].ID)
+	assert.Equal(t, "APPROVED", updates[0].KYCStatus)
+	assert.Equal(t, 2, updates[0].Attempts)
+	assert.JSONEq(t, `100`, string(captured.Variables["limit"]))
+	assert.JSONEq(t, `10`, string(captured.Variables["max_attempts"]))
+	assert.Contains(t, captured.Query, "where: {attempts: {_lt: $max_attempts}}")
+}
+
+func TestApplyFailedKYCUpdate(t *testing.T) {
+	update := models.FailedKYCUpdate{
+		ID: "retry-1", UserID: "user123", KYCStatus: "REJECTED",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("applies an update newer than the stored status", func(t *testing.T) {
+		response := `{"data":{"insert_terrace_schema_id_verification_sessions_one":{"verification_session_id":"s-1","status":"REJECTED"}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		status, applied, err := client.ApplyFailedKYCUpdate(context.Background(), update)
+		require.NoError(t, err)
+		assert.True(t, applied)
+		assert.Equal(t, "REJECTED", status)
+		assert.JSONEq(t, `"2024-05-01T12:00:00Z"`, string(captured.Variables["queued_at"]))
+		assert.Contains(t, captured.Query, "updated_at: {_lt: $queued_at}")
+	})
+	t.Run("skips an update older than the stored status", func(t *testing.T) {
+		response := `{"data":{"insert_terrace_schema_id_verification_sessions_one":null}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, applied, err := client.ApplyFailedKYCUpdate(context.Background(), update)
+		require.NoError(t, err)
+		assert.False(t, applied)
+	})
+}
+
+func TestGetPendingTransactions(t *testing.T) {
+	response := `{"data":{"terrace_schema_fiat_transactions":[` +
+		`{"user_id":"user123","transaction_id":"tx-1","transaction_status":"processing_payout","transaction_type":"SELL","partner_id":"acme"}]}}`
+	var captured graphQLRequest
+	client := newTestGraphQLClient(t, response, &captured)
+
+	transactions, err := client.GetPendingTransactions(context.Background(), time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "SELL", transactions[0].TransactionType)
+	assert.Equal(t, "acme", transactions[0].PartnerID)
+	assert.Contains(t, captured.Query, "partner_id")
+
+	assert.JSONEq(t, `["pending","processing_payout"]`, string(captured.Variables["statuses"]))
+	assert.JSONEq(t, `"2024-05-01T12:00:00Z"`, string(captured.Variables["older_than"]))
+	assert.Contains(t, captured.Query, "transaction_status: {_in: $statuses}")
+}
+
+func TestMarkTransactionReconciled(t *testing.T) {
+	t.Run("stamps reconciled_at and status", func(t *testing.T) {
+		response := `{"data":{"update_terrace_schema_fiat_transactions":{"affected_rows":1}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		before := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, client.MarkTransactionReconciled(context.Background(), "tx_123", "completed"))
+
+		assert.JSONEq(t, `"tx_123"`, string(captured.Variables["transaction_id"]))
+		assert.JSONEq(t, `"completed"`, string(captured.Variables["status"]))
+		var reconciledAt time.Time
+		require.NoError(t, json.Unmarshal(captured.Variables["reconciled_at"], &reconciledAt))
+		assert.False(t, reconciledAt.Before(before))
+		assert.Contains(t, captured.Query, "reconciled_at: {_is_null: true}")
+	})
+	t.Run("already reconciled", func(t *testing.T) {
+		response := `{"data":{"update_terrace_schema_fiat_transactions":{"affected_rows":0}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		err := client.MarkTransactionReconciled(context.Background(), "tx_123", "completed")
+		require.ErrorIs(t, err, ErrAlreadyReconciled)
+	})
+}
+
+func TestSupersedeTransaction(t *testing.T) {
+	t.Run("marks and links the transaction", func(t *testing.T) {
+		response := `{"data":{"update_terrace_schema_fiat_transactions":{"affected_rows":1}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		require.NoError(t, client.SupersedeTransaction(context.Background(), "tx_old", "tx_new"))
+		assert.JSONEq(t, `"tx_old"`, string(captured.Variables["transaction_id"]))
+		assert.JSONEq(t, `"superseded"`, string(captured.Variables["status"]))
+		assert.JSONEq(t, `"tx_new"`, string(captured.Variables["superseded_by"]))
+		assert.Contains(t, captured.Query, "_set: {transaction_status: $status, superseded_by: $superseded_by}")
+		assert.Contains(t, captured.Query, "superseded_by: {_is_null: true}")
+	})
+	t.Run("already superseded transaction", func(t *testing.T) {
+		response := `{"data":{"update_terrace_schema_fiat_transactions":{"affected_rows":0}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		err := client.SupersedeTransaction(context.Background(), "tx_old", "tx_new")
+		require.ErrorIs(t, err, ErrAlreadySuperseded)
+	})
+}
+
+func TestGetWebhookAck(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_webhook_acks_by_pk":{
+			"event_id":"evt-1","user_id":"user123","kyc_status":"APPROVED","created_at":"2024-05-01T12:00:00Z"}}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		ack, err := client.GetWebhookAck(context.Background(), "evt-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", ack.UserID)
+		assert.Equal(t, "APPROVED", ack.KYCStatus)
+		assert.JSONEq(t, `"evt-1"`, string(captured.Variables["event_id"]))
+	})
+	t.Run("not found", func(t *testing.T) {
+		response := `{"data":{"terrace_schema_webhook_acks_by_pk":null}}`
+		var captured graphQLRequest
+		client := newTestGraphQLClient(t, response, &captured)
+
+		_, err := client.GetWebhookAck(context.Background(), "evt-1")
+		require.ErrorIs(t, err, ErrWebhookAckNotFound)
+	})
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
 )
@@ -13,4 +14,36 @@ type QueryClient interface {
 	// UpdateKYCStatus updates the KYC status of a user in the id_verification_sessions table.
 	UpdateKYCStatus(ctx context.Context, userID, transactionStatus string) (string, error)
 	GetUserIDFromTransaction(ctx context.Context, transactionID, onrampTxID, walletAddress string) (string, error)
+	// GetPendingTransactions returns pending transactions, and sells awaiting their payout,
+	// last updated before olderThan.
+	GetPendingTransactions(ctx context.Context, olderThan time.Time) ([]models.FiatTransaction, error)
+	// MarkTransactionReconciled stores a reconciled final status and stamps reconciled_at.
+	MarkTransactionReconciled(ctx context.Context, transactionID string, status string) error
+	// SupersedeTransaction marks a transaction superseded by the one that replaced it,
+	// unless it was superseded before.
+	SupersedeTransaction(ctx context.Context, transactionID, supersededBy string) error
+	// GetTransactionIDByOnrampID resolves the internal transaction_id for a provider transaction id.
+	GetTransactionIDByOnrampID(ctx context.Context, onrampTxID string) (string, error)
+	// GetTransactionByID returns the stored transaction row, including checkout details.
+	GetTransactionByID(ctx context.Context, transactionID string) (models.FiatTransaction, error)
+	// GetTransactionsByUser returns up to limit of a user's stored transactions, newest first.
+	GetTransactionsByUser(ctx context.Context, userID string, limit int) ([]models.FiatTransaction, error)
+	// GetTransactionsByWallet returns every stored transaction paying out to walletAddress, newest first.
+	GetTransactionsByWallet(ctx context.Context, walletAddress string) ([]models.FiatTransaction, error)
+	// EnqueueFailedKYCUpdate stores a KYC status change that could not be applied, for retry.
+	EnqueueFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) error
+	// GetFailedKYCUpdates returns up to limit queued KYC updates tried fewer than
+	// maxAttempts times, oldest first.
+	GetFailedKYCUpdates(ctx context.Context, limit, maxAttempts int) ([]models.FailedKYCUpdate, error)
+	// ApplyFailedKYCUpdate stores a queued KYC status unless a newer one is stored, and
+	// reports whether it did.
+	ApplyFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) (string, bool, error)
+	// ResolveFailedKYCUpdate removes a queued KYC update once it has been applied.
+	ResolveFailedKYCUpdate(ctx context.Context, id string) error
+	// RecordFailedKYCRetry counts another failed attempt at a queued KYC update.
+	RecordFailedKYCRetry(ctx context.Context, id string, lastError string) error
+	// GetWebhookAck returns the stored result of a processed webhook event, or ErrWebhookAckNotFound.
+	GetWebhookAck(ctx context.Context, eventID string) (models.WebhookAck, error)
+	// StoreWebhookAck stores the result of processing a webhook event, keeping any earlier one.
+	StoreWebhookAck(ctx context.Context, ack models.WebhookAck) error
 }
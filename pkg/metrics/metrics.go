@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KYCPendingStatus is the KYC status counted by the pending verifications gauge.
+const KYCPendingStatus = "PENDING"
+
+// KYCMetrics tracks KYC status transitions driven by Onramper webhooks.
+type KYCMetrics struct {
+	// Transitions counts transaction status -> KYC status transitions.
+	Transitions *prometheus.CounterVec
+	// PendingVerifications reports users currently waiting on a KYC decision.
+	PendingVerifications prometheus.Gauge
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+// KYC is the process-wide KYC metrics set registered with the default Prometheus registry.
+//
+//nolint:gochecknoglobals // Prometheus collectors are registered once per process.
+var KYC = NewKYCMetrics(prometheus.DefaultRegisterer)
+
+// NewKYCMetrics creates the KYC collectors and registers them with reg.
+func NewKYCMetrics(reg prometheus.Registerer) *KYCMetrics {
+	m := &KYCMetrics{
+		Transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kyc_transitions_total",
+			Help: "Number of KYC status transitions triggered by transaction webhooks.",
+		}, []string{"from", "to"}),
+		PendingVerifications: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kyc_pending_verifications",
+			Help: "Number of users with a pending KYC verification.",
+		}),
+		pending: make(map[string]struct{}),
+	}
+	reg.MustRegister(m.Transitions, m.PendingVerifications)
+	return m
+}
+
+// ObserveTransition records a transition for userID and keeps the pending gauge in sync.
+func (m *KYCMetrics) ObserveTransition(userID, from, to string) {
+	m.Transitions.WithLabelValues(from, to).Inc()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if to == KYCPendingStatus {
+		m.pending[userID] = struct{}{}
+	} else {
+		delete(m.pending, userID)
+	}
+	m.PendingVerifications.Set(float64(len(m.pending)))
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// Amount is a decimal money amount. It keeps every digit Onramper sends, which float64
+// cannot do for crypto amounts with up to 18 decimals.
+//
+// Amounts marshal as plain JSON numbers so the API shape is unchanged, and
+// unmarshal from numbers or numeric strings.
+type Amount struct {
+	decimal.Decimal
+}
+
+// NewAmount parses a decimal string such as "0.000123456789012345678".
+func NewAmount(value string) (Amount, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Amount{}, fmt.Errorf("invalid amount %q: %w", value, err)
+	}
+	return Amount{Decimal: d}, nil
+}
+
+// ParseLocalizedAmount parses an amount formatted for display, such as "1,000.50" or
+// "$68". Currency symbols, whitespace, underscores and apostrophes are dropped before
+// parsing. Commas are only accepted as thousands separators, splitting the whole part into
+// groups of three digits; any other comma, such as the decimal comma of "68,5" or
+// "1.000,50", is an error rather than a guess that may store a wrong amount.
+func ParseLocalizedAmount(value string) (Amount, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '_' || r == '\'':
+			return -1
+		case unicode.IsSpace(r) || unicode.Is(unicode.Sc, r):
+			return -1
+		}
+		return r
+	}, value)
+	whole, fraction, hasFraction := strings.Cut(cleaned, ".")
+	if strings.Contains(whole, ",") {
+		groups := strings.Split(whole, ",")
+		lead := strings.TrimLeft(groups[0], "+-")
+		if lead == "" || len(lead) > 3 {
+			return Amount{}, fmt.Errorf("invalid amount %q: comma is not a thousands separator", value)
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return Amount{}, fmt.Errorf("invalid amount %q: comma is not a thousands separator", value)
+			}
+		}
+		cleaned = strings.Join(groups, "")
+		if hasFraction {
+			cleaned += "." + fraction
+		}
+	}
+	d, err := decimal.NewFromString(cleaned)
+	if err != nil {
+		return Amount{}, fmt.Errorf("invalid amount %q: %w", value, err)
+	}
+	return Amount{Decimal: d}, nil
+}
+
+// NewAmountFromFloat converts a float64 amount, e.g. one parsed from a query string.
+func NewAmountFromFloat(value float64) Amount {
+	return Amount{Decimal: decimal.NewFromFloat(value)}
+}
+
+// MarshalJSON encodes the amount as a JSON number without losing precision.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.Decimal.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, a numeric string or null (zero).
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		a.Decimal = decimal.Zero
+		return nil
+	}
+	data = bytes.Trim(data, `"`)
+	d, err := decimal.NewFromString(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	a.Decimal = d
+	return nil
+}
+
+// GreaterThan reports whether a > other.
+func (a Amount) GreaterThan(other Amount) bool {
+	return a.Decimal.GreaterThan(other.Decimal)
+}
+
+// LessThan reports whether a < other.
+func (a Amount) LessThan(other Amount) bool {
+	return a.Decimal.LessThan(other.Decimal)
+}
+
+// Equal reports whether a and other are numerically equal, ignoring trailing zeros.
+func (a Amount) Equal(other Amount) bool {
+	return a.Decimal.Equal(other.Decimal)
+}
+
+// localizedAmount is an Amount that also unmarshals from display-formatted strings,
+// which some onramps forward in webhook amounts.
+type localizedAmount Amount
+
+// UnmarshalJSON accepts everything Amount does plus strings ParseLocalizedAmount reads.
+func (a *localizedAmount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '"' {
+		return (*Amount)(a).UnmarshalJSON(data)
+	}
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	amount, err := ParseLocalizedAmount(value)
+	if err != nil {
+		return err
+	}
+	*a = localizedAmount(amount)
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountPreservesPayoutPrecision(t *testing.T) {
+	// An 18-decimal ETH payout; float64 keeps only ~17 significant digits.
+	body := `{"ramp":"moonpay","payout":1.123456789012345678}`
+
+	var asFloat struct {
+		Payout float64 `json:"payout"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &asFloat))
+
+	var quote QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &quote))
+
+	assert.Equal(t, "1.123456789012345678", quote.Payout.String())
+	assert.NotEqual(t, "1.123456789012345678", fmtFloat(asFloat.Payout), "float64 drops trailing digits")
+
+	encoded, err := json.Marshal(quote.Payout)
+	require.NoError(t, err)
+	assert.Equal(t, "1.123456789012345678", string(encoded), "amounts marshal as bare JSON numbers")
+}
+
+func TestAmountComparison(t *testing.T) {
+	a, err := NewAmount("0.1")
+	require.NoError(t, err)
+	b, err := NewAmount("0.2")
+	require.NoError(t, err)
+	want, err := NewAmount("0.30")
+	require.NoError(t, err)
+
+	sum := Amount{Decimal: a.Add(b.Decimal)}
+	assert.True(t, sum.Equal(want))
+	assert.NotEqual(t, 0.3, 0.1+fp(0.2), "float64 addition drifts")
+
+	assert.True(t, b.GreaterThan(a))
+	assert.True(t, a.LessThan(b))
+}
+
+func TestAmountUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: `68`, expected: "68"},
+		{input: `"0.00202087"`, expected: "0.00202087"},
+		{input: `null`, expected: "0"},
+		{input: `"abc"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var amount Amount
+			err := json.Unmarshal([]byte(tt.input), &amount)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, amount.String())
+		})
+	}
+}
+
+func TestParseLocalizedAmount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "1,000.50", expected: "1000.5"},
+		{input: "-1,234,567", expected: "-1234567"},
+		{input: "$68", expected: "68"},
+		{input: "€ 1 234.00", expected: "1234"},
+		{input: "-12.5", expected: "-12.5"},
+		{input: "1.000,50", wantErr: true},
+		{input: "68,5", wantErr: true},
+		{input: "€ 1 234,00", wantErr: true},
+		{input: "1,00,000", wantErr: true},
+		{input: "1234,567", wantErr: true},
+		{input: ",500", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "$", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			amount, err := ParseLocalizedAmount(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, amount.String())
+		})
+	}
+}
+
+// fmtFloat formats f with the shortest representation that round-trips.
+func fmtFloat(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+// fp keeps the compiler from folding float constants exactly.
+func fp(f float64) float64 { return f }
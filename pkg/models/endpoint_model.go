@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -26,11 +29,25 @@ type CryptoCurrency struct {
 	Network            string `json:"network"`
 	Decimals           int    `json:"decimals"`
 	Address            string `json:"address"`
-	ChainID            int    `json:"chainId"`
+	ChainID            int64  `json:"chainId"`
 	Icon               string `json:"icon"`
 	NetworkDisplayName string `json:"networkDisplayName"`
 }
 
+// MaxCryptoDecimals is the largest decimals value accepted for a cryptocurrency.
+const MaxCryptoDecimals = 30
+
+// Validate reports decimals outside 0-MaxCryptoDecimals or a negative chain ID.
+func (c CryptoCurrency) Validate() error {
+	if c.Decimals < 0 || c.Decimals > MaxCryptoDecimals {
+		return fmt.Errorf("crypto %s: decimals %d out of range 0-%d", c.ID, c.Decimals, MaxCryptoDecimals)
+	}
+	if c.ChainID < 0 {
+		return fmt.Errorf("crypto %s: negative chain id %d", c.ID, c.ChainID)
+	}
+	return nil
+}
+
 // FiatCurrency represents a supported fiat currency.
 type FiatCurrency struct {
 	ID     string `json:"id"`
@@ -52,6 +69,17 @@ type PaymentType struct {
 	Icon          string `json:"icon"`
 }
 
+// AllPaymentTypesResponse merges the buy and sell payment types, keyed by payment type ID.
+type AllPaymentTypesResponse struct {
+	Message map[string]CombinedPaymentType `json:"message"`
+}
+
+// CombinedPaymentType is a payment type together with the transaction types it supports.
+type CombinedPaymentType struct {
+	PaymentType
+	SupportedFor []string `json:"supportedFor"`
+}
+
 // PaymentRequest represents the query parameters for fetching payment types.
 type PaymentRequest struct {
 	TransactionType string `form:"type" json:"type" binding:"omitempty,oneof=buy sell"`
@@ -111,6 +139,13 @@ type DefaultSetting struct {
 	Country       string  `json:"country,omitempty"`
 }
 
+// DefaultSelection represents the default setting selected for a single country.
+type DefaultSelection struct {
+	Country  string         `json:"country"`
+	Setting  DefaultSetting `json:"setting"`
+	Fallback bool           `json:"fallback"`
+}
+
 type OnramperClient struct {
 	validate *validator.Validate
 }
@@ -142,27 +177,62 @@ type TransactionItem struct {
 	SourceCurrency        string    `json:"sourceCurrency"`
 	Country               string    `json:"country"`
 	Info                  string    `json:"info,omitempty"`
-	InAmount              float64   `json:"inAmount"`
-	OutAmount             float64   `json:"outAmount,omitempty"`
+	InAmount              Amount    `json:"inAmount"`
+	OutAmount             Amount    `json:"outAmount"`
 	TxID                  string    `json:"TxId"`
 	ExternalTransactionID string    `json:"externalTransactionId,omitempty"`
 	SK                    string    `json:"sk"`
 	Wallet                string    `json:"wallet,omitempty"`
 	PaymentMethod         string    `json:"paymentMethod"`
+	// TransactionID is the Onramper transaction ID, whichever of TxId, txId or
+	// transactionId the API populated. TxID is kept in sync for existing callers.
+	TransactionID string `json:"transactionId"`
+}
+
+// UnmarshalJSON decodes a transaction item, normalising the transaction ID aliases.
+func (t *TransactionItem) UnmarshalJSON(data []byte) error {
+	type plain TransactionItem
+	var aux struct {
+		plain
+		LowerTxID string `json:"txId"`
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+	*t = TransactionItem(aux.plain)
+	for _, id := range []string{t.TransactionID, t.TxID, aux.LowerTxID} {
+		if id != "" {
+			t.TransactionID = id
+			break
+		}
+	}
+	t.TxID = t.TransactionID
+	return nil
 }
 
 type TransactionListResponse struct {
 	Transactions []TransactionItem `json:"transactions"`
 	Limit        int               `json:"limit"`
+	// Cursor fetches the next page; empty on the last page.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// TransactionDetailsResponse is a transaction from Onramper enriched with checkout
+// details stored when it was initiated.
+type TransactionDetailsResponse struct {
+	TransactionResponse
+	RedirectURL      string     `json:"redirectUrl,omitempty"`
+	SessionExpiresAt *time.Time `json:"sessionExpiresAt,omitempty"`
 }
 
 // TransactionResponse represents the response for a single transaction.
 type TransactionResponse struct {
 	Country             string    `json:"country"`
-	InAmount            float64   `json:"inAmount"`
+	InAmount            Amount    `json:"inAmount"`
 	Onramp              string    `json:"onramp"`
 	OnrampTransactionID string    `json:"onrampTransactionId"`
-	OutAmount           float64   `json:"outAmount"`
+	OutAmount           Amount    `json:"outAmount"`
 	PaymentMethod       string    `json:"paymentMethod"`
 	SourceCurrency      string    `json:"sourceCurrency"`
 	Status              string    `json:"status"`
@@ -176,16 +246,19 @@ type TransactionResponse struct {
 
 // QuoteQueryParams represents the query parameters for the /quotes/{fiat}/{crypto} endpoint.
 type QuoteQueryParams struct {
-	Amount             float64 `form:"amount"`
+	Amount             float64 `form:"-"` // Parsed by the manager with utils.ParseFloatOrDefault.
 	PaymentMethod      string  `form:"paymentMethod"`
 	UUID               string  `form:"uuid"`
 	ClientName         string  `form:"clientName"`
 	Type               string  `form:"type"`
 	WalletAddress      string  `form:"walletAddress"`
 	IsRecurringPayment bool    `form:"isRecurringPayment"`
-	Input              string  `form:"input"`
-	Country            string  `form:"country"`
-	TxInitiation       bool    `form:"txInitiation"`
+	// Input names the currency Amount is in: "source" (the default when empty) or "destination".
+	Input        string `form:"input"`
+	Country      string `form:"country"`
+	TxInitiation bool   `form:"txInitiation"`
+	// AmountInMinorUnits means Amount is in the currency's smallest unit, e.g. cents or satoshis.
+	AmountInMinorUnits bool `form:"amountInMinorUnits"`
 }
 
 // QuoteResponse represents a single quote from the /quotes/{fiat}/{crypto} endpoint.
@@ -193,27 +266,146 @@ type QuoteResponse struct {
 	Rate                    float64              `json:"rate"`
 	NetworkFee              float64              `json:"networkFee"`
 	TransactionFee          float64              `json:"transactionFee"`
-	Payout                  float64              `json:"payout"`
+	Payout                  Amount               `json:"payout"`
 	AvailablePaymentMethods []QuotePaymentMethod `json:"availablePaymentMethods"`
 	Ramp                    string               `json:"ramp"`
 	PaymentMethod           string               `json:"paymentMethod"`
 	QuoteID                 string               `json:"quoteId"`
 	Recommendations         []string             `json:"recommendations"`
 	Errors                  []QuoteError         `json:"errors,omitempty"`
+	// ExpiresAt is when the quote stops being honoured, if the provider reports it.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// FeeBreakdown itemises further fees, present only when the provider reports them.
+	FeeBreakdown *FeeBreakdown `json:"feeBreakdown,omitempty"`
+}
+
+// FeeBreakdown lists fees charged on top of the network and transaction fees.
+type FeeBreakdown struct {
+	ProcessingFee Amount `json:"processingFee"`
+	PartnerFee    Amount `json:"partnerFee"`
+	SpreadFee     Amount `json:"spreadFee"`
+}
+
+// Total returns the sum of the itemised fees.
+func (f FeeBreakdown) Total() Amount {
+	return Amount{Decimal: f.ProcessingFee.Add(f.PartnerFee.Decimal).Add(f.SpreadFee.Decimal)}
+}
+
+// TotalFees returns the network and transaction fees plus any itemised fees.
+func (q QuoteResponse) TotalFees() Amount {
+	total := NewAmountFromFloat(q.NetworkFee).Add(NewAmountFromFloat(q.TransactionFee).Decimal)
+	if q.FeeBreakdown != nil {
+		total = total.Add(q.FeeBreakdown.Total().Decimal)
+	}
+	return Amount{Decimal: total}
+}
+
+// IsExpired reports whether the quote has expired at now. Quotes without an expiry never expire.
+func (q QuoteResponse) IsExpired(now time.Time) bool {
+	return q.ExpiresAt != nil && !now.Before(*q.ExpiresAt)
+}
+
+// HasRecommendation reports whether the quote carries a recommendation of recType.
+func (q QuoteResponse) HasRecommendation(recType string) bool {
+	for _, recommendation := range q.Recommendations {
+		if strings.EqualFold(recommendation, recType) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresFullKYC reports whether the ramp will run full KYC for this quote, which
+// Onramper implies by leaving out the LowKyc recommendation.
+func (q QuoteResponse) RequiresFullKYC() bool {
+	return !q.HasRecommendation(RecommendationLowKyc)
+}
+
+// LowKYCQuotes returns the quotes that do not require full KYC, in their original order.
+func LowKYCQuotes(quotes []QuoteResponse) []QuoteResponse {
+	lowKYC := make([]QuoteResponse, 0, len(quotes))
+	for _, quote := range quotes {
+		if !quote.RequiresFullKYC() {
+			lowKYC = append(lowKYC, quote)
+		}
+	}
+	return lowKYC
+}
+
+// GroupQuotesByRamp buckets quotes by provider, keeping their order within each bucket.
+// Quotes with provider errors are skipped, so every bucket holds usable quotes.
+func GroupQuotesByRamp(quotes []QuoteResponse) map[string][]QuoteResponse {
+	return groupUsableQuotes(quotes, func(quote QuoteResponse) string { return quote.Ramp })
+}
+
+// GroupQuotesByPaymentMethod buckets quotes by payment method ID, as GroupQuotesByRamp
+// does by provider.
+func GroupQuotesByPaymentMethod(quotes []QuoteResponse) map[string][]QuoteResponse {
+	return groupUsableQuotes(quotes, func(quote QuoteResponse) string { return quote.PaymentMethod })
+}
+
+func groupUsableQuotes(quotes []QuoteResponse, key func(QuoteResponse) string) map[string][]QuoteResponse {
+	groups := make(map[string][]QuoteResponse)
+	for _, quote := range quotes {
+		if len(quote.Errors) > 0 {
+			continue
+		}
+		groups[key(quote)] = append(groups[key(quote)], quote)
+	}
+	return groups
+}
+
+// DedupeQuotes keeps one quote per ramp and payment method pair, in the order each pair
+// first appears. The kept quote is the one with the fewest provider errors and, among
+// those, the highest payout; remaining ties keep the earlier quote.
+func DedupeQuotes(quotes []QuoteResponse) []QuoteResponse {
+	deduped := make([]QuoteResponse, 0, len(quotes))
+	index := make(map[[2]string]int)
+	for _, quote := range quotes {
+		key := [2]string{quote.Ramp, quote.PaymentMethod}
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(deduped)
+			deduped = append(deduped, quote)
+			continue
+		}
+		if quote.betterThan(deduped[i]) {
+			deduped[i] = quote
+		}
+	}
+	return deduped
+}
+
+// betterThan reports whether q should replace other as the quote for their pair.
+func (q QuoteResponse) betterThan(other QuoteResponse) bool {
+	if len(q.Errors) != len(other.Errors) {
+		return len(q.Errors) < len(other.Errors)
+	}
+	return q.Payout.GreaterThan(other.Payout)
 }
 
 // QuotePaymentMethod represents a payment method.
 type QuotePaymentMethod struct {
-	PaymentTypeID string              `json:"paymentTypeId"`
-	Name          string              `json:"name"`
-	Icon          string              `json:"icon"`
-	Details       QuotePaymentDetails `json:"details"`
+	PaymentTypeID string `json:"paymentTypeId"`
+	Name          string `json:"name"`
+	Icon          string `json:"icon"`
+	// Details is nil when Onramper omits it, as opposed to sending an empty object.
+	Details *QuotePaymentDetails `json:"details,omitempty"`
+}
+
+// Limits returns the payment method's limits and whether Onramper provided any.
+func (m QuotePaymentMethod) Limits() (PaymentLimits, bool) {
+	if m.Details == nil || m.Details.Limits == nil {
+		return PaymentLimits{}, false
+	}
+	return *m.Details.Limits, true
 }
 
 // QuotePaymentDetails represents the details of a payment method.
 type QuotePaymentDetails struct {
-	CurrencyStatus string        `json:"currencyStatus"`
-	Limits         PaymentLimits `json:"limits"`
+	CurrencyStatus string `json:"currencyStatus"`
+	// Limits is nil when the details carry no limits.
+	Limits *PaymentLimits `json:"limits,omitempty"`
 }
 
 // PaymentLimits represents the limits for a payment method.
@@ -222,6 +414,37 @@ type PaymentLimits struct {
 	AggregatedLimit LimitRange            `json:"aggregatedLimit"`
 }
 
+// aggregatedLimitKey is the limits entry holding the aggregated range across providers.
+const aggregatedLimitKey = "aggregatedLimit"
+
+// UnmarshalJSON splits the flat limits object into per-provider and aggregated limits.
+func (p *PaymentLimits) UnmarshalJSON(data []byte) error {
+	var raw map[string]LimitRange
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	p.ProviderLimits = make(map[string]LimitRange, len(raw))
+	for key, limit := range raw {
+		if key == aggregatedLimitKey {
+			p.AggregatedLimit = limit
+			continue
+		}
+		p.ProviderLimits[key] = limit
+	}
+	return nil
+}
+
+// MarshalJSON flattens provider limits back alongside the aggregated limit.
+func (p PaymentLimits) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]LimitRange, len(p.ProviderLimits)+1)
+	for key, limit := range p.ProviderLimits {
+		raw[key] = limit
+	}
+	raw[aggregatedLimitKey] = p.AggregatedLimit
+	return json.Marshal(raw)
+}
+
 // LimitRange represents the minimum and maximum limits for a payment method.
 type LimitRange struct {
 	Min float64 `json:"min"`
@@ -242,6 +465,12 @@ type SupportedAssetsResponse struct { //nolint:revive // Renaming would break AP
 	} `json:"message"`
 }
 
+// CountryAssets is the supported assets of one country, as merged by GetAssetsByCountries.
+type CountryAssets struct {
+	Country string        `json:"country"`
+	Assets  []interface{} `json:"assets"`
+}
+
 type BuyAsset struct {
 	Fiat           string   `json:"fiat"`
 	PaymentMethods []string `json:"paymentMethods"`
@@ -273,26 +502,84 @@ type AssetRequest struct {
 
 // OnrampsQuery represents the structure of the response from the Onramper API.
 type OnrampsQuery struct {
-	TransactionType string `form:"type"`
-	Source          string `form:"source"`
-	Destination     string `form:"destination"`
+	TransactionType string `form:"type" binding:"omitempty,oneof=buy sell"`
+	Source          string `form:"source" binding:"required"`
+	Destination     string `form:"destination" binding:"required"`
 	Country         string `form:"country"`
 	Subdivision     string `form:"subdivision"`
 }
 type Onramp struct {
-	Onramp                   string        `json:"onramp"`
-	Icon                     string        `json:"icon"`
-	Icons                    OnrampIconSet `json:"icons"`
-	DisplayName              string        `json:"displayName"`
-	Country                  string        `json:"country"`
-	PaymentMethods           []string      `json:"paymentMethods"`
-	RecommendedPaymentMethod string        `json:"recommendedPaymentMethod"`
-	Recommendations          []interface{} `json:"recommendations"`
+	Onramp                   string                 `json:"onramp"`
+	Icon                     string                 `json:"icon"`
+	Icons                    OnrampIconSet          `json:"icons"`
+	DisplayName              string                 `json:"displayName"`
+	Country                  string                 `json:"country"`
+	PaymentMethods           []string               `json:"paymentMethods"`
+	RecommendedPaymentMethod string                 `json:"recommendedPaymentMethod"`
+	Recommendations          []OnrampRecommendation `json:"recommendations"`
+}
+
+// HasRecommendation reports whether the onramp carries a recommendation of recType.
+func (o Onramp) HasRecommendation(recType string) bool {
+	for _, recommendation := range o.Recommendations {
+		if strings.EqualFold(recommendation.Type, recType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recommendation types Onramper attaches to onramps and quotes.
+const (
+	RecommendationBestPrice = "BestPrice"
+	RecommendationLowKyc    = "LowKyc"
+)
+
+// OnrampRecommendation is one recommendation on an onramp. Onramper sends them as
+// bare strings such as "LowKyc"; objects with a "type" field are accepted too.
+type OnrampRecommendation struct {
+	Type string `json:"type"`
+}
+
+// UnmarshalJSON accepts a recommendation string or an object with a type.
+func (r *OnrampRecommendation) UnmarshalJSON(data []byte) error {
+	var recType string
+	if json.Unmarshal(data, &recType) == nil {
+		r.Type = recType
+		return nil
+	}
+	type plain OnrampRecommendation
+	var object plain
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		return fmt.Errorf("invalid onramp recommendation %s: %w", data, err)
+	}
+	*r = OnrampRecommendation(object)
+	return nil
+}
+
+// MarshalJSON encodes the recommendation as the bare string Onramper sends.
+func (r OnrampRecommendation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Type)
 }
 
 type OnrampIconSet struct { //nolint:revive // Renaming would break API compatibility.
 	SVG string           `json:"svg"`
 	PNG OnrampImageSizes `json:"png"`
+	// Mono and Colored are theme variants, present only when Onramper provides them.
+	Mono    *IconVariant `json:"mono,omitempty"`
+	Colored *IconVariant `json:"colored,omitempty"`
+}
+
+// IconURL returns the icon for variant ("" or "default", "mono", "colored") and
+// size ("svg", "32x32", "160x160"), and whether it exists.
+func (s OnrampIconSet) IconURL(variant, size string) (string, bool) {
+	switch variant {
+	case "", IconVariantDefault:
+		return iconURL(s.SVG, s.PNG.Size32x32, s.PNG.Size160x160, size)
+	default:
+		return variantIconURL(s.Mono, s.Colored, variant, size)
+	}
 }
 
 type OnrampImageSizes struct {
@@ -305,6 +592,40 @@ type OnrampResponse struct {
 	Message []Onramp `json:"message"`
 }
 
+// CorridorQuery holds the query parameters of GET /supported/corridor. Source and
+// Destination are given in buy order: fiat first, then crypto.
+type CorridorQuery struct {
+	Source      string `form:"source" binding:"required"`
+	Destination string `form:"destination" binding:"required"`
+	Country     string `form:"country"`
+}
+
+// CorridorAvailability answers whether a fiat and a crypto can be traded in a country,
+// for buying and for selling.
+type CorridorAvailability struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Country     string            `json:"country,omitempty"`
+	Buy         CorridorDirection `json:"buy"`
+	Sell        CorridorDirection `json:"sell"`
+	// Warnings lists the sections that could not be fetched and are left empty.
+	Warnings []CorridorWarning `json:"warnings,omitempty"`
+}
+
+// CorridorWarning reports a corridor section, e.g. "sell.paymentMethods", that failed.
+type CorridorWarning struct {
+	Section string `json:"section"`
+	Message string `json:"message"`
+}
+
+// CorridorDirection lists the onramps and payment methods, with their per-onramp limits,
+// for one direction of a corridor.
+type CorridorDirection struct {
+	Available      bool            `json:"available"`
+	Onramps        []Onramp        `json:"onramps"`
+	PaymentMethods []PaymentMethod `json:"paymentMethods"`
+}
+
 // CryptoFiatResponse represents a fiat currency (e.g., USD) and its associated onramps.
 type CryptoFiatResponse struct {
 	Message []AssetMessage `json:"message"`
@@ -348,6 +669,20 @@ type OnrampMetadata struct {
 type IconSet struct { //nolint:revive // Renaming would break API compatibility.
 	SVG string     `json:"svg"`
 	PNG ImageSizes `json:"png"`
+	// Mono and Colored are theme variants, present only when Onramper provides them.
+	Mono    *IconVariant `json:"mono,omitempty"`
+	Colored *IconVariant `json:"colored,omitempty"`
+}
+
+// IconURL returns the icon for variant ("" or "default", "mono", "colored") and
+// size ("svg", "32x32", "160x160"), and whether it exists.
+func (s IconSet) IconURL(variant, size string) (string, bool) {
+	switch variant {
+	case "", IconVariantDefault:
+		return iconURL(s.SVG, s.PNG.Size32x32, s.PNG.Size160x160, size)
+	default:
+		return variantIconURL(s.Mono, s.Colored, variant, size)
+	}
 }
 
 type ImageSizes struct { //nolint:revive // Renaming would break API compatibility.
@@ -355,6 +690,48 @@ type ImageSizes struct { //nolint:revive // Renaming would break API compatibili
 	Size160x160 string `json:"160x160"`
 }
 
+// Icon variant names accepted by IconURL.
+const (
+	IconVariantDefault = "default"
+	IconVariantMono    = "mono"
+	IconVariantColored = "colored"
+)
+
+// IconVariant is an alternative themed rendering of an icon.
+type IconVariant struct {
+	SVG string     `json:"svg"`
+	PNG ImageSizes `json:"png"`
+}
+
+// variantIconURL looks size up in the mono or colored variant.
+func variantIconURL(mono, colored *IconVariant, variant, size string) (string, bool) {
+	var v *IconVariant
+	switch variant {
+	case IconVariantMono:
+		v = mono
+	case IconVariantColored:
+		v = colored
+	}
+	if v == nil {
+		return "", false
+	}
+	return iconURL(v.SVG, v.PNG.Size32x32, v.PNG.Size160x160, size)
+}
+
+// iconURL picks the URL for size from one icon rendering.
+func iconURL(svg, png32, png160, size string) (string, bool) {
+	var u string
+	switch size {
+	case "svg":
+		u = svg
+	case "32x32":
+		u = png32
+	case "160x160":
+		u = png160
+	}
+	return u, u != ""
+}
+
 type InitiateTransactionRequest struct {
 	Onramp        string  `json:"onramp"`
 	Source        string  `json:"source"`
@@ -367,7 +744,25 @@ type InitiateTransactionRequest struct {
 	Wallet        struct {
 		Address string `json:"address"`
 	} `json:"wallet"`
-	Country string `json:"country"`
+	Country         string           `json:"country"`
+	SupportedParams *SupportedParams `json:"supportedParams,omitempty"`
+}
+
+// SupportedParams carries optional widget customisation for a checkout session.
+type SupportedParams struct {
+	Theme *CheckoutTheme `json:"theme,omitempty"`
+}
+
+// CheckoutTheme represents the theming applied to the Onramper checkout widget.
+type CheckoutTheme struct {
+	IsDark             bool   `json:"isDark"`
+	ThemeName          string `json:"themeName,omitempty"`
+	PrimaryColor       string `json:"primaryColor,omitempty"`
+	SecondaryColor     string `json:"secondaryColor,omitempty"`
+	PrimaryTextColor   string `json:"primaryTextColor,omitempty"`
+	SecondaryTextColor string `json:"secondaryTextColor,omitempty"`
+	CardColor          string `json:"cardColor,omitempty"`
+	BorderRadius       *int   `json:"borderRadius,omitempty"`
 }
 
 type InitiateTransactionResponse struct {
@@ -389,16 +784,7 @@ type InitiateTransactionResponse struct {
 				Address string `json:"address"`
 			} `json:"wallet"`
 			SupportedParams struct {
-				Theme struct {
-					IsDark             bool   `json:"isDark"`
-					ThemeName          string `json:"themeName"`
-					PrimaryColor       string `json:"primaryColor"`
-					SecondaryColor     string `json:"secondaryColor"`
-					PrimaryTextColor   string `json:"primaryTextColor"`
-					SecondaryTextColor string `json:"secondaryTextColor"`
-					CardColor          string `json:"cardColor"`
-					BorderRadius       *int   `json:"borderRadius"`
-				} `json:"theme"`
+				Theme       CheckoutTheme `json:"theme"`
 				PartnerData struct {
 					RedirectURL struct {
 						Success string `json:"success"`
@@ -424,3 +810,12 @@ type InitiateTransactionResponse struct {
 type SellTransactionConfirmationResponse struct {
 	Status string `json:"status"`
 }
+
+// SellPayoutStatusResponse represents the payout progress of a confirmed sell transaction.
+type SellPayoutStatusResponse struct {
+	TransactionID   string     `json:"transactionId"`
+	PayoutStatus    string     `json:"payoutStatus"`
+	PayoutAmount    *Amount    `json:"payoutAmount,omitempty"`
+	PayoutCurrency  string     `json:"payoutCurrency,omitempty"`
+	ExpectedArrival *time.Time `json:"expectedArrival,omitempty"`
+}
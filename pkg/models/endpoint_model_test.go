@@ -0,0 +1,343 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteResponseProviderLimits(t *testing.T) {
+	mockResponse := `{
+		"ramp": "moonpay",
+		"paymentMethod": "creditcard",
+		"availablePaymentMethods": [
+			{
+				"paymentTypeId": "creditcard",
+				"name": "Credit Card",
+				"icon": "https://cdn.onramper.com/icons/payments/creditcard.svg",
+				"details": {
+					"currencyStatus": "SourceAndDestSupported",
+					"limits": {
+						"moonpay": { "min": 30, "max": 30000 },
+						"banxa": { "min": 50, "max": 15000 },
+						"aggregatedLimit": { "min": 30, "max": 30000 }
+					}
+				}
+			}
+		]
+	}`
+
+	var quote QuoteResponse
+	err := json.Unmarshal([]byte(mockResponse), &quote)
+	require.NoError(t, err)
+	require.Len(t, quote.AvailablePaymentMethods, 1)
+
+	limits, ok := quote.AvailablePaymentMethods[0].Limits()
+	require.True(t, ok)
+	assert.Len(t, limits.ProviderLimits, 2)
+	assert.Equal(t, LimitRange{Min: 30, Max: 30000}, limits.ProviderLimits["moonpay"])
+	assert.Equal(t, LimitRange{Min: 50, Max: 15000}, limits.ProviderLimits["banxa"])
+	assert.Equal(t, LimitRange{Min: 30, Max: 30000}, limits.AggregatedLimit)
+	assert.NotContains(t, limits.ProviderLimits, "aggregatedLimit")
+
+	// Provider limits survive a round trip.
+	encoded, err := json.Marshal(limits)
+	require.NoError(t, err)
+	var decoded PaymentLimits
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, limits, decoded)
+}
+
+func TestQuoteResponseIsExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	var quote QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"ramp":"moonpay","expiresAt":"2024-05-01T12:01:00Z"}`), &quote))
+	require.NotNil(t, quote.ExpiresAt)
+	assert.True(t, quote.ExpiresAt.Equal(future))
+
+	assert.False(t, quote.IsExpired(now))
+	assert.True(t, QuoteResponse{ExpiresAt: &past}.IsExpired(now))
+	assert.True(t, QuoteResponse{ExpiresAt: &now}.IsExpired(now))
+	assert.False(t, QuoteResponse{}.IsExpired(now), "quotes without an expiry never expire")
+}
+
+func TestOnrampIconSetVariants(t *testing.T) {
+	fixture := `{
+		"svg": "https://cdn.onramper.com/icons/onramps/moonpay.svg",
+		"png": {
+			"32x32": "https://cdn.onramper.com/icons/onramps/moonpay-32.png",
+			"160x160": "https://cdn.onramper.com/icons/onramps/moonpay-160.png"
+		},
+		"mono": {
+			"svg": "https://cdn.onramper.com/icons/onramps/moonpay-mono.svg",
+			"png": {"32x32": "https://cdn.onramper.com/icons/onramps/moonpay-mono-32.png"}
+		},
+		"colored": {
+			"svg": "https://cdn.onramper.com/icons/onramps/moonpay-colored.svg",
+			"png": {
+				"32x32": "https://cdn.onramper.com/icons/onramps/moonpay-colored-32.png",
+				"160x160": "https://cdn.onramper.com/icons/onramps/moonpay-colored-160.png"
+			}
+		}
+	}`
+
+	var icons OnrampIconSet
+	require.NoError(t, json.Unmarshal([]byte(fixture), &icons))
+	require.NotNil(t, icons.Mono)
+	require.NotNil(t, icons.Colored)
+
+	tests := []struct {
+		variant, size, want string
+		ok                  bool
+	}{
+		{"", "svg", "https://cdn.onramper.com/icons/onramps/moonpay.svg", true},
+		{IconVariantDefault, "160x160", "https://cdn.onramper.com/icons/onramps/moonpay-160.png", true},
+		{IconVariantMono, "svg", "https://cdn.onramper.com/icons/onramps/moonpay-mono.svg", true},
+		{IconVariantMono, "32x32", "https://cdn.onramper.com/icons/onramps/moonpay-mono-32.png", true},
+		{IconVariantMono, "160x160", "", false},
+		{IconVariantColored, "160x160", "https://cdn.onramper.com/icons/onramps/moonpay-colored-160.png", true},
+		{"neon", "svg", "", false},
+		{IconVariantColored, "64x64", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := icons.IconURL(tt.variant, tt.size)
+		assert.Equal(t, tt.ok, ok, "%s/%s", tt.variant, tt.size)
+		assert.Equal(t, tt.want, got, "%s/%s", tt.variant, tt.size)
+	}
+}
+
+func TestIconSetWithoutVariants(t *testing.T) {
+	var icons IconSet
+	require.NoError(t, json.Unmarshal([]byte(`{"svg":"a.svg","png":{"32x32":"a-32.png"}}`), &icons))
+	assert.Nil(t, icons.Mono)
+	assert.Nil(t, icons.Colored)
+
+	_, ok := icons.IconURL(IconVariantColored, "svg")
+	assert.False(t, ok)
+	got, ok := icons.IconURL("", "32x32")
+	assert.True(t, ok)
+	assert.Equal(t, "a-32.png", got)
+
+	out, err := json.Marshal(icons)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "mono")
+}
+
+func TestCryptoCurrencyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		crypto  CryptoCurrency
+		wantErr bool
+	}{
+		{name: "typical", crypto: CryptoCurrency{ID: "eth", Decimals: 18, ChainID: 1}},
+		{name: "no chain", crypto: CryptoCurrency{ID: "btc", Decimals: 8}},
+		{name: "max decimals", crypto: CryptoCurrency{ID: "x", Decimals: MaxCryptoDecimals}},
+		{name: "large chain id", crypto: CryptoCurrency{ID: "x", Decimals: 18, ChainID: 9007199254740993}},
+		{name: "too many decimals", crypto: CryptoCurrency{ID: "x", Decimals: MaxCryptoDecimals + 1}, wantErr: true},
+		{name: "negative decimals", crypto: CryptoCurrency{ID: "x", Decimals: -1}, wantErr: true},
+		{name: "negative chain id", crypto: CryptoCurrency{ID: "x", ChainID: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.crypto.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestQuotePaymentMethodDetailsPresence(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		expectDetails bool
+		expectLimits  bool
+	}{
+		{name: "absent details", method: `{"paymentTypeId":"creditcard"}`},
+		{name: "null details", method: `{"paymentTypeId":"creditcard","details":null}`},
+		{name: "empty details", method: `{"paymentTypeId":"creditcard","details":{}}`, expectDetails: true},
+		{name: "empty limits", method: `{"paymentTypeId":"creditcard","details":{"limits":{}}}`,
+			expectDetails: true, expectLimits: true},
+		{name: "aggregated limit", method: `{"paymentTypeId":"creditcard","details":{"limits":{"aggregatedLimit":{"min":10,"max":100}}}}`,
+			expectDetails: true, expectLimits: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method QuotePaymentMethod
+			require.NoError(t, json.Unmarshal([]byte(tt.method), &method))
+			assert.Equal(t, tt.expectDetails, method.Details != nil)
+
+			limits, ok := method.Limits()
+			assert.Equal(t, tt.expectLimits, ok)
+			if !ok {
+				assert.Equal(t, PaymentLimits{}, limits)
+			}
+
+			// Absent details stay absent when re-encoded.
+			encoded, err := json.Marshal(method)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectDetails, strings.Contains(string(encoded), `"details"`))
+		})
+	}
+}
+
+func TestTransactionItemIDAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		item string
+	}{
+		{name: "list field", item: `{"TxId":"01GTKAZ20PCES058TDY7WJY2PZ","status":"completed"}`},
+		{name: "single transaction field", item: `{"transactionId":"01GTKAZ20PCES058TDY7WJY2PZ","status":"completed"}`},
+		{name: "camel case field", item: `{"txId":"01GTKAZ20PCES058TDY7WJY2PZ","status":"completed"}`},
+		{name: "both fields", item: `{"TxId":"01GTKAZ20PCES058TDY7WJY2PZ","transactionId":"01GTKAZ20PCES058TDY7WJY2PZ"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list TransactionListResponse
+			require.NoError(t, json.Unmarshal([]byte(`{"transactions":[`+tt.item+`],"limit":1}`), &list))
+			require.Len(t, list.Transactions, 1)
+			item := list.Transactions[0]
+			assert.Equal(t, "01GTKAZ20PCES058TDY7WJY2PZ", item.TransactionID)
+			assert.Equal(t, "01GTKAZ20PCES058TDY7WJY2PZ", item.TxID)
+		})
+	}
+
+	var item TransactionItem
+	require.NoError(t, json.Unmarshal([]byte(`{"status":"pending"}`), &item))
+	assert.Empty(t, item.TransactionID)
+	assert.Equal(t, "pending", item.Status)
+}
+
+func TestQuoteResponseFeeBreakdown(t *testing.T) {
+	var withBreakdown QuoteResponse
+	err := json.Unmarshal([]byte(`{
+		"ramp": "moonpay",
+		"networkFee": 1.5,
+		"transactionFee": 3,
+		"feeBreakdown": {"processingFee": 0.5, "partnerFee": "1.25", "spreadFee": 0.75}
+	}`), &withBreakdown)
+	require.NoError(t, err)
+	require.NotNil(t, withBreakdown.FeeBreakdown)
+	assert.Equal(t, "1.25", withBreakdown.FeeBreakdown.PartnerFee.String())
+	assert.Equal(t, "7", withBreakdown.TotalFees().String())
+
+	var withoutBreakdown QuoteResponse
+	err = json.Unmarshal([]byte(`{"ramp": "banxa", "networkFee": 1.5, "transactionFee": 3}`), &withoutBreakdown)
+	require.NoError(t, err)
+	assert.Nil(t, withoutBreakdown.FeeBreakdown)
+	assert.Equal(t, "4.5", withoutBreakdown.TotalFees().String())
+
+	encoded, err := json.Marshal(withoutBreakdown)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "feeBreakdown")
+}
+
+func TestOnrampRecommendations(t *testing.T) {
+	var response OnrampResponse
+	err := json.Unmarshal([]byte(`{"message":[
+		{"onramp":"banxa","paymentMethods":["creditcard"],"recommendations":["LowKyc","BestPrice"]},
+		{"onramp":"moonpay","paymentMethods":["applepay"],"recommendations":[{"type":"BestPrice"}]},
+		{"onramp":"transak","paymentMethods":["sepa"]}
+	]}`), &response)
+	require.NoError(t, err)
+	require.Len(t, response.Message, 3)
+
+	banxa := response.Message[0]
+	assert.Equal(t, []OnrampRecommendation{{Type: "LowKyc"}, {Type: "BestPrice"}}, banxa.Recommendations)
+	assert.True(t, banxa.HasRecommendation(RecommendationLowKyc))
+	assert.True(t, banxa.HasRecommendation("bestprice"))
+
+	moonpay := response.Message[1]
+	assert.True(t, moonpay.HasRecommendation(RecommendationBestPrice))
+	assert.False(t, moonpay.HasRecommendation(RecommendationLowKyc))
+	assert.False(t, response.Message[2].HasRecommendation(RecommendationBestPrice))
+
+	encoded, err := json.Marshal(banxa)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"recommendations":["LowKyc","BestPrice"]`)
+
+	var invalid Onramp
+	require.Error(t, json.Unmarshal([]byte(`{"recommendations":[42]}`), &invalid))
+}
+
+func TestQuoteResponseRequiresFullKYC(t *testing.T) {
+	fixture := `[
+		{"ramp":"banxa","payout":0.0039,"recommendations":["LowKyc","BestPrice"]},
+		{"ramp":"moonpay","payout":0.0040,"recommendations":["BestPrice"]},
+		{"ramp":"transak","payout":0.0038,"recommendations":["lowkyc"]},
+		{"ramp":"mercuryo","payout":0.0037,"recommendations":[]}
+	]`
+	var quotes []QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(fixture), &quotes))
+
+	assert.False(t, quotes[0].RequiresFullKYC())
+	assert.True(t, quotes[1].RequiresFullKYC())
+	assert.False(t, quotes[2].RequiresFullKYC())
+	assert.True(t, quotes[3].RequiresFullKYC())
+
+	lowKYC := LowKYCQuotes(quotes)
+	require.Len(t, lowKYC, 2)
+	assert.Equal(t, "banxa", lowKYC[0].Ramp)
+	assert.Equal(t, "transak", lowKYC[1].Ramp)
+	assert.Empty(t, LowKYCQuotes(nil))
+}
+
+func TestGroupQuotes(t *testing.T) {
+	fixture := `[
+		{"ramp":"moonpay","payout":0.00398,"paymentMethod":"creditcard","quoteId":"01H985NH79FW951SKERQ45JMYXmoonpay"},
+		{"ramp":"fonbnk","paymentMethod":"creditcard","quoteId":"01H985NH79FW951SKERQ45JMYXfonbnk",
+			"errors":[{"type":"NoSupportedPaymentFound","errorId":6103,"message":"No supported payments found"}]},
+		{"ramp":"moonpay","payout":0.00391,"paymentMethod":"sepainstant","quoteId":"01H985NH79FW951SKERQ45JMYXmoonpaysepa"},
+		{"ramp":"fonbnk","payout":0.00385,"paymentMethod":"mobilemoney","quoteId":"01H985NH79FW951SKERQ45JMYXfonbnkmm"}
+	]`
+	var quotes []QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(fixture), &quotes))
+
+	byRamp := GroupQuotesByRamp(quotes)
+	require.Len(t, byRamp, 2)
+	require.Len(t, byRamp["moonpay"], 2)
+	assert.Equal(t, "creditcard", byRamp["moonpay"][0].PaymentMethod)
+	assert.Equal(t, "sepainstant", byRamp["moonpay"][1].PaymentMethod)
+	require.Len(t, byRamp["fonbnk"], 1, "the errored fonbnk quote is skipped")
+	assert.Equal(t, "mobilemoney", byRamp["fonbnk"][0].PaymentMethod)
+
+	byMethod := GroupQuotesByPaymentMethod(quotes)
+	require.Len(t, byMethod, 3)
+	require.Len(t, byMethod["creditcard"], 1)
+	assert.Equal(t, "moonpay", byMethod["creditcard"][0].Ramp)
+	assert.Equal(t, "fonbnk", byMethod["mobilemoney"][0].Ramp)
+	assert.Empty(t, GroupQuotesByRamp(nil))
+}
+
+func TestDedupeQuotes(t *testing.T) {
+	fixture := `[
+		{"ramp":"moonpay","payout":0.00391,"paymentMethod":"creditcard","quoteId":"moonpay-low"},
+		{"ramp":"banxa","payout":0.00380,"paymentMethod":"creditcard","quoteId":"banxa-card"},
+		{"ramp":"moonpay","payout":0.00398,"paymentMethod":"creditcard","quoteId":"moonpay-high"},
+		{"ramp":"moonpay","payout":0.00390,"paymentMethod":"sepainstant","quoteId":"moonpay-sepa"},
+		{"ramp":"banxa","payout":0.00420,"paymentMethod":"creditcard","quoteId":"banxa-errored",
+			"errors":[{"type":"LimitMismatch","errorId":6101,"message":"Amount too small"}]},
+		{"ramp":"moonpay","payout":0.00398,"paymentMethod":"creditcard","quoteId":"moonpay-tie"}
+	]`
+	var quotes []QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(fixture), &quotes))
+
+	deduped := DedupeQuotes(quotes)
+	ids := make([]string, 0, len(deduped))
+	for _, quote := range deduped {
+		ids = append(ids, quote.QuoteID)
+	}
+	assert.Equal(t, []string{"moonpay-high", "banxa-card", "moonpay-sepa"}, ids,
+		"highest payout wins, errored quotes lose to clean ones and ties keep the earlier quote")
+	assert.Empty(t, DedupeQuotes(nil))
+}
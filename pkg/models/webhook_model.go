@@ -1,14 +1,60 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransactionStatus is a lowercased Onramper transaction status, as sent in webhooks.
+type TransactionStatus string
+
+// Transaction statuses with a default KYC mapping.
+const (
+	TransactionStatusPending   TransactionStatus = "pending"
+	TransactionStatusCompleted TransactionStatus = "completed"
+	TransactionStatusFailed    TransactionStatus = "failed"
+	TransactionStatusCanceled  TransactionStatus = "canceled"
+)
+
+// Sell statuses between the user's crypto arriving and the fiat payout.
+const (
+	// TransactionStatusCryptoReceived is the event Onramper sends once a sell's crypto
+	// has been received, before the fiat is paid out.
+	TransactionStatusCryptoReceived TransactionStatus = "crypto_received"
+	// TransactionStatusProcessingPayout is stored for a sell whose crypto was received
+	// and whose payout is under way.
+	TransactionStatusProcessingPayout TransactionStatus = "processing_payout"
+)
+
+// TransactionStatusSuperseded is stored for a transaction whose expired checkout session
+// was opened again as a new transaction.
+const TransactionStatusSuperseded TransactionStatus = "superseded"
+
+// SellStatus returns the status to store for a sell webhook: the crypto-received event
+// becomes TransactionStatusProcessingPayout, any other status is returned unchanged.
+func SellStatus(rawStatus string) string {
+	if TransactionStatus(strings.ToLower(strings.TrimSpace(rawStatus))) == TransactionStatusCryptoReceived {
+		return string(TransactionStatusProcessingPayout)
+	}
+	return rawStatus
+}
+
+// IsFinal reports whether s is a status a transaction never leaves.
+func (s TransactionStatus) IsFinal() bool {
+	return s == TransactionStatusCompleted || s == TransactionStatusFailed || s == TransactionStatusCanceled
+}
 
 // WebhookPayload represents the webhook payload received from Onramper.
 type WebhookPayload struct {
 	Country             string    `json:"country"`
-	InAmount            float64   `json:"inAmount"`
+	InAmount            Amount    `json:"inAmount"`
 	Onramp              string    `json:"onramp"`
 	OnrampTransactionID string    `json:"onrampTransactionId"`
-	OutAmount           float64   `json:"outAmount"`
+	OutAmount           Amount    `json:"outAmount"`
 	PaymentMethod       string    `json:"paymentMethod"`
 	PartnerContext      string    `json:"partnerContext"`
 	SourceCurrency      string    `json:"sourceCurrency"`
@@ -19,4 +65,184 @@ type WebhookPayload struct {
 	TransactionType     string    `json:"transactionType"`
 	TransactionHash     string    `json:"transactionHash"`
 	WalletAddress       string    `json:"walletAddress"`
+	// RawPayload holds the original webhook body for replay and debugging.
+	RawPayload json.RawMessage `json:"-"`
+	// RedirectURL is the checkout link returned when the transaction was initiated.
+	RedirectURL string `json:"-"`
+	// SessionExpiresAt is when the checkout session returned at initiation expires.
+	SessionExpiresAt *time.Time `json:"-"`
+	// InitiateParams is the request that opened the checkout session, kept to refresh it.
+	InitiateParams *InitiateTransactionRequest `json:"-"`
+	// PartnerID is the partner whose Onramper client initiated the transaction.
+	PartnerID string `json:"-"`
+}
+
+// Webhook payload versions, read from the payload's "version" field.
+const (
+	// WebhookVersion1 is the flat payload Onramper sends today.
+	WebhookVersion1 = "1"
+	// WebhookVersion2 groups transaction, amount and wallet details into objects.
+	WebhookVersion2 = "2"
+	// CurrentWebhookVersion is assumed for payloads without a version.
+	CurrentWebhookVersion = WebhookVersion1
+)
+
+// ErrUnsupportedWebhookVersion is returned for payloads of a version with no parser.
+var ErrUnsupportedWebhookVersion = errors.New("unsupported webhook version")
+
+// webhookParsers decode each payload version into a WebhookPayload.
+var webhookParsers = map[string]func(body []byte) (WebhookPayload, error){
+	WebhookVersion1: decodeWebhookV1,
+	WebhookVersion2: decodeWebhookV2,
+}
+
+// DecodeWebhookPayload detects the version of a webhook body and decodes it with that
+// version's parser. The version may be sent as a string or a number.
+func DecodeWebhookPayload(body []byte) (payload WebhookPayload, err error) {
+	var envelope struct {
+		Version json.RawMessage `json:"version"`
+	}
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		return payload, err
+	}
+	version := string(bytes.Trim(envelope.Version, `"`))
+	if version == "" || version == "null" {
+		version = CurrentWebhookVersion
+	}
+	parse, ok := webhookParsers[version]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedWebhookVersion, version)
+		return payload, err
+	}
+	return parse(body)
+}
+
+func decodeWebhookV1(body []byte) (payload WebhookPayload, err error) {
+	err = json.Unmarshal(body, &payload)
+	return payload, err
+}
+
+// UnmarshalJSON decodes a version 1 payload, reading amounts with ParseLocalizedAmount
+// since some onramps forward them formatted for display.
+func (p *WebhookPayload) UnmarshalJSON(data []byte) error {
+	type plain WebhookPayload
+	var v1 struct {
+		plain
+		InAmount  localizedAmount `json:"inAmount"`
+		OutAmount localizedAmount `json:"outAmount"`
+	}
+	err := json.Unmarshal(data, &v1)
+	if err != nil {
+		return err
+	}
+	*p = WebhookPayload(v1.plain)
+	p.InAmount = Amount(v1.InAmount)
+	p.OutAmount = Amount(v1.OutAmount)
+	return nil
+}
+
+// webhookV2 is the grouped payload shape of version 2.
+type webhookV2 struct {
+	Transaction struct {
+		ID         string    `json:"id"`
+		OnrampID   string    `json:"onrampId"`
+		Type       string    `json:"type"`
+		Status     string    `json:"status"`
+		StatusDate time.Time `json:"statusDate"`
+		Hash       string    `json:"hash"`
+	} `json:"transaction"`
+	Onramp        string `json:"onramp"`
+	Country       string `json:"country"`
+	PaymentMethod string `json:"paymentMethod"`
+	Partner       struct {
+		Context string `json:"context"`
+	} `json:"partner"`
+	Amounts struct {
+		In  webhookV2Amount `json:"in"`
+		Out webhookV2Amount `json:"out"`
+	} `json:"amounts"`
+	Wallet struct {
+		Address string `json:"address"`
+	} `json:"wallet"`
+}
+
+type webhookV2Amount struct {
+	Value    localizedAmount `json:"value"`
+	Currency string          `json:"currency"`
+}
+
+func decodeWebhookV2(body []byte) (payload WebhookPayload, err error) {
+	var v2 webhookV2
+	err = json.Unmarshal(body, &v2)
+	if err != nil {
+		return payload, err
+	}
+	return WebhookPayload{
+		Country:             v2.Country,
+		InAmount:            Amount(v2.Amounts.In.Value),
+		Onramp:              v2.Onramp,
+		OnrampTransactionID: v2.Transaction.OnrampID,
+		OutAmount:           Amount(v2.Amounts.Out.Value),
+		PaymentMethod:       v2.PaymentMethod,
+		PartnerContext:      v2.Partner.Context,
+		SourceCurrency:      v2.Amounts.In.Currency,
+		Status:              v2.Transaction.Status,
+		StatusDate:          v2.Transaction.StatusDate,
+		TargetCurrency:      v2.Amounts.Out.Currency,
+		TransactionID:       v2.Transaction.ID,
+		TransactionType:     v2.Transaction.Type,
+		TransactionHash:     v2.Transaction.Hash,
+		WalletAddress:       v2.Wallet.Address,
+	}, nil
+}
+
+// FiatTransaction represents a stored row of the fiat transactions table.
+type FiatTransaction struct {
+	UserID              string     `json:"user_id"`
+	TransactionID       string     `json:"transaction_id"`
+	OnrampTransactionID string     `json:"onramp_transaction_id"`
+	Status              string     `json:"transaction_status"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	RedirectURL         string     `json:"redirect_url,omitempty"`
+	SessionExpiresAt    *time.Time `json:"session_expires_at,omitempty"`
+	TransactionType     string     `json:"transaction_type,omitempty"`
+	SourceCurrency      string     `json:"source_currency,omitempty"`
+	TargetCurrency      string     `json:"target_currency,omitempty"`
+	InAmount            *Amount    `json:"in_amount,omitempty"`
+	OutAmount           *Amount    `json:"out_amount,omitempty"`
+	Onramp              string     `json:"onramp,omitempty"`
+	PaymentMethod       string     `json:"payment_method,omitempty"`
+	WalletAddress       string     `json:"wallet_address,omitempty"`
+	// ReconciledAt is set once reconciliation stored the transaction's final status.
+	ReconciledAt *time.Time `json:"reconciled_at,omitempty"`
+	// InitiateParams is the request that opened the checkout session.
+	InitiateParams *InitiateTransactionRequest `json:"initiate_params,omitempty"`
+	// SupersededBy is the transaction that replaced this one when its session was refreshed.
+	SupersededBy string `json:"superseded_by,omitempty"`
+	// PartnerID is the partner whose Onramper client initiated the transaction; empty
+	// for the default client.
+	PartnerID string `json:"partner_id,omitempty"`
+}
+
+// FailedKYCUpdate is a row of the failed KYC updates table: a KYC status change that
+// could not be stored and is waiting to be retried.
+type FailedKYCUpdate struct {
+	ID                string    `json:"id,omitempty"`
+	UserID            string    `json:"user_id"`
+	KYCStatus         string    `json:"kyc_status"`
+	TransactionID     string    `json:"transaction_id"`
+	TransactionStatus string    `json:"transaction_status"`
+	Attempts          int       `json:"attempts"`
+	LastError         string    `json:"last_error"`
+	CreatedAt         time.Time `json:"created_at,omitempty"`
+}
+
+// WebhookAck is a row of the webhook acks table: the result of processing one webhook
+// delivery, returned again when Onramper redelivers the same event.
+type WebhookAck struct {
+	EventID   string    `json:"event_id"`
+	UserID    string    `json:"user_id"`
+	KYCStatus string    `json:"kyc_status"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
 }
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeWebhookPayloadVersions(t *testing.T) {
+	statusDate := time.Date(2023, 8, 9, 13, 15, 18, 725000000, time.UTC)
+	want := WebhookPayload{
+		Country:             "us",
+		InAmount:            NewAmountFromFloat(100),
+		Onramp:              "gatefi",
+		OnrampTransactionID: "8bf94c80-aabb-851-143835984d1d",
+		OutAmount:           NewAmountFromFloat(3.83527521),
+		PaymentMethod:       "creditcard",
+		PartnerContext:      "ctx-1",
+		SourceCurrency:      "usd",
+		Status:              "completed",
+		StatusDate:          statusDate,
+		TargetCurrency:      "sol",
+		TransactionID:       "01H7D547TESTV2RQJ52ZAB7WF7",
+		TransactionType:     "buy",
+		TransactionHash:     "0xabc",
+		WalletAddress:       "G15oy66q7cU6aNige54PxLLEfGZvRsAADjbF7D4",
+	}
+	v1 := `{"country":"us","inAmount":100,"onramp":"gatefi","onrampTransactionId":"8bf94c80-aabb-851-143835984d1d",
+		"outAmount":3.83527521,"paymentMethod":"creditcard","partnerContext":"ctx-1","sourceCurrency":"usd",
+		"status":"completed","statusDate":"2023-08-09T13:15:18.725Z","targetCurrency":"sol",
+		"transactionId":"01H7D547TESTV2RQJ52ZAB7WF7","transactionType":"buy","transactionHash":"0xabc",
+		"walletAddress":"G15oy66q7cU6aNige54PxLLEfGZvRsAADjbF7D4"}`
+	v2 := `{"version":2,
+		"transaction":{"id":"01H7D547TESTV2RQJ52ZAB7WF7","onrampId":"8bf94c80-aabb-851-143835984d1d","type":"buy",
+			"status":"completed","statusDate":"2023-08-09T13:15:18.725Z","hash":"0xabc"},
+		"onramp":"gatefi","country":"us","paymentMethod":"creditcard","partner":{"context":"ctx-1"},
+		"amounts":{"in":{"value":"100","currency":"usd"},"out":{"value":"3.83527521","currency":"sol"}},
+		"wallet":{"address":"G15oy66q7cU6aNige54PxLLEfGZvRsAADjbF7D4"}}`
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "unversioned defaults to current", body: v1},
+		{name: "explicit v1", body: `{"version":"1",` + v1[1:]},
+		{name: "v2", body: v2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := DecodeWebhookPayload([]byte(tt.body))
+			require.NoError(t, err)
+			assert.True(t, want.InAmount.Equal(payload.InAmount))
+			assert.True(t, want.OutAmount.Equal(payload.OutAmount))
+			payload.InAmount, payload.OutAmount = want.InAmount, want.OutAmount
+			assert.Equal(t, want, payload)
+		})
+	}
+
+	t.Run("localized amounts", func(t *testing.T) {
+		for _, body := range []string{
+			`{"transactionId":"tx","inAmount":"1,000.50","outAmount":"$68"}`,
+			`{"version":2,"transaction":{"id":"tx"},"amounts":{"in":{"value":"1,000.50"},"out":{"value":"$68"}}}`,
+		} {
+			payload, err := DecodeWebhookPayload([]byte(body))
+			require.NoError(t, err)
+			assert.Equal(t, "1000.5", payload.InAmount.String())
+			assert.Equal(t, "68", payload.OutAmount.String())
+		}
+	})
+
+	t.Run("invalid amount", func(t *testing.T) {
+		_, err := DecodeWebhookPayload([]byte(`{"transactionId":"tx","inAmount":"abc"}`))
+		require.Error(t, err)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := DecodeWebhookPayload([]byte(`{"version":"9","transactionId":"tx"}`))
+		require.ErrorIs(t, err, ErrUnsupportedWebhookVersion)
+	})
+}
+
+func TestSellStatus(t *testing.T) {
+	assert.Equal(t, "processing_payout", SellStatus(" Crypto_Received "))
+	assert.Equal(t, "completed", SellStatus("completed"))
+}
@@ -0,0 +1,65 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// maxConcurrentAssetRequests bounds the per-country requests GetAssetsByCountries runs at once.
+const maxConcurrentAssetRequests = 4
+
+// GetAssetsByCountries fetches supported assets one country at a time, since Onramper
+// does not paginate assets, and returns them in the order of countries. Duplicate
+// countries are fetched once; any failed country fails the whole call.
+func (h Client) GetAssetsByCountries(ctx context.Context, countries []string, assetParam models.AssetRequest) (assets []models.CountryAssets, err error) {
+	countries = uniqueCountries(countries)
+	results := make([]models.SupportedAssetsResponse, len(countries))
+	errs := make([]error, len(countries))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentAssetRequests)
+	for i, country := range countries {
+		wg.Add(1)
+		go func(i int, country string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			param := assetParam
+			param.Country = country
+			results[i], errs[i] = h.GetAssets(ctx, &param)
+		}(i, country)
+	}
+	wg.Wait()
+
+	assets = make([]models.CountryAssets, 0, len(countries))
+	for i, country := range countries {
+		if errs[i] != nil {
+			err = fmt.Errorf("failed to fetch assets for %s: %w", country, errs[i])
+			return nil, err
+		}
+		assets = append(assets, models.CountryAssets{
+			Country: country,
+			Assets:  results[i].Message.Assets,
+		})
+	}
+	return assets, err
+}
+
+// uniqueCountries lowercases countries and drops blanks and duplicates, keeping order.
+func uniqueCountries(countries []string) []string {
+	seen := make(map[string]struct{}, len(countries))
+	unique := make([]string, 0, len(countries))
+	for _, country := range countries {
+		country = strings.ToLower(strings.TrimSpace(country))
+		if _, dup := seen[country]; dup || country == "" {
+			continue
+		}
+		seen[country] = struct{}{}
+		unique = append(unique, country)
+	}
+	return unique
+}
@@ -0,0 +1,151 @@
+package onrampclient
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerCooldown is how long an open breaker rejects requests before allowing a trial.
+const DefaultBreakerCooldown = 30 * time.Second
+
+// Breaker states reported by Stats.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerStats is the state of one endpoint's circuit breaker.
+type BreakerStats struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+}
+
+// ClientStats is a point-in-time view of the client's caches and breakers.
+type ClientStats struct {
+	HealthyBaseURL string                    `json:"healthyBaseUrl"`
+	QuoteCacheSize int                       `json:"quoteCacheSize"`
+	Breakers       map[Endpoint]BreakerStats `json:"breakers"`
+}
+
+// Stats reports the client's cache sizes and the breaker state of every endpoint
+// that has been called.
+func (h Client) Stats() ClientStats {
+	return ClientStats{
+		HealthyBaseURL: h.HealthyBaseURL(),
+		QuoteCacheSize: h.quotes.size(),
+		Breakers:       h.breakers.stats(time.Now(), h.breakerCooldown()),
+	}
+}
+
+// breakerCooldown returns the configured breaker cooldown, or the default.
+func (h Client) breakerCooldown() time.Duration {
+	if h.BreakerCooldown > 0 {
+		return h.BreakerCooldown
+	}
+	return DefaultBreakerCooldown
+}
+
+// endpointOf returns the endpoint whose configured path is the longest prefix of req's path.
+func (h Client) endpointOf(req *http.Request) Endpoint {
+	var (
+		match   Endpoint
+		longest int
+	)
+	for endpoint := range DefaultPaths() {
+		p := h.path(endpoint)
+		if len(p) > longest && strings.HasPrefix(req.URL.Path, p) {
+			match, longest = endpoint, len(p)
+		}
+	}
+	return match
+}
+
+// circuitBreakers tracks consecutive upstream failures per endpoint.
+type circuitBreakers struct {
+	mu    sync.Mutex
+	state map[Endpoint]*breakerState
+}
+
+type breakerState struct {
+	failures    int
+	openedAt    time.Time
+	probeSentAt time.Time // when the half-open breaker admitted its probe
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{state: make(map[Endpoint]*breakerState)}
+}
+
+// allow reports whether a request to endpoint may be sent. Once cooldown has passed an
+// open breaker is half-open and lets a single probe through; the probe's outcome closes
+// or re-opens it. A probe that never reports back is replaced after another cooldown.
+func (b *circuitBreakers) allow(endpoint Endpoint, now time.Time, cooldown time.Duration) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.state[endpoint]
+	if !ok || s.openedAt.IsZero() {
+		return true
+	}
+	if now.Sub(s.openedAt) < cooldown {
+		return false
+	}
+	if !s.probeSentAt.IsZero() && now.Sub(s.probeSentAt) < cooldown {
+		return false
+	}
+	s.probeSentAt = now
+	return true
+}
+
+// record stores the outcome of a request, opening the breaker after threshold
+// consecutive failures. A success closes it; a failed probe re-opens it for another cooldown.
+func (b *circuitBreakers) record(endpoint Endpoint, failed bool, now time.Time, threshold int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.state[endpoint]
+	if !ok {
+		s = &breakerState{}
+		b.state[endpoint] = s
+	}
+	s.probeSentAt = time.Time{}
+	if !failed {
+		s.failures = 0
+		s.openedAt = time.Time{}
+		return
+	}
+	s.failures++
+	if s.failures >= threshold {
+		s.openedAt = now
+	}
+}
+
+func (b *circuitBreakers) stats(now time.Time, cooldown time.Duration) map[Endpoint]BreakerStats {
+	stats := make(map[Endpoint]BreakerStats)
+	if b == nil {
+		return stats
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for endpoint, s := range b.state {
+		entry := BreakerStats{State: BreakerClosed, ConsecutiveFailures: s.failures}
+		if !s.openedAt.IsZero() {
+			openedAt := s.openedAt
+			entry.OpenedAt = &openedAt
+			entry.State = BreakerOpen
+			if now.Sub(openedAt) >= cooldown {
+				entry.State = BreakerHalfOpen
+			}
+		}
+		stats[endpoint] = entry
+	}
+	return stats
+}
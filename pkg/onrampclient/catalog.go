@@ -0,0 +1,100 @@
+package onrampclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DefaultCatalogTTL is how long currency and onramp metadata responses are served from
+// memory while the currency refresher runs.
+const DefaultCatalogTTL = 10 * time.Minute
+
+// catalogCache serves currency and onramp metadata responses from memory once
+// StartCurrencyRefresher has enabled it. Entries are keyed like etagCache entries and,
+// like them, only the catalogCacheSize most recently used are kept. The refresher renews
+// the default lists; other entries are fetched again once they expire.
+type catalogCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration // zero until enabled: nothing is served from memory
+	entries *lru[catalogEntry]
+}
+
+// catalogEntry is a cached response: the body as sent and the decoded value.
+type catalogEntry struct {
+	raw       json.RawMessage
+	value     interface{}
+	expiresAt time.Time
+}
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{entries: newLRU[catalogEntry](catalogCacheSize)}
+}
+
+// enable starts serving responses from memory for ttl after they were fetched.
+func (c *catalogCache) enable(ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// get returns the entry for key while it is fresh.
+func (c *catalogCache) get(key string, now time.Time) (catalogEntry, bool) {
+	if c == nil {
+		return catalogEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries.get(key)
+	return entry, ok && c.ttl > 0 && now.Before(entry.expiresAt)
+}
+
+// put stores entry under key, fresh for the cache's TTL from now. It is a no-op until
+// the cache is enabled.
+func (c *catalogCache) put(key string, entry catalogEntry, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	entry.expiresAt = now.Add(c.ttl)
+	c.entries.put(key, entry)
+}
+
+var (
+	countryPattern     = regexp.MustCompile(`^[A-Za-z]{2}$`)
+	subdivisionPattern = regexp.MustCompile(`^([A-Za-z]{2}-)?[A-Za-z0-9]{1,3}$`)
+	localePattern      = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,2}$`)
+)
+
+// ValidLocale reports whether locale is a language tag such as "de" or "de-DE", the
+// form WithLocale accepts from callers.
+func ValidLocale(locale string) bool {
+	return localePattern.MatchString(locale)
+}
+
+// checkCatalogParams returns ErrInvalidCatalogParams unless the parameters of a
+// catalogue request, which become part of its cache key, are well formed: an ISO 3166
+// alpha-2 country, a subdivision such as "us-ny" or "ny", buy or sell and a language
+// tag. Empty country, subdivision and locale are accepted.
+func checkCatalogParams(country, subdivision, transactionType, locale string) error {
+	switch {
+	case country != "" && !countryPattern.MatchString(country):
+		return fmt.Errorf("%w: country %q", ErrInvalidCatalogParams, country)
+	case subdivision != "" && !subdivisionPattern.MatchString(subdivision):
+		return fmt.Errorf("%w: subdivision %q", ErrInvalidCatalogParams, subdivision)
+	case transactionType != transactionTypeBuy && transactionType != transactionTypeSell:
+		return fmt.Errorf("%w: type %q", ErrInvalidCatalogParams, transactionType)
+	case locale != "" && !ValidLocale(locale):
+		return fmt.Errorf("%w: locale %q", ErrInvalidCatalogParams, locale)
+	}
+	return nil
+}
@@ -3,13 +3,16 @@ package onrampclient
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
 	"go.uber.org/zap"
@@ -18,21 +21,26 @@ import (
 type OnRamperClient interface {
 	GetCurrencies(ctx context.Context, country string, subdivision string, transactionType string) (currrencies models.SupportedCurrenciesResponse, err error)
 	GetPaymentTypes(ctx context.Context, transactionType string, isRecurringPayment bool, country string) (paymentTypes models.PaymentTypesResponse, err error)
+	GetAllPaymentTypes(ctx context.Context, country string) (paymentTypes models.AllPaymentTypesResponse, err error)
 	GetPaymentsByCurrency(ctx context.Context, sourceCurrency string, transactionType string, isRecurringPayment bool, destination string, country string, subdivision string) (paymentByCurrency models.PaymentResponse, err error)
 	GetDefaults(ctx context.Context, transactionType string, conutry string, subdivision string) (defaults models.DefaultsResponse, err error)
+	GetSupportedCountries(ctx context.Context, transactionType string) (countries []string, err error)
 	GetAssets(ctx context.Context, paymentParam *models.AssetRequest) (assets models.SupportedAssetsResponse, err error)
 	GetOnramps(ctx context.Context, params *models.OnrampsQuery) (onramps models.OnrampResponse, err error)
 	GetOnrampMetadata(ctx context.Context, transactionType string) (metadata models.OnrampMetadataResponse, err error)
 	GetCryptoByFiat(ctx context.Context, source string, country string) (cryptofiat models.CryptoFiatResponse, err error)
 	GetQuotes(ctx context.Context, fiat string, crypto string, quotesParam *models.QuoteQueryParams) (quotes []models.QuoteResponse, err error)
+	GetQuoteByID(ctx context.Context, quoteID string) (quote models.QuoteResponse, err error)
 	GetTransactionByID(ctx context.Context, transactionID string) (transactionid models.TransactionResponse, err error)
 	ListTransactions(ctx context.Context, ListTransactions models.TransactionListQuery) (transactionlist models.TransactionListResponse, err error)
 	InitiateTransaction(ctx context.Context, payload models.InitiateTransactionRequest) (transaction models.InitiateTransactionResponse, err error)
-	ConfirmSellTransaction(ctx context.Context, txType string) (confirmation models.SellTransactionConfirmationResponse, err error)
+	ConfirmSellTransaction(ctx context.Context, transactionID string, txHash string) (confirmation models.SellTransactionConfirmationResponse, err error)
+	GetSellPayoutStatus(ctx context.Context, transactionID string) (payout models.SellPayoutStatusResponse, err error)
 }
 
 const (
-	transactionTypeBuy = "buy"
+	transactionTypeBuy  = "buy"
+	transactionTypeSell = "sell"
 )
 
 // Client manages communication with the Onramper API.
@@ -42,6 +50,50 @@ type Client struct {
 	WebhookSecret string
 	HTTPClient    *http.Client
 	Logger        *zap.Logger
+	// IconBaseURL, when set, replaces the CDN host in returned icon URLs.
+	IconBaseURL string
+	// Paths overrides the default path per endpoint (see DefaultPaths).
+	Paths map[Endpoint]string
+	// MaxResponseBytes caps upstream response bodies (DefaultMaxResponseBytes when zero).
+	MaxResponseBytes int64
+	// BaseURLs lists the primary and fallback base URLs in order; BaseURL is used when empty.
+	BaseURLs []string
+	// MaxRetries is how often a failing idempotent request is retried before failing over.
+	MaxRetries int
+	// RetryBackoff is the pause between retries (DefaultRetryBackoff when zero).
+	RetryBackoff time.Duration
+	// QuoteTTL is how long quotes without an expiry stay retrievable by ID (DefaultQuoteTTL when zero).
+	QuoteTTL time.Duration
+	// QuotesTimeout bounds a whole GetQuotes call, so a slow quote fails before the
+	// client-wide timeout; zero applies no extra deadline.
+	QuotesTimeout time.Duration
+	// FlightTimeout bounds a GET request shared by concurrent identical callers, which
+	// no single caller's deadline applies to (DefaultFlightTimeout when zero).
+	FlightTimeout time.Duration
+	// ValidateQuoteAssets rejects quotes whose fiat and crypto are swapped, checked against
+	// the cached currency list.
+	ValidateQuoteAssets bool
+	// BreakerThreshold is how many consecutive failures open an endpoint's circuit breaker;
+	// zero disables breakers.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker rejects requests (DefaultBreakerCooldown when zero).
+	BreakerCooldown time.Duration
+	// Locale is sent as Accept-Language on currency, payment method and onramp metadata
+	// calls so Onramper localises names and labels; WithLocale overrides it per request.
+	Locale string
+	// Clock used for cache expiry; defaults to time.Now.
+	Clock func() time.Time
+
+	health     *baseURLHealth
+	lifecycle  *clientLifecycle
+	quotes     *quoteStore
+	breakers   *circuitBreakers
+	assetTypes *assetTypesCache
+	flights    *requestFlights
+	etags      *etagCache
+	catalog    *catalogCache
+	// ticker starts the currency refresher's ticker; nil uses time.NewTicker.
+	ticker func(interval time.Duration) (ticks <-chan time.Time, stop func())
 }
 
 // NewClient initializes a new Onramper API client.
@@ -53,13 +105,76 @@ func NewClient(baseURL, apiKey string, webhookSecret string, logger *zap.Logger)
 		WebhookSecret: webhookSecret,
 		HTTPClient:    &http.Client{},
 		Logger:        logger,
+		health:        &baseURLHealth{},
+		lifecycle:     newClientLifecycle(),
+		quotes:        newQuoteStore(),
+		breakers:      newCircuitBreakers(),
+		assetTypes:    newAssetTypesCache(),
+		flights:       newRequestFlights(),
+		etags:         newETagCache(),
+		catalog:       newCatalogCache(),
 	}
 }
 
+// DisableHTTP2 makes the client speak HTTP/1.1 only, for proxies that mishandle HTTP/2.
+// HTTPClient is replaced by a copy whose transport is a clone of the current one
+// (http.DefaultTransport when unset) with HTTP/2 upgrades turned off. Transports that
+// are not an *http.Transport are left as they are.
+func (h *Client) DisableHTTP2() {
+	var httpClient http.Client
+	if h.HTTPClient != nil {
+		httpClient = *h.HTTPClient
+	}
+	roundTripper := httpClient.Transport
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	transport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		h.Logger.Warn("Cannot disable HTTP/2 on a custom transport", zap.String("transport", fmt.Sprintf("%T", roundTripper)))
+		return
+	}
+	transport = transport.Clone()
+	transport.ForceAttemptHTTP2 = false
+	// A non-nil, empty map stops the transport from negotiating h2 over TLS.
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	httpClient.Transport = transport
+	h.HTTPClient = &httpClient
+}
+
+// now returns the current time from the injected clock, or time.Now.
+func (h Client) now() time.Time {
+	if h.Clock != nil {
+		return h.Clock()
+	}
+	return time.Now()
+}
+
 func (h Client) GetCurrencies(ctx context.Context, country string, subdivision string, transactionType string) (currrencies models.SupportedCurrenciesResponse, err error) {
+	_, currrencies, err = h.GetCurrenciesRaw(ctx, country, subdivision, transactionType)
+	return currrencies, err
+}
+
+// GetCurrenciesRaw is GetCurrencies that also returns the response body exactly as Onramper
+// sent it, before icon URLs are rewritten. raw is nil when the request fails.
+func (h Client) GetCurrenciesRaw(ctx context.Context, country string, subdivision string, transactionType string) (raw json.RawMessage, currrencies models.SupportedCurrenciesResponse, err error) {
+	return h.fetchCurrencies(ctx, country, subdivision, transactionType, true)
+}
+
+// fetchCurrencies implements GetCurrenciesRaw. With fromCache unset the response cache is
+// bypassed, so the refresher can renew an entry that is still fresh.
+func (h Client) fetchCurrencies(
+	ctx context.Context,
+	country, subdivision, transactionType string,
+	fromCache bool,
+) (raw json.RawMessage, currrencies models.SupportedCurrenciesResponse, err error) {
+	locale, _ := LocaleFromContext(ctx)
+	if err = checkCatalogParams(country, subdivision, transactionType, locale); err != nil {
+		return raw, currrencies, err
+	}
 	// Construct API request URL with query parameters
 	h.Logger.Info("Fetching currencies", zap.String("url", h.BaseURL))
-	apiURL := fmt.Sprintf("%s/supported?type=%s", h.BaseURL, transactionType)
+	apiURL := fmt.Sprintf("%s?type=%s", h.endpointURL(EndpointCurrencies), transactionType)
 	if country != "" {
 		apiURL += "&country=" + country
 	}
@@ -72,41 +187,54 @@ func (h Client) GetCurrencies(ctx context.Context, country string, subdivision s
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		h.Logger.Error("Failed to create request", zap.Error(err))
-		return currrencies, err
+		return raw, currrencies, err
 	}
 
 	req.Header.Add("Authorization", h.APIKey)
+	h.setLocaleHeader(req)
+	etagKey, cached, hasCached := h.conditional(req)
+	if entry, ok := h.catalog.get(etagKey, h.now()); ok && fromCache {
+		return entry.raw, cloneCurrencies(entry.value.(models.SupportedCurrenciesResponse)), nil //nolint:forcetypeassert // stored below.
+	}
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch currencies", zap.Error(err))
-		return currrencies, err
+		return raw, currrencies, err
 	}
 	defer resp.Body.Close()
 	h.Logger.Info("Received response", zap.Int("status", resp.StatusCode))
+	if notModified(resp, hasCached) {
+		h.catalog.put(etagKey, catalogEntry{raw: cached.raw, value: cached.value}, h.now())
+		return cached.raw, cloneCurrencies(cached.value.(models.SupportedCurrenciesResponse)), nil //nolint:forcetypeassert // stored below.
+	}
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
-			return currrencies, err
+			return raw, currrencies, err
 		}
-		err = fmt.Errorf("unable to get currencies with status code: %d - message: %s", resp.StatusCode, string(body))
-		return currrencies, err
+		err = authError(resp.StatusCode, fmt.Errorf("unable to get currencies with status code: %d - message: %s", resp.StatusCode, string(body)))
+		return raw, currrencies, err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&currrencies)
+	raw, err = h.decodeRawJSON(resp, EndpointCurrencies, &currrencies)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
-		return currrencies, err
+		return raw, currrencies, err
 	}
-	return currrencies, err
+	h.logCurrencyAnomalies(currrencies.Message.Crypto)
+	h.rewriteCurrencyIcons(&currrencies)
+	h.etags.put(etagKey, etagEntry{etag: resp.Header.Get("ETag"), raw: raw, value: cloneCurrencies(currrencies)})
+	h.catalog.put(etagKey, catalogEntry{raw: raw, value: cloneCurrencies(currrencies)}, h.now())
+	return raw, currrencies, err
 }
 func (h Client) GetPaymentTypes(ctx context.Context, transactionType string, isRecurringPayment bool, country string) (paymentTypes models.PaymentTypesResponse, err error) {
 	// Construct API request URL with query parameters
-	apiURL := fmt.Sprintf("%s/supported/payment-types?type=%s", h.BaseURL, transactionType)
+	apiURL := fmt.Sprintf("%s?type=%s", h.endpointURL(EndpointPaymentTypes), transactionType)
 	if country != "" {
 		apiURL += "&country=" + country
 	}
@@ -120,13 +248,14 @@ func (h Client) GetPaymentTypes(ctx context.Context, transactionType string, isR
 	}
 
 	req.Header.Add("Authorization", h.APIKey)
+	h.setLocaleHeader(req)
 	if transactionType == transactionTypeBuy {
 		// Convert bool to string: "true" / "false"
 		recurringValue := strconv.FormatBool(isRecurringPayment)
 		req.Header.Add("X-Is-Recurringpayment", recurringValue)
 	}
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch payment types", zap.Error(err))
 		return paymentTypes, err
@@ -138,7 +267,7 @@ func (h Client) GetPaymentTypes(ctx context.Context, transactionType string, isR
 	// Check for non-OK status
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return paymentTypes, err
@@ -150,7 +279,7 @@ func (h Client) GetPaymentTypes(ctx context.Context, transactionType string, isR
 		return paymentTypes, err
 	}
 	// Parse the JSON response
-	err = json.NewDecoder(resp.Body).Decode(&paymentTypes)
+	err = h.decodeJSON(resp, EndpointPaymentTypes, &paymentTypes)
 	if err != nil {
 		h.Logger.Error("Failed to decode payment types response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
@@ -162,8 +291,8 @@ func (h Client) GetPaymentTypes(ctx context.Context, transactionType string, isR
 func (h Client) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string, transactionType string, isRecurringPayment bool, destination string, country string, subdivision string) (paymentByCurrency models.PaymentResponse, err error) {
 
 	apiURL := fmt.Sprintf(
-		"%s/supported/payment-types/%s?type=%s&destination=%s&isRecurringPayment=%t",
-		h.BaseURL,
+		"%s/%s?type=%s&destination=%s&isRecurringPayment=%t",
+		h.endpointURL(EndpointPaymentTypes),
 		sourceCurrency,
 		transactionType,
 		destination,
@@ -187,12 +316,13 @@ func (h Client) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string
 
 	// Always add your API key
 	req.Header.Add("Authorization", h.APIKey)
+	h.setLocaleHeader(req)
 	if transactionType == transactionTypeBuy {
 		req.Header.Add("X-Is-Recurringpayment", strconv.FormatBool(isRecurringPayment))
 	}
 
 	// Perform the request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch payment types by currency", zap.Error(err))
 		return paymentByCurrency, err
@@ -204,7 +334,7 @@ func (h Client) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string
 	// Handle non-OK status codes
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return paymentByCurrency, err
@@ -215,7 +345,7 @@ func (h Client) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string
 		)
 		return paymentByCurrency, err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&paymentByCurrency)
+	err = h.decodeJSON(resp, EndpointPaymentTypes, &paymentByCurrency)
 	if err != nil {
 		h.Logger.Error("Failed to decode payment types response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
@@ -225,7 +355,7 @@ func (h Client) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string
 }
 func (h Client) GetDefaults(ctx context.Context, transactionType string, country string, subdivision string) (defaults models.DefaultsResponse, err error) {
 	// Construct API request URL with query parameters
-	apiURL := fmt.Sprintf("%s/supported/defaults/all?type=%s", h.BaseURL, transactionType)
+	apiURL := fmt.Sprintf("%s?type=%s", h.endpointURL(EndpointDefaults), transactionType)
 	if country != "" {
 		apiURL += "&country=" + country
 	}
@@ -243,7 +373,7 @@ func (h Client) GetDefaults(ctx context.Context, transactionType string, country
 
 	req.Header.Add("Authorization", h.APIKey)
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch currencies", zap.Error(err))
 		return defaults, err
@@ -253,7 +383,7 @@ func (h Client) GetDefaults(ctx context.Context, transactionType string, country
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return defaults, err
@@ -261,7 +391,7 @@ func (h Client) GetDefaults(ctx context.Context, transactionType string, country
 		err = fmt.Errorf("unable to get currencies with status code: %d - message: %s", resp.StatusCode, string(body))
 		return defaults, err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&defaults)
+	err = h.decodeJSON(resp, EndpointDefaults, &defaults)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
@@ -291,7 +421,7 @@ func (h Client) GetAssets(ctx context.Context, paymentParam *models.AssetRequest
 		params.Add("subdivision", paymentParam.Subdivision)
 	}
 
-	apiURL := fmt.Sprintf("%s/supported/assets?%s", h.BaseURL, params.Encode())
+	apiURL := fmt.Sprintf("%s?%s", h.endpointURL(EndpointAssets), params.Encode())
 
 	h.Logger.Info("Fetching supported assets", zap.String("url", apiURL))
 
@@ -303,7 +433,7 @@ func (h Client) GetAssets(ctx context.Context, paymentParam *models.AssetRequest
 
 	req.Header.Add("Authorization", h.APIKey)
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch supported assets", zap.Error(err))
 		return assets, err
@@ -315,7 +445,7 @@ func (h Client) GetAssets(ctx context.Context, paymentParam *models.AssetRequest
 	// Handle non-OK status
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return assets, err
@@ -323,7 +453,7 @@ func (h Client) GetAssets(ctx context.Context, paymentParam *models.AssetRequest
 		err = fmt.Errorf("unable to get assets with status code: %d - message: %s", resp.StatusCode, string(body))
 		return assets, err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&assets)
+	err = h.decodeJSON(resp, EndpointAssets, &assets)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
@@ -344,7 +474,7 @@ func (h Client) GetOnramps(ctx context.Context, params *models.OnrampsQuery) (on
 	if params.Subdivision != "" {
 		queryParams.Add("subdivision", params.Subdivision)
 	}
-	apiURL := fmt.Sprintf("%s/supported/onramps?%s", h.BaseURL, queryParams.Encode())
+	apiURL := fmt.Sprintf("%s?%s", h.endpointURL(EndpointOnramps), queryParams.Encode())
 
 	// Logging for debug
 	h.Logger.Info("Fetching supported onramps", zap.String("url", apiURL))
@@ -357,9 +487,10 @@ func (h Client) GetOnramps(ctx context.Context, params *models.OnrampsQuery) (on
 	}
 	// Add authorization header
 	req.Header.Add("Authorization", h.APIKey)
+	h.setLocaleHeader(req)
 
 	// Execute request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch supported onramps", zap.Error(err))
 		return onramps, err
@@ -373,7 +504,7 @@ func (h Client) GetOnramps(ctx context.Context, params *models.OnrampsQuery) (on
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return onramps, err
@@ -382,17 +513,27 @@ func (h Client) GetOnramps(ctx context.Context, params *models.OnrampsQuery) (on
 		return onramps, err
 	}
 	// Decode successful response
-	err = json.NewDecoder(resp.Body).Decode(&onramps)
+	err = h.decodeJSON(resp, EndpointOnramps, &onramps)
 	if err != nil {
 		h.Logger.Error("Failed to decode onramps response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
 		return onramps, err
 	}
+	h.rewriteOnrampIcons(&onramps)
 	return onramps, err
 }
 func (h Client) GetOnrampMetadata(ctx context.Context, transactionType string) (metadata models.OnrampMetadataResponse, err error) {
+	return h.fetchOnrampMetadata(ctx, transactionType, true)
+}
+
+// fetchOnrampMetadata implements GetOnrampMetadata; fromCache is as for fetchCurrencies.
+func (h Client) fetchOnrampMetadata(ctx context.Context, transactionType string, fromCache bool) (metadata models.OnrampMetadataResponse, err error) {
+	locale, _ := LocaleFromContext(ctx)
+	if err = checkCatalogParams("", "", transactionType, locale); err != nil {
+		return metadata, err
+	}
 	// Construct API request URL with query parameters
-	apiURL := fmt.Sprintf("%s/supported/onramps/all?type=%s", h.BaseURL, transactionType)
+	apiURL := fmt.Sprintf("%s?type=%s", h.endpointURL(EndpointOnrampMetadata), transactionType)
 	h.Logger.Info("Fetching onramp metadata", zap.String("url", apiURL))
 
 	// Prepare Onramper API request
@@ -404,9 +545,14 @@ func (h Client) GetOnrampMetadata(ctx context.Context, transactionType string) (
 
 	// Add your API key (if required)
 	req.Header.Add("Authorization", h.APIKey)
+	h.setLocaleHeader(req)
+	etagKey, cached, hasCached := h.conditional(req)
+	if entry, ok := h.catalog.get(etagKey, h.now()); ok && fromCache {
+		return cloneMetadata(entry.value.(models.OnrampMetadataResponse)), nil //nolint:forcetypeassert // stored below.
+	}
 
 	// Execute the request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch onramp metadata", zap.Error(err))
 		return metadata, err
@@ -414,11 +560,15 @@ func (h Client) GetOnrampMetadata(ctx context.Context, transactionType string) (
 	defer resp.Body.Close()
 
 	h.Logger.Info("Received response", zap.Int("status", resp.StatusCode))
+	if notModified(resp, hasCached) {
+		h.catalog.put(etagKey, catalogEntry{value: cached.value}, h.now())
+		return cloneMetadata(cached.value.(models.OnrampMetadataResponse)), nil //nolint:forcetypeassert // stored below.
+	}
 
 	// Handle non-200 status codes
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return metadata, err
@@ -428,17 +578,20 @@ func (h Client) GetOnrampMetadata(ctx context.Context, transactionType string) (
 		)
 		return metadata, err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	err = h.decodeJSON(resp, EndpointOnrampMetadata, &metadata)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %s", err.Error())
 		return metadata, err
 	}
+	h.rewriteMetadataIcons(&metadata)
+	h.etags.put(etagKey, etagEntry{etag: resp.Header.Get("ETag"), value: cloneMetadata(metadata)})
+	h.catalog.put(etagKey, catalogEntry{value: cloneMetadata(metadata)}, h.now())
 	return metadata, err
 }
 func (h Client) GetCryptoByFiat(ctx context.Context, source string, country string) (cryptofiat models.CryptoFiatResponse, err error) {
 
-	apiURL := fmt.Sprintf("%s/supported/crypto?source=%s", h.BaseURL, source)
+	apiURL := fmt.Sprintf("%s?source=%s", h.endpointURL(EndpointCrypto), source)
 	if country != "" {
 		apiURL += "&country=" + country
 	}
@@ -455,7 +608,7 @@ func (h Client) GetCryptoByFiat(ctx context.Context, source string, country stri
 	req.Header.Add("Authorization", h.APIKey)
 
 	// Make the request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch crypto by fiat", zap.Error(err))
 		return cryptofiat, err
@@ -468,7 +621,7 @@ func (h Client) GetCryptoByFiat(ctx context.Context, source string, country stri
 	// Handle non-200 status codes
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return cryptofiat, err
@@ -477,7 +630,7 @@ func (h Client) GetCryptoByFiat(ctx context.Context, source string, country stri
 		return cryptofiat, err
 	}
 	// Decode the JSON into our CryptoFiatResponse model
-	err = json.NewDecoder(resp.Body).Decode(&cryptofiat)
+	err = h.decodeJSON(resp, EndpointCrypto, &cryptofiat)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %w", err)
@@ -490,7 +643,7 @@ func (h Client) GetCryptoByFiat(ctx context.Context, source string, country stri
 func (h Client) buildGetQuotesURL(fiat, crypto string, quotesParam *models.QuoteQueryParams) string {
 	q := url.Values{}
 	if quotesParam.Amount > 0 {
-		q.Set("amount", strconv.FormatFloat(quotesParam.Amount, 'f', -1, 64))
+		q.Set("amount", formatQuoteAmount(fiat, quotesParam))
 	}
 	if quotesParam.PaymentMethod != "" {
 		q.Set("paymentMethod", quotesParam.PaymentMethod)
@@ -523,12 +676,12 @@ func (h Client) buildGetQuotesURL(fiat, crypto string, quotesParam *models.Quote
 	// Determine path based on transaction type
 	var path string
 	if quotesParam.Type == transactionTypeBuy {
-		path = fmt.Sprintf("/quotes/%s/%s", fiat, crypto)
+		path = fmt.Sprintf("/%s/%s", fiat, crypto)
 	} else {
-		path = fmt.Sprintf("/quotes/%s/%s", crypto, fiat)
+		path = fmt.Sprintf("/%s/%s", crypto, fiat)
 	}
 
-	apiURL := h.BaseURL + path
+	apiURL := h.endpointURL(EndpointQuotes) + path
 	if len(q) > 0 {
 		apiURL += "?" + q.Encode()
 	}
@@ -536,9 +689,47 @@ func (h Client) buildGetQuotesURL(fiat, crypto string, quotesParam *models.Quote
 }
 
 func (h Client) GetQuotes(ctx context.Context, fiat string, crypto string, quotesParam *models.QuoteQueryParams) (quotes []models.QuoteResponse, err error) {
+	_, quotes, err = h.GetQuotesRaw(ctx, fiat, crypto, quotesParam)
+	return quotes, err
+}
+
+// GetQuotesRaw is GetQuotes that also returns the response body exactly as Onramper sent it,
+// so callers can read fields QuoteResponse does not model yet. raw is nil when the request
+// fails before a successful response is decoded.
+func (h Client) GetQuotesRaw(ctx context.Context, fiat string, crypto string, quotesParam *models.QuoteQueryParams) (raw json.RawMessage, quotes []models.QuoteResponse, err error) {
 	if fiat == "" || crypto == "" {
 		err = errors.New("both fiat and crypto parameters are required")
-		return quotes, err
+		return raw, quotes, err
+	}
+	quotesParam, err = normalizeQuoteParams(quotesParam)
+	if err != nil {
+		return raw, quotes, err
+	}
+	if quotesParam.AmountInMinorUnits {
+		quotesParam.Amount, err = h.majorUnitAmount(ctx, fiat, crypto, quotesParam)
+		if err != nil {
+			return raw, quotes, err
+		}
+		quotesParam.AmountInMinorUnits = false
+	}
+	if h.QuotesTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.QuotesTimeout)
+		defer cancel()
+	}
+
+	if h.ValidateQuoteAssets {
+		err = h.validateQuoteAssets(ctx, fiat, crypto)
+		if err != nil {
+			return raw, quotes, err
+		}
+	}
+
+	if quotesParam.PaymentMethod != "" {
+		err = h.validatePaymentMethod(ctx, quotesParam)
+		if err != nil {
+			return raw, quotes, err
+		}
 	}
 
 	apiURL := h.buildGetQuotesURL(fiat, crypto, quotesParam)
@@ -553,15 +744,16 @@ func (h Client) GetQuotes(ctx context.Context, fiat string, crypto string, quote
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		h.Logger.Error("Failed to create request", zap.Error(err))
-		return quotes, err
+		return raw, quotes, err
 	}
 	req.Header.Add("Authorization", h.APIKey)
 	req.Header.Set("Accept", "application/json")
+	setClientIPHeader(req)
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch quotes", zap.Error(err))
-		return quotes, err
+		return raw, quotes, err
 	}
 	defer resp.Body.Close()
 
@@ -569,34 +761,49 @@ func (h Client) GetQuotes(ctx context.Context, fiat string, crypto string, quote
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
-			return quotes, err
+			return raw, quotes, err
 		}
-		err = fmt.Errorf("unable to get quotes: %d - %s", resp.StatusCode, string(body))
-		return quotes, err
+		err = quoteError(resp.StatusCode, body)
+		return raw, quotes, err
 	}
-	err = json.NewDecoder(resp.Body).Decode(&quotes)
+	raw, err = h.decodeRawJSON(resp, EndpointQuotes, &quotes)
 	if err != nil {
 		h.Logger.Error("Failed to decode quotes", zap.Error(err))
 		err = fmt.Errorf("failed to decode quotes: %w", err)
-		return quotes, err
+		return raw, quotes, err
 	}
 
+	h.quotes.put(quotes, time.Now(), h.quoteTTL())
+
+	failed := allQuotesFailed(quotes)
+	if quotesParam.PaymentMethod != "" && !hasUsableQuote(quotes) {
+		h.Logger.Error("No quotes for payment method", zap.String("payment_method", quotesParam.PaymentMethod))
+		err = fmt.Errorf("%w: %s %s/%s", ErrPaymentMethodUnsupported, quotesParam.PaymentMethod, fiat, crypto)
+		if failed != nil {
+			err = fmt.Errorf("%w: %w", err, failed)
+		}
+		return raw, quotes, err
+	}
 	if len(quotes) == 0 {
 		h.Logger.Error("Onramper returned empty quotes")
-		err = errors.New("no quotes found")
-		return quotes, err
+		err = ErrNoQuotes
+		return raw, quotes, err
+	}
+	if failed != nil {
+		h.Logger.Error("Every quote failed", zap.Error(failed))
+		return raw, quotes, failed
 	}
 
 	h.Logger.Info("Quotes response",
 		zap.Int("quote_count", len(quotes)))
 
-	return quotes, err
+	return raw, quotes, err
 }
 func (h Client) GetTransactionByID(ctx context.Context, transactionID string) (transactionid models.TransactionResponse, err error) {
-	apiURL := fmt.Sprintf("%s/transactions/%s", h.BaseURL, transactionID)
+	apiURL := fmt.Sprintf("%s/%s", h.endpointURL(EndpointTransactions), transactionID)
 
 	h.Logger.Info("Fetching transaction details", zap.String("url", apiURL))
 
@@ -612,7 +819,7 @@ func (h Client) GetTransactionByID(ctx context.Context, transactionID string) (t
 	req.Header.Set("X-Onramper-Secret", h.WebhookSecret)
 
 	// Make the request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to fetch transaction", zap.Error(err))
 		return transactionid, err
@@ -624,17 +831,17 @@ func (h Client) GetTransactionByID(ctx context.Context, transactionID string) (t
 	// Handle error responses
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return transactionid, err
 		}
-		err = fmt.Errorf("unable to get transaction: %d - %s", resp.StatusCode, string(body))
+		err = transactionError(resp.StatusCode, body)
 		return transactionid, err
 	}
 	// var response models.TransactionResponse // This was unused, transactionid is the return variable
 	// Decode into struct
-	err = json.NewDecoder(resp.Body).Decode(&transactionid)
+	err = h.decodeJSON(resp, EndpointTransactions, &transactionid)
 	if err != nil {
 		h.Logger.Error("Failed to decode transaction response", zap.Error(err))
 		err = fmt.Errorf("failed to decode transaction response: %w", err)
@@ -647,7 +854,7 @@ func (h Client) GetTransactionByID(ctx context.Context, transactionID string) (t
 	return transactionid, err
 }
 func (h Client) ListTransactions(ctx context.Context, query models.TransactionListQuery) (transactionlist models.TransactionListResponse, err error) {
-	apiURL := fmt.Sprintf("%s/transactions", h.BaseURL)
+	apiURL := h.endpointURL(EndpointTransactions)
 	// Construct query string
 	params := url.Values{}
 	if query.StartDateTime != "" {
@@ -684,7 +891,7 @@ func (h Client) ListTransactions(ctx context.Context, query models.TransactionLi
 	req.Header.Set("X-Onramper-Secret", h.WebhookSecret)
 
 	// Execute request
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to perform request", zap.Error(err))
 		return transactionlist, err
@@ -696,7 +903,7 @@ func (h Client) ListTransactions(ctx context.Context, query models.TransactionLi
 	// Handle non-200
 	if resp.StatusCode != http.StatusOK {
 		var body []byte
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read error response body for ListTransactions", zap.Error(err))
 			// err is already set from ReadAll, so we can return it
@@ -706,7 +913,7 @@ func (h Client) ListTransactions(ctx context.Context, query models.TransactionLi
 		return transactionlist, err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&transactionlist)
+	err = h.decodeJSON(resp, EndpointTransactions, &transactionlist)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode transaction list: %w", err)
@@ -720,7 +927,7 @@ func (h Client) ListTransactions(ctx context.Context, query models.TransactionLi
 }
 func (h Client) InitiateTransaction(ctx context.Context, payload models.InitiateTransactionRequest) (transaction models.InitiateTransactionResponse, err error) {
 	// Construct API request URL
-	apiURL := fmt.Sprintf("%s/checkout/intent", h.BaseURL)
+	apiURL := h.endpointURL(EndpointCheckoutIntent)
 	h.Logger.Info("Initiating transaction", zap.String("url", apiURL))
 
 	// Marshal payload to JSON
@@ -740,8 +947,9 @@ func (h Client) InitiateTransaction(ctx context.Context, payload models.Initiate
 	req.Header.Add("Authorization", h.APIKey)
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
+	setClientIPHeader(req)
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to initiate transaction", zap.Error(err))
 		return transaction, err
@@ -751,7 +959,7 @@ func (h Client) InitiateTransaction(ctx context.Context, payload models.Initiate
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read response body", zap.Error(err))
 			return transaction, err
@@ -760,7 +968,7 @@ func (h Client) InitiateTransaction(ctx context.Context, payload models.Initiate
 		return transaction, err
 	}
 	// Parse the JSON response body into the model struct
-	err = json.NewDecoder(resp.Body).Decode(&transaction)
+	err = h.decodeJSON(resp, EndpointCheckoutIntent, &transaction)
 	if err != nil {
 		h.Logger.Error("Failed to decode response", zap.Error(err))
 		err = fmt.Errorf("failed to decode response: %s", err.Error())
@@ -778,9 +986,23 @@ func (h Client) InitiateTransaction(ctx context.Context, payload models.Initiate
 	)
 	return transaction, err
 }
-func (h Client) ConfirmSellTransaction(ctx context.Context, txType string) (confirmation models.SellTransactionConfirmationResponse, err error) {
+
+// IdempotencyKeyHeader marks a request whose repeats Onramper treats as one.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// confirmIdempotencyKey derives the Idempotency-Key of a sell confirmation, so every
+// confirm of the same transaction and transfer carries the same key.
+func confirmIdempotencyKey(transactionID, txHash string) string {
+	sum := sha256.Sum256([]byte("confirm-sell\n" + transactionID + "\n" + txHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfirmSellTransaction confirms the sell transactionID once its crypto was sent in txHash.
+// The request carries an Idempotency-Key derived from both, so a retried confirm is
+// treated as the same confirm by Onramper and may be retried by send like a GET.
+func (h Client) ConfirmSellTransaction(ctx context.Context, transactionID string, txHash string) (confirmation models.SellTransactionConfirmationResponse, err error) {
 	// Construct API request URL
-	apiURL := fmt.Sprintf("%s/transactions/confirm/%s", h.BaseURL, txType)
+	apiURL := fmt.Sprintf("%s/%s", h.endpointURL(EndpointConfirmSell), transactionID)
 	h.Logger.Info("Confirming sell transaction", zap.String("url", apiURL))
 	// Prepare Onramper API request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, nil)
@@ -791,8 +1013,9 @@ func (h Client) ConfirmSellTransaction(ctx context.Context, txType string) (conf
 	req.Header.Add("Authorization", "Bearer "+h.APIKey)
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, confirmIdempotencyKey(transactionID, txHash))
 
-	resp, err := h.HTTPClient.Do(req)
+	resp, err := h.do(req)
 	if err != nil {
 		h.Logger.Error("Failed to send confirmation request", zap.Error(err))
 		return confirmation, err
@@ -802,16 +1025,16 @@ func (h Client) ConfirmSellTransaction(ctx context.Context, txType string) (conf
 
 	var body []byte
 	if resp.StatusCode != http.StatusOK {
-		body, err = io.ReadAll(resp.Body)
+		body, err = h.readBody(resp)
 		if err != nil {
 			h.Logger.Error("Failed to read error response body", zap.Error(err))
 			return confirmation, err
 		}
-		err = fmt.Errorf("failed to confirm sell transaction with status code: %d - message: %s", resp.StatusCode, string(body))
+		err = confirmSellError(resp.StatusCode, body)
 		return confirmation, err
 	}
 	// Decode the response bod
-	err = json.NewDecoder(resp.Body).Decode(&confirmation)
+	err = h.decodeJSON(resp, EndpointConfirmSell, &confirmation)
 	if err != nil {
 		h.Logger.Error("Failed to decode confirmation response", zap.Error(err))
 		err = fmt.Errorf("failed to decode confirmation response: %s", err.Error())
@@ -829,3 +1052,55 @@ func (h Client) ConfirmSellTransaction(ctx context.Context, txType string) (conf
 	)
 	return confirmation, err
 }
+func (h Client) GetSellPayoutStatus(ctx context.Context, transactionID string) (payout models.SellPayoutStatusResponse, err error) {
+	if transactionID == "" {
+		err = errors.New("transaction ID is required")
+		return payout, err
+	}
+	apiURL := fmt.Sprintf("%s/%s/payout", h.endpointURL(EndpointTransactions), url.PathEscape(transactionID))
+	h.Logger.Info("Fetching sell payout status", zap.String("url", apiURL))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		h.Logger.Error("Failed to create request", zap.Error(err))
+		return payout, err
+	}
+	req.Header.Set("Authorization", h.APIKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.do(req)
+	if err != nil {
+		h.Logger.Error("Failed to fetch sell payout status", zap.Error(err))
+		return payout, err
+	}
+	defer resp.Body.Close()
+	h.Logger.Info("Received response", zap.Int("status", resp.StatusCode))
+
+	var body []byte
+	if resp.StatusCode != http.StatusOK {
+		body, err = h.readBody(resp)
+		if err != nil {
+			h.Logger.Error("Failed to read response body", zap.Error(err))
+			return payout, err
+		}
+		// Onramper answers 409 while the sell is still waiting for confirmation.
+		if resp.StatusCode == http.StatusConflict {
+			err = fmt.Errorf("%w: %s", ErrTransactionNotConfirmed, string(body))
+			return payout, err
+		}
+		err = fmt.Errorf("unable to get sell payout status with status code: %d - message: %s", resp.StatusCode, string(body))
+		return payout, err
+	}
+	err = h.decodeJSON(resp, EndpointTransactions, &payout)
+	if err != nil {
+		h.Logger.Error("Failed to decode payout status response", zap.Error(err))
+		err = fmt.Errorf("failed to decode payout status response: %w", err)
+		return payout, err
+	}
+
+	h.Logger.Info("Sell payout status fetched",
+		zap.String("transaction_id", payout.TransactionID),
+		zap.String("payout_status", payout.PayoutStatus),
+	)
+	return payout, err
+}
@@ -4,14 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // Mock transport.
@@ -77,17 +87,18 @@ func TestGetCurrencies(t *testing.T) {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	require.NoError(t, err)
 
-	var raw map[string]interface{}
-	err = json.Unmarshal(bodyBytes, &raw)
+	var currencies models.SupportedCurrenciesResponse
+	err = json.Unmarshal(bodyBytes, &currencies)
 	require.NoError(t, err)
 
 	// Validate contents
-	message := raw["message"].(map[string]interface{})
-	crypto := message["crypto"].([]interface{})[0].(map[string]interface{})
-	fiat := message["fiat"].([]interface{})[0].(map[string]interface{})
-	assert.Equal(t, "AAVE", crypto["code"])
-	assert.Equal(t, "Euro Member Countries", fiat["name"])
-	assert.Equal(t, "https://cdn.onramper.com/icons/tokens/eur.svg", fiat["icon"])
+	crypto := currencies.Message.Crypto[0]
+	fiat := currencies.Message.Fiat[0]
+	assert.Equal(t, "AAVE", crypto.Code)
+	assert.Equal(t, 18, crypto.Decimals)
+	assert.Equal(t, int64(1), crypto.ChainID)
+	assert.Equal(t, "Euro Member Countries", fiat.Name)
+	assert.Equal(t, "https://cdn.onramper.com/icons/tokens/eur.svg", fiat.Icon)
 }
 func TestGetPaymentTypes(t *testing.T) {
 	mockResponse := `{
@@ -1066,7 +1077,2098 @@ func TestConfirmSellTransaction(t *testing.T) {
 		}),
 	}
 	ctx := context.Background()
-	resp, err := client.ConfirmSellTransaction(ctx, "sell123")
+	resp, err := client.ConfirmSellTransaction(ctx, "sell123", "0xabc")
 	require.NoError(t, err)
 	assert.Equal(t, "success", resp.Status)
 }
+func TestConfirmSellTransactionIdempotencyKey(t *testing.T) {
+	var keys []string
+	client := &Client{
+		BaseURL:      "https://mockapi.com",
+		APIKey:       "test-api-key",
+		Logger:       zap.NewNop(),
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		health:       &baseURLHealth{},
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			keys = append(keys, req.Header.Get(IdempotencyKeyHeader))
+			status, body := http.StatusOK, `{"status":"success"}`
+			if len(keys) == 1 {
+				status, body = http.StatusBadGateway, `boom`
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       io.NopCloser(bytes.NewBufferString(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	resp, err := client.ConfirmSellTransaction(context.Background(), "sell123", "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+	require.Len(t, keys, 2, "the keyed confirm is retried after a 5xx")
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+
+	_, err = client.ConfirmSellTransaction(context.Background(), "sell123", "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, keys[0], keys[2], "the key is stable across calls")
+
+	_, err = client.ConfirmSellTransaction(context.Background(), "sell123", "0xdef")
+	require.NoError(t, err)
+	assert.NotEqual(t, keys[0], keys[3], "another transfer gets another key")
+}
+func TestGetQuotesForwardsClientIP(t *testing.T) {
+	mockResponse := `[{"ramp": "moonpay", "paymentMethod": "creditcard", "payout": 0.00398}]`
+	tests := []struct {
+		name       string
+		clientIP   string
+		expectedIP string
+	}{
+		{name: "forwarded when present", clientIP: "203.0.113.7", expectedIP: "203.0.113.7"},
+		{name: "omitted when absent", clientIP: "", expectedIP: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					assert.Equal(t, tt.expectedIP, req.Header.Get(ClientIPHeader))
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+			ctx := WithClientIP(context.Background(), tt.clientIP)
+			quotes, err := client.GetQuotes(ctx, "usd", "btc", &models.QuoteQueryParams{Type: "buy", Amount: 100})
+			require.NoError(t, err)
+			assert.Len(t, quotes, 1)
+		})
+	}
+}
+func TestGetCurrenciesIconRewrite(t *testing.T) {
+	mockResponse := `{
+		"message": {
+			"crypto": [{"id": "btc", "code": "BTC", "icon": "https://cdn.onramper.com/icons/crypto/btc.png"}],
+			"fiat": [{"id": "eur", "code": "EUR", "icon": "https://cdn.onramper.com/icons/tokens/eur.svg"}]
+		}
+	}`
+	tests := []struct {
+		name        string
+		iconBaseURL string
+		expectedBTC string
+		expectedEUR string
+	}{
+		{
+			name:        "not configured",
+			iconBaseURL: "",
+			expectedBTC: "https://cdn.onramper.com/icons/crypto/btc.png",
+			expectedEUR: "https://cdn.onramper.com/icons/tokens/eur.svg",
+		},
+		{
+			name:        "configured",
+			iconBaseURL: "https://assets.example.com/onramper/",
+			expectedBTC: "https://assets.example.com/onramper/icons/crypto/btc.png",
+			expectedEUR: "https://assets.example.com/onramper/icons/tokens/eur.svg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				BaseURL:     "https://mockapi.com",
+				APIKey:      "test-api-key",
+				Logger:      zap.NewNop(),
+				IconBaseURL: tt.iconBaseURL,
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+			currencies, err := client.GetCurrencies(context.Background(), "", "", "buy")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedBTC, currencies.Message.Crypto[0].Icon)
+			assert.Equal(t, tt.expectedEUR, currencies.Message.Fiat[0].Icon)
+		})
+	}
+}
+
+func TestGetCurrenciesChainIDEdgeCases(t *testing.T) {
+	// 2^53+1 cannot be represented exactly as a float64.
+	mockResponse := `{
+		"message": {
+			"crypto": [
+				{"id": "big_chain", "decimals": 18, "chainId": 9007199254740993},
+				{"id": "max_chain", "decimals": 0, "chainId": 9223372036854775807},
+				{"id": "bad_decimals", "decimals": 31, "chainId": 1},
+				{"id": "bad_chain", "decimals": 8, "chainId": -1}
+			],
+			"fiat": []
+		}
+	}`
+	core, logs := observer.New(zap.WarnLevel)
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.New(core),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	currencies, err := client.GetCurrencies(context.Background(), "", "", "buy")
+	require.NoError(t, err)
+	require.Len(t, currencies.Message.Crypto, 4)
+	assert.Equal(t, int64(9007199254740993), currencies.Message.Crypto[0].ChainID)
+	assert.Equal(t, int64(math.MaxInt64), currencies.Message.Crypto[1].ChainID)
+
+	anomalies := logs.FilterMessage("Unexpected cryptocurrency metadata").All()
+	require.Len(t, anomalies, 2)
+	assert.Equal(t, "bad_decimals", anomalies[0].ContextMap()["id"])
+	assert.Equal(t, "bad_chain", anomalies[1].ContextMap()["id"])
+}
+
+func TestGetSellPayoutStatus(t *testing.T) {
+	mockResponse := `{
+		"transactionId": "01H9KBT5C21JY0BAX4VTW9EP3V",
+		"payoutStatus": "processing",
+		"payoutAmount": 95.5,
+		"payoutCurrency": "eur",
+		"expectedArrival": "2024-05-02T12:00:00Z"
+	}`
+	t.Run("success", func(t *testing.T) {
+		client := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				assert.Equal(t, "https://mockapi.com/transactions/01H9KBT5C21JY0BAX4VTW9EP3V/payout", req.URL.String())
+				assert.Equal(t, "test-api-key", req.Header.Get("Authorization"))
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+		payout, err := client.GetSellPayoutStatus(context.Background(), "01H9KBT5C21JY0BAX4VTW9EP3V")
+		require.NoError(t, err)
+		assert.Equal(t, "processing", payout.PayoutStatus)
+		assert.Equal(t, "eur", payout.PayoutCurrency)
+		require.NotNil(t, payout.ExpectedArrival)
+		assert.Equal(t, 2024, payout.ExpectedArrival.Year())
+	})
+	t.Run("not yet confirmed", func(t *testing.T) {
+		client := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusConflict,
+					Body:       io.NopCloser(bytes.NewBufferString(`{"message":"transaction awaiting confirmation"}`)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+		_, err := client.GetSellPayoutStatus(context.Background(), "01H9KBT5C21JY0BAX4VTW9EP3V")
+		require.ErrorIs(t, err, ErrTransactionNotConfirmed)
+	})
+}
+func TestGetQuotesPathOverride(t *testing.T) {
+	mockResponse := `[{"ramp": "moonpay", "paymentMethod": "creditcard", "payout": 0.00398}]`
+	paths, err := ParseEndpointPaths("quotes=/v2/quotes")
+	require.NoError(t, err)
+
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		Paths:   paths,
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			assert.Equal(t, "/v2/quotes/usd/btc", req.URL.Path)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	_, err = client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{Type: "buy", Amount: 100})
+	require.NoError(t, err)
+
+	_, err = ParseEndpointPaths("unknown=/v2/unknown")
+	require.Error(t, err)
+}
+func TestMaxResponseBytes(t *testing.T) {
+	oversized := `{"message":{"crypto":[],"fiat":[],"padding":"` + strings.Repeat("x", 2048) + `"}}`
+	newClient := func(statusCode int) *Client {
+		return &Client{
+			BaseURL:          "https://mockapi.com",
+			APIKey:           "test-api-key",
+			Logger:           zap.NewNop(),
+			MaxResponseBytes: 1024,
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: statusCode,
+					Body:       io.NopCloser(bytes.NewBufferString(oversized)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+	}
+
+	t.Run("oversized success body", func(t *testing.T) {
+		_, err := newClient(http.StatusOK).GetCurrencies(context.Background(), "", "", "buy")
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+	t.Run("oversized error body", func(t *testing.T) {
+		_, err := newClient(http.StatusBadGateway).GetCurrencies(context.Background(), "", "", "buy")
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+}
+func TestConfirmSellTransactionErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		expectedErr error
+	}{
+		{name: "not found", statusCode: http.StatusNotFound, body: `{"message":"Transaction not found"}`, expectedErr: ErrTransactionNotFound},
+		{name: "already confirmed", statusCode: http.StatusConflict, body: `{"message":"Transaction already confirmed"}`, expectedErr: ErrAlreadyConfirmed},
+		{name: "invalid state", statusCode: http.StatusConflict, body: `{"message":"Transaction is not awaiting confirmation"}`, expectedErr: ErrInvalidTransactionState},
+		{name: "invalid state unprocessable", statusCode: http.StatusUnprocessableEntity, body: `{"message":"Transaction expired"}`, expectedErr: ErrInvalidTransactionState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					return &http.Response{
+						StatusCode: tt.statusCode,
+						Body:       io.NopCloser(bytes.NewBufferString(tt.body)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+			_, err := client.ConfirmSellTransaction(context.Background(), "sell123", "0xabc")
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+	t.Run("generic upstream failure", func(t *testing.T) {
+		client := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       io.NopCloser(bytes.NewBufferString(`boom`)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+		_, err := client.ConfirmSellTransaction(context.Background(), "sell123", "0xabc")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTransactionNotFound)
+		assert.NotErrorIs(t, err, ErrAlreadyConfirmed)
+		assert.NotErrorIs(t, err, ErrInvalidTransactionState)
+	})
+}
+func TestGetTransactionByIDErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		expectedErr error
+	}{
+		{name: "not found", statusCode: http.StatusNotFound, expectedErr: ErrTransactionNotFound},
+		{name: "bad request", statusCode: http.StatusBadRequest, expectedErr: ErrInvalidTransactionID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					return &http.Response{
+						StatusCode: tt.statusCode,
+						Body:       io.NopCloser(bytes.NewBufferString(`{"message":"nope"}`)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+			_, err := client.GetTransactionByID(context.Background(), "tx123")
+			require.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestDecodeErrorContext(t *testing.T) {
+	malformed := `{"message": {"apiKey": "pk_prod_123456", "crypto": [` + strings.Repeat(`{"id":"btc"},`, 50)
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(malformed)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := client.GetCurrencies(context.Background(), "", "", "buy")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "currencies endpoint /supported")
+	assert.Contains(t, err.Error(), `\"crypto\": [`)
+	assert.Contains(t, err.Error(), "[REDACTED]")
+	assert.NotContains(t, err.Error(), "pk_prod_123456")
+	assert.Contains(t, err.Error(), "...")
+}
+func TestGetCurrenciesConditionalRequest(t *testing.T) {
+	const body = `{"message":{"crypto":[{"id":"btc","code":"BTC"}],"fiat":[{"id":"usd","code":"USD"}]}}`
+	var ifNoneMatch []string
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		etags:   newETagCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			ifNoneMatch = append(ifNoneMatch, req.Header.Get("If-None-Match"))
+			if req.Header.Get("If-None-Match") == `"v1"` {
+				// An empty body: a 304 must be served from the cache, not decoded.
+				return &http.Response{StatusCode: http.StatusNotModified, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+			}
+			header := make(http.Header)
+			header.Set("ETag", `"v1"`)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body)), Header: header}
+		}),
+	}
+
+	first, err := client.GetCurrencies(context.Background(), "us", "", "buy")
+	require.NoError(t, err)
+	first.Message.Crypto[0].Code = "changed by caller"
+
+	raw, second, err := client.GetCurrenciesRaw(context.Background(), "us", "", "buy")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", `"v1"`}, ifNoneMatch)
+	assert.JSONEq(t, body, string(raw))
+	require.Len(t, second.Message.Crypto, 1)
+	assert.Equal(t, "BTC", second.Message.Crypto[0].Code, "callers get their own copy of the cached lists")
+	assert.Equal(t, "usd", second.Message.Fiat[0].ID)
+
+	t.Run("responses without an ETag are not cached", func(t *testing.T) {
+		var conditional int
+		client := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			etags:   newETagCache(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				if req.Header.Get("If-None-Match") != "" {
+					conditional++
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body)), Header: make(http.Header)}
+			}),
+		}
+		for i := 0; i < 2; i++ {
+			_, err := client.GetCurrencies(context.Background(), "us", "", "buy")
+			require.NoError(t, err)
+		}
+		assert.Zero(t, conditional)
+	})
+}
+
+func TestGetOnrampMetadataConditionalRequest(t *testing.T) {
+	var calls int
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		etags:   newETagCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls++
+			if req.Header.Get("If-None-Match") == "W/\"meta-1\"" {
+				return &http.Response{StatusCode: http.StatusNotModified, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+			}
+			header := make(http.Header)
+			header.Set("ETag", "W/\"meta-1\"")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":[{"id":"moonpay","displayName":"MoonPay"}]}`)),
+				Header:     header,
+			}
+		}),
+	}
+
+	_, err := client.GetOnrampMetadata(context.Background(), "buy")
+	require.NoError(t, err)
+	metadata, err := client.GetOnrampMetadata(context.Background(), "buy")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	require.Len(t, metadata.Message, 1)
+	assert.Equal(t, "MoonPay", metadata.Message[0].DisplayName)
+}
+
+func TestGetCurrenciesBothDirections(t *testing.T) {
+	responses := map[string]string{
+		"buy": `{"message":{
+			"crypto":[{"id":"btc","code":"BTC"},{"id":"usdt_ethereum","code":"USDT"},{"id":"sol","code":"SOL"}],
+			"fiat":[{"id":"usd","code":"USD"},{"id":"eur","code":"EUR"}]}}`,
+		"sell": `{"message":{
+			"crypto":[{"id":"usdt_tron","code":"USDT"},{"id":"BTC","code":"BTC"},{"id":"sol","code":"SOL"}],
+			"fiat":[{"id":"eur","code":"EUR"},{"id":"gbp","code":"GBP"}]}}`,
+	}
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			assert.Equal(t, "us", req.URL.Query().Get("country"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(responses[req.URL.Query().Get("type")])),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	currencies, err := client.GetCurrenciesBothDirections(context.Background(), "us")
+	require.NoError(t, err)
+	cryptoIDs := make([]string, 0, len(currencies.Message.Crypto))
+	for _, crypto := range currencies.Message.Crypto {
+		cryptoIDs = append(cryptoIDs, crypto.ID)
+	}
+	assert.Equal(t, []string{"btc", "sol"}, cryptoIDs, "USDT on different networks is not the same currency")
+	require.Len(t, currencies.Message.Fiat, 1)
+	assert.Equal(t, "eur", currencies.Message.Fiat[0].ID)
+
+	t.Run("one side fails", func(t *testing.T) {
+		failing := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				status, body := http.StatusOK, responses["buy"]
+				if req.URL.Query().Get("type") == "sell" {
+					status, body = http.StatusInternalServerError, `boom`
+				}
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(bytes.NewBufferString(body)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+		_, err := failing.GetCurrenciesBothDirections(context.Background(), "us")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sell currencies")
+	})
+}
+
+func TestGetAllPaymentTypes(t *testing.T) {
+	responses := map[string]string{
+		"buy": `{"message":{
+			"creditcard":{"paymentTypeId":"creditcard","name":"Credit Card","icon":"https://cdn.onramper.com/icons/payments/creditcard.svg"},
+			"applepay":{"paymentTypeId":"applepay","name":"Apple Pay","icon":"https://cdn.onramper.com/icons/payments/applepay.svg"}}}`,
+		"sell": `{"message":{
+			"creditcard":{"paymentTypeId":"creditcard","name":"Credit Card","icon":"https://cdn.onramper.com/icons/payments/creditcard.svg"},
+			"sepabanktransfer":{"paymentTypeId":"sepabanktransfer","name":"SEPA Bank Transfer","icon":"https://cdn.onramper.com/icons/payments/banktransfer.svg"}}}`,
+	}
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			assert.Equal(t, "us", req.URL.Query().Get("country"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(responses[req.URL.Query().Get("type")])),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	paymentTypes, err := client.GetAllPaymentTypes(context.Background(), "us")
+	require.NoError(t, err)
+	require.Len(t, paymentTypes.Message, 3)
+	assert.Equal(t, []string{"buy", "sell"}, paymentTypes.Message["creditcard"].SupportedFor)
+	assert.Equal(t, "Credit Card", paymentTypes.Message["creditcard"].Name)
+	assert.Equal(t, []string{"buy"}, paymentTypes.Message["applepay"].SupportedFor)
+	assert.Equal(t, []string{"sell"}, paymentTypes.Message["sepabanktransfer"].SupportedFor)
+
+	t.Run("one side fails", func(t *testing.T) {
+		failing := &Client{
+			BaseURL: "https://mockapi.com",
+			APIKey:  "test-api-key",
+			Logger:  zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				if req.URL.Query().Get("type") == "sell" {
+					return &http.Response{
+						StatusCode: http.StatusInternalServerError,
+						Body:       io.NopCloser(bytes.NewBufferString(`boom`)),
+						Header:     make(http.Header),
+					}
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(responses["buy"])),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+		_, err := failing.GetAllPaymentTypes(context.Background(), "us")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sell payment types")
+	})
+}
+
+// errRoundTripper fails requests to unreachable hosts and delegates the rest.
+type errRoundTripper struct {
+	unreachable string
+	next        http.RoundTripper
+}
+
+func (e *errRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == e.unreachable {
+		return nil, fmt.Errorf("dial tcp %s: connection refused", e.unreachable)
+	}
+	return e.next.RoundTrip(req)
+}
+
+func TestBaseURLFailover(t *testing.T) {
+	quotesJSON := `[{"ramp":"moonpay","rate":1.5}]`
+	okResponse := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(quotesJSON)),
+			Header:     make(http.Header),
+		}
+	}
+	params := &models.QuoteQueryParams{Amount: 100}
+
+	t.Run("primary 5xx fails over after retries", func(t *testing.T) {
+		var hosts []string
+		client := &Client{
+			BaseURLs:     []string{"https://primary.onramper.com", "https://fallback.onramper.com"},
+			APIKey:       "test-api-key",
+			Logger:       zap.NewNop(),
+			MaxRetries:   1,
+			RetryBackoff: time.Millisecond,
+			health:       &baseURLHealth{},
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				hosts = append(hosts, req.URL.Host)
+				if req.URL.Host == "primary.onramper.com" {
+					return &http.Response{
+						StatusCode: http.StatusBadGateway,
+						Body:       io.NopCloser(bytes.NewBufferString(`bad gateway`)),
+						Header:     make(http.Header),
+					}
+				}
+				assert.True(t, strings.HasPrefix(req.URL.Path, "/quotes/"), "path is kept on failover")
+				return okResponse()
+			}),
+		}
+
+		quotes, err := client.GetQuotes(context.Background(), "usd", "btc", params)
+		require.NoError(t, err)
+		require.Len(t, quotes, 1)
+		assert.Equal(t, []string{"primary.onramper.com", "primary.onramper.com", "fallback.onramper.com"}, hosts)
+		assert.Equal(t, "https://fallback.onramper.com", client.HealthyBaseURL())
+
+		// The healthy fallback is now tried first.
+		hosts = nil
+		_, err = client.GetQuotes(context.Background(), "usd", "btc", params)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"fallback.onramper.com"}, hosts)
+	})
+
+	t.Run("unreachable primary fails over", func(t *testing.T) {
+		client := &Client{
+			BaseURLs: []string{"https://primary.onramper.com", "https://fallback.onramper.com"},
+			APIKey:   "test-api-key",
+			Logger:   zap.NewNop(),
+			health:   &baseURLHealth{},
+			HTTPClient: &http.Client{Transport: &errRoundTripper{
+				unreachable: "primary.onramper.com",
+				next:        &mockRoundTripper{mockFunc: func(req *http.Request) *http.Response { return okResponse() }},
+			}},
+		}
+
+		quotes, err := client.GetQuotes(context.Background(), "usd", "btc", params)
+		require.NoError(t, err)
+		require.Len(t, quotes, 1)
+		assert.Equal(t, "https://fallback.onramper.com", client.HealthyBaseURL())
+	})
+
+	t.Run("all base URLs failing returns the last error", func(t *testing.T) {
+		client := &Client{
+			BaseURLs: []string{"https://primary.onramper.com", "https://fallback.onramper.com"},
+			APIKey:   "test-api-key",
+			Logger:   zap.NewNop(),
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(bytes.NewBufferString(`down for maintenance`)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+
+		_, err := client.GetQuotes(context.Background(), "usd", "btc", params)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "down for maintenance")
+	})
+
+	t.Run("non-idempotent requests are not replayed", func(t *testing.T) {
+		var calls int
+		client := &Client{
+			BaseURLs:   []string{"https://primary.onramper.com", "https://fallback.onramper.com"},
+			APIKey:     "test-api-key",
+			Logger:     zap.NewNop(),
+			MaxRetries: 2,
+			health:     &baseURLHealth{},
+			HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+				calls++
+				return &http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       io.NopCloser(bytes.NewBufferString(`boom`)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+
+		_, err := client.InitiateTransaction(context.Background(), models.InitiateTransactionRequest{Onramp: "moonpay"})
+		require.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+func TestGetQuotesPaymentMethod(t *testing.T) {
+	paymentTypesJSON := `{"message":{
+		"creditcard":{"paymentTypeId":"creditcard","name":"Credit Card"},
+		"pix":{"paymentTypeId":"pix","name":"Pix"}}}`
+	tests := []struct {
+		name          string
+		paymentMethod string
+		quotesJSON    string
+		expectedErr   error
+	}{
+		{
+			name:          "supported method",
+			paymentMethod: "creditcard",
+			quotesJSON:    `[{"ramp":"moonpay","paymentMethod":"creditcard","payout":0.0015}]`,
+		},
+		{
+			name:          "unknown method",
+			paymentMethod: "carrierpigeon",
+			expectedErr:   ErrUnknownPaymentMethod,
+		},
+		{
+			name:          "method unsupported for corridor",
+			paymentMethod: "pix",
+			quotesJSON:    `[{"ramp":"moonpay","errors":[{"type":"NoSupportedPaymentFound","errorId":6103,"message":"No supported payment method found"}]}]`,
+			expectedErr:   ErrPaymentMethodUnsupported,
+		},
+		{
+			name:          "empty results for method",
+			paymentMethod: "pix",
+			quotesJSON:    `[]`,
+			expectedErr:   ErrPaymentMethodUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quoteCalls int
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					body := paymentTypesJSON
+					if strings.HasPrefix(req.URL.Path, "/quotes/") {
+						quoteCalls++
+						assert.Equal(t, tt.paymentMethod, req.URL.Query().Get("paymentMethod"))
+						body = tt.quotesJSON
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(body)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			quotes, err := client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{
+				Amount:        100,
+				Type:          "buy",
+				PaymentMethod: tt.paymentMethod,
+			})
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.NotErrorIs(t, err, ErrNoQuotes)
+				if errors.Is(tt.expectedErr, ErrUnknownPaymentMethod) {
+					assert.Zero(t, quoteCalls, "unknown methods are rejected before quoting")
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, quotes, 1)
+			assert.Equal(t, "creditcard", quotes[0].PaymentMethod)
+		})
+	}
+}
+
+func TestGetQuotesErrorStatus(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		rejected   bool
+	}{
+		{statusCode: http.StatusBadRequest, rejected: true},
+		{statusCode: http.StatusNotFound, rejected: true},
+		{statusCode: http.StatusTooManyRequests},
+		{statusCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					return &http.Response{
+						StatusCode: tt.statusCode,
+						Body:       io.NopCloser(bytes.NewBufferString(`{"message":"invalid amount"}`)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			_, err := client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{Amount: 100, Type: "buy"})
+			require.Error(t, err)
+			assert.Equal(t, tt.rejected, errors.Is(err, ErrQuoteRejected))
+		})
+	}
+}
+
+func TestGetQuotesAllQuotesFailed(t *testing.T) {
+	mockResponse := `[
+		{
+			"ramp": "fonbnk",
+			"paymentMethod": "creditcard",
+			"errors": [
+				{"type": "NoSupportedPaymentFound", "errorId": 6103, "message": "No supported payments found"}
+			],
+			"quoteId": "01H985NH79FW951SKERQ45JMYXfonbnk"
+		},
+		{
+			"ramp": "banxa",
+			"paymentMethod": "creditcard",
+			"errors": [
+				{"type": "NoSupportedPaymentFound", "errorId": 6103, "message": "No supported payments found"},
+				{"type": "LimitMismatch", "errorId": 6101, "message": "Amount is below the minimum"}
+			],
+			"quoteId": "01H985NH79FW951SKERQ45JMYXbanxa"
+		}
+	]`
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	quotes, err := client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{Amount: 100, Type: "buy"})
+	require.Error(t, err)
+	assert.Len(t, quotes, 2)
+	assert.NotErrorIs(t, err, ErrNoQuotes)
+
+	var allFailed *AllQuotesFailed
+	require.ErrorAs(t, err, &allFailed)
+	require.Len(t, allFailed.Errors, 2, "duplicate provider errors are reported once")
+	assert.Equal(t, "NoSupportedPaymentFound", allFailed.Errors[0].Type)
+	assert.Equal(t, "LimitMismatch", allFailed.Errors[1].Type)
+	assert.Contains(t, err.Error(), "NoSupportedPaymentFound (No supported payments found)")
+}
+
+func TestGetSupportedCountries(t *testing.T) {
+	mockResponse := `{
+		"message": {
+			"recommended": {"source": "NGN", "target": "BTC", "amount": 30000, "country": "ng"},
+			"defaults": {
+				"zm": {"source": "ZMW", "target": "BTC", "amount": 1000},
+				"AD": {"source": "EUR", "target": "BTC", "amount": 300},
+				"au": {"source": "AUD", "target": "BTC", "amount": 100}
+			}
+		}
+	}`
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			assert.Equal(t, "https://mockapi.com/supported/defaults/all?type=sell", req.URL.String())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	countries, err := client.GetSupportedCountries(context.Background(), "sell")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ad", "au", "zm"}, countries)
+}
+
+func TestClientClose(t *testing.T) {
+	t.Run("calls after close fail fast", func(t *testing.T) {
+		var calls int
+		client := NewClient("https://mockapi.com", "test-api-key", "secret", zap.NewNop()).(*Client)
+		client.HTTPClient = newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls++
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{}`)), Header: make(http.Header)}
+		})
+		require.NoError(t, client.Close())
+
+		_, err := client.GetCurrencies(context.Background(), "", "", "buy")
+		require.ErrorIs(t, err, ErrClientClosed)
+		assert.Zero(t, calls)
+	})
+	t.Run("close cancels in-flight requests", func(t *testing.T) {
+		started := make(chan struct{})
+		client := NewClient("https://mockapi.com", "test-api-key", "secret", zap.NewNop()).(*Client)
+		client.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			close(started)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		})}
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := client.GetCurrencies(context.Background(), "", "", "buy")
+			errs <- err
+		}()
+		<-started
+		require.NoError(t, client.Close())
+
+		select {
+		case err := <-errs:
+			require.ErrorIs(t, err, ErrClientClosed)
+		case <-time.After(time.Second):
+			t.Fatal("in-flight request was not cancelled by Close")
+		}
+	})
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetQuoteByID(t *testing.T) {
+	mockResponse := `[
+		{"ramp": "moonpay", "paymentMethod": "creditcard", "payout": 0.0015, "quoteId": "01H985NH79FW951SKERQ45JMYXmoonpay"},
+		{"ramp": "banxa", "paymentMethod": "creditcard", "payout": 0.0014, "quoteId": "01H985NH79FW951SKERQ45JMYXbanxa",
+			"expiresAt": "2020-01-01T00:00:00Z"},
+		{"ramp": "fonbnk", "errors": [{"type": "NoSupportedPaymentFound", "errorId": 6103, "message": "No supported payments found"}],
+			"quoteId": "01H985NH79FW951SKERQ45JMYXfonbnk"}
+	]`
+	client := NewClient("https://mockapi.com", "test-api-key", "secret", zap.NewNop()).(*Client)
+	client.HTTPClient = newMockHTTPClient(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(mockResponse)),
+			Header:     make(http.Header),
+		}
+	})
+	_, err := client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{Amount: 100, Type: "buy"})
+	require.NoError(t, err)
+
+	quote, err := client.GetQuoteByID(context.Background(), "01H985NH79FW951SKERQ45JMYXmoonpay")
+	require.NoError(t, err)
+	assert.Equal(t, "moonpay", quote.Ramp)
+
+	_, err = client.GetQuoteByID(context.Background(), "01H985NH79FW951SKERQ45JMYXbanxa")
+	require.ErrorIs(t, err, ErrQuoteExpired)
+
+	_, err = client.GetQuoteByID(context.Background(), "01H985NH79FW951SKERQ45JMYXfonbnk")
+	require.ErrorIs(t, err, ErrQuoteNotFound, "failed quotes are not retrievable")
+
+	_, err = client.GetQuoteByID(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrQuoteNotFound)
+
+	_, err = client.GetQuoteByID(context.Background(), " ")
+	require.ErrorIs(t, err, ErrQuoteNotFound)
+}
+
+func TestUpstreamUnavailable(t *testing.T) {
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			header := make(http.Header)
+			header.Set("Retry-After", "120")
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       io.NopCloser(bytes.NewBufferString(`scheduled maintenance`)),
+				Header:     header,
+			}
+		}),
+	}
+
+	_, err := client.GetCurrencies(context.Background(), "", "", "buy")
+	require.ErrorIs(t, err, ErrUpstreamUnavailable)
+	var unavailable *UnavailableError
+	require.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, "120", unavailable.RetryAfter)
+	assert.Equal(t, "scheduled maintenance", unavailable.Message)
+}
+
+func TestQuoteAmountFormatting(t *testing.T) {
+	tests := []struct {
+		name           string
+		amount         float64
+		params         models.QuoteQueryParams
+		expectedAmount string
+	}{
+		{name: "fiat amount rounds to cents", amount: 100.456, params: models.QuoteQueryParams{Type: "buy"}, expectedAmount: "100.46"},
+		{name: "fiat float noise", amount: 0.1 + 0.2, params: models.QuoteQueryParams{Type: "buy"}, expectedAmount: "0.3"},
+		{name: "small crypto amount when selling", amount: 0.00001234, params: models.QuoteQueryParams{Type: "sell"}, expectedAmount: "0.00001234"},
+		{name: "crypto amount rounds to eight places", amount: 0.123456789, params: models.QuoteQueryParams{Type: "sell"}, expectedAmount: "0.12345679"},
+		{name: "buy with crypto input", amount: 0.00001234, params: models.QuoteQueryParams{Type: "buy", Input: "destination"}, expectedAmount: "0.00001234"},
+		{name: "sell with fiat input", amount: 49.999, params: models.QuoteQueryParams{Type: "sell", Input: "destination"}, expectedAmount: "50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{BaseURL: "https://mockapi.com"}
+			params := tt.params
+			params.Amount = tt.amount
+
+			parsed, err := url.Parse(client.buildGetQuotesURL("usd", "btc", &params))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAmount, parsed.Query().Get("amount"))
+		})
+	}
+}
+
+func TestClientRegistry(t *testing.T) {
+	base := NewClient("https://mockapi.com", "default-key", "secret", zap.NewNop()).(*Client)
+	partnerA := base.WithAPIKey("partner-a-key")
+	registry := NewClientRegistry(base)
+	registry.Register("partner-a", partnerA)
+
+	client, err := registry.Client("")
+	require.NoError(t, err)
+	assert.Same(t, base, client)
+
+	client, err = registry.Client("partner-a")
+	require.NoError(t, err)
+	assert.Equal(t, "partner-a-key", client.(*Client).APIKey)
+	assert.Equal(t, "default-key", base.APIKey)
+
+	_, err = registry.Client("partner-b")
+	require.ErrorIs(t, err, ErrUnknownPartner)
+
+	clients := registry.Clients()
+	assert.Len(t, clients, 2)
+	assert.Same(t, base, clients[""])
+	assert.Same(t, partnerA, clients["partner-a"])
+}
+
+func TestParsePartnerAPIKeys(t *testing.T) {
+	keys, err := ParsePartnerAPIKeys(" partner-a = key-a ,partner-b=key-b")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"partner-a": "key-a", "partner-b": "key-b"}, keys)
+
+	_, err = ParsePartnerAPIKeys("partner-a")
+	require.Error(t, err)
+}
+
+func TestCircuitBreakerStats(t *testing.T) {
+	var calls int
+	client := &Client{
+		BaseURL:          "https://mockapi.com",
+		APIKey:           "test-api-key",
+		Logger:           zap.NewNop(),
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+		breakers:         newCircuitBreakers(),
+		quotes:           newQuoteStore(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls++
+			if strings.HasPrefix(req.URL.Path, "/quotes/") {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(`[{"ramp":"moonpay","quoteId":"q1"}]`)),
+					Header:     make(http.Header),
+				}
+			}
+			return &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(bytes.NewBufferString(`bad gateway`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := client.GetQuotes(context.Background(), "usd", "btc", &models.QuoteQueryParams{Amount: 100})
+	require.NoError(t, err)
+	for range 2 {
+		_, err = client.GetCurrencies(context.Background(), "", "", "buy")
+		require.Error(t, err)
+	}
+	assert.Equal(t, 3, calls)
+
+	// The open breaker short-circuits further calls to that endpoint only.
+	_, err = client.GetCurrencies(context.Background(), "", "", "buy")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, calls)
+
+	stats := client.Stats()
+	assert.Equal(t, 1, stats.QuoteCacheSize)
+	assert.Equal(t, "https://mockapi.com", stats.HealthyBaseURL)
+	require.Contains(t, stats.Breakers, EndpointCurrencies)
+	assert.Equal(t, BreakerOpen, stats.Breakers[EndpointCurrencies].State)
+	assert.Equal(t, 2, stats.Breakers[EndpointCurrencies].ConsecutiveFailures)
+	assert.NotNil(t, stats.Breakers[EndpointCurrencies].OpenedAt)
+	assert.Equal(t, BreakerClosed, stats.Breakers[EndpointQuotes].State)
+
+	// After the cooldown a trial request is let through, and its success closes the breaker.
+	client.BreakerCooldown = time.Nanosecond
+	assert.Equal(t, BreakerHalfOpen, client.Stats().Breakers[EndpointCurrencies].State)
+	client.breakers.record(EndpointCurrencies, false, time.Now(), client.BreakerThreshold)
+	assert.Equal(t, BreakerClosed, client.Stats().Breakers[EndpointCurrencies].State)
+}
+
+func TestCircuitBreakerHalfOpenAdmitsOneProbe(t *testing.T) {
+	const threshold, cooldown = 2, time.Minute
+	opened := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	breakers := newCircuitBreakers()
+	for range threshold {
+		breakers.record(EndpointCurrencies, true, opened, threshold)
+	}
+	assert.False(t, breakers.allow(EndpointCurrencies, opened.Add(cooldown/2), cooldown), "open during cooldown")
+
+	halfOpen := opened.Add(cooldown)
+	assert.True(t, breakers.allow(EndpointCurrencies, halfOpen, cooldown), "one probe after cooldown")
+	assert.False(t, breakers.allow(EndpointCurrencies, halfOpen, cooldown), "no second request while the probe is out")
+
+	// A failed probe re-opens the breaker for another cooldown.
+	breakers.record(EndpointCurrencies, true, halfOpen, threshold)
+	assert.False(t, breakers.allow(EndpointCurrencies, halfOpen.Add(cooldown/2), cooldown))
+	reopened := halfOpen.Add(cooldown)
+	assert.True(t, breakers.allow(EndpointCurrencies, reopened, cooldown))
+
+	// A probe that never reports back is replaced after another cooldown.
+	assert.False(t, breakers.allow(EndpointCurrencies, reopened.Add(cooldown/2), cooldown))
+	assert.True(t, breakers.allow(EndpointCurrencies, reopened.Add(cooldown), cooldown))
+
+	// A successful probe closes the breaker for everyone.
+	breakers.record(EndpointCurrencies, false, reopened.Add(cooldown), threshold)
+	for range 3 {
+		assert.True(t, breakers.allow(EndpointCurrencies, reopened.Add(cooldown), cooldown))
+	}
+}
+
+// delayingRoundTripper answers after delay unless the request context ends first.
+type delayingRoundTripper struct {
+	delay time.Duration
+}
+
+func (d *delayingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-time.After(d.delay):
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`[{"ramp":"moonpay","rate":1.5}]`)),
+			Header:     make(http.Header),
+		}, nil
+	}
+}
+
+func TestGetQuotesTimeout(t *testing.T) {
+	params := &models.QuoteQueryParams{Amount: 100}
+	newClient := func(quotesTimeout time.Duration) *Client {
+		return &Client{
+			BaseURL:       "https://mockapi.com",
+			APIKey:        "test-api-key",
+			Logger:        zap.NewNop(),
+			QuotesTimeout: quotesTimeout,
+			HTTPClient:    &http.Client{Transport: &delayingRoundTripper{delay: 200 * time.Millisecond}},
+		}
+	}
+
+	t.Run("quotes timeout fires", func(t *testing.T) {
+		start := time.Now()
+		_, err := newClient(20*time.Millisecond).GetQuotes(context.Background(), "usd", "btc", params)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("other calls are not bounded by it", func(t *testing.T) {
+		client := newClient(20 * time.Millisecond)
+		client.HTTPClient = &http.Client{Transport: &delayingRoundTripper{delay: 50 * time.Millisecond}}
+		_, err := client.GetTransactionByID(context.Background(), "tx_123")
+		require.NotErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("no override waits for the response", func(t *testing.T) {
+		quotes, err := newClient(0).GetQuotes(context.Background(), "usd", "btc", params)
+		require.NoError(t, err)
+		assert.Len(t, quotes, 1)
+	})
+}
+
+func TestGetQuotesValidatesAssetTypes(t *testing.T) {
+	currenciesJSON := `{"message":{
+		"crypto":[{"id":"btc","code":"BTC"},{"id":"usdc_ethereum","code":"USDC"}],
+		"fiat":[{"id":"usd","code":"USD"},{"id":"eur","code":"EUR"}]
+	}}`
+	tests := []struct {
+		name      string
+		fiat      string
+		crypto    string
+		txType    string
+		expectErr bool
+	}{
+		{name: "buy in order", fiat: "usd", crypto: "btc", txType: "buy"},
+		{name: "sell in order", fiat: "eur", crypto: "usdc_ethereum", txType: "sell"},
+		{name: "buy swapped", fiat: "btc", crypto: "usd", txType: "buy", expectErr: true},
+		{name: "sell swapped", fiat: "BTC", crypto: "EUR", txType: "sell", expectErr: true},
+		{name: "crypto given as fiat", fiat: "usd", crypto: "eur", txType: "buy", expectErr: true},
+		{name: "unknown codes pass", fiat: "xyz", crypto: "abc", txType: "buy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quoteCalls int
+			client := &Client{
+				BaseURL:             "https://mockapi.com",
+				APIKey:              "test-api-key",
+				Logger:              zap.NewNop(),
+				ValidateQuoteAssets: true,
+				assetTypes:          newAssetTypesCache(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					body := currenciesJSON
+					if strings.HasPrefix(req.URL.Path, "/quotes/") {
+						quoteCalls++
+						body = `[{"ramp":"moonpay","rate":1.5}]`
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(body)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			_, err := client.GetQuotes(context.Background(), tt.fiat, tt.crypto, &models.QuoteQueryParams{Amount: 100, Type: tt.txType})
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrAssetTypeMismatch)
+				assert.Zero(t, quoteCalls)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, quoteCalls)
+		})
+	}
+}
+
+func TestValidateQuoteAssetsCachesCurrencies(t *testing.T) {
+	var currencyCalls int
+	client := &Client{
+		BaseURL:    "https://mockapi.com",
+		APIKey:     "test-api-key",
+		Logger:     zap.NewNop(),
+		assetTypes: newAssetTypesCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			currencyCalls++
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[{"id":"btc"}],"fiat":[{"id":"usd"}]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	require.NoError(t, client.validateQuoteAssets(context.Background(), "usd", "btc"))
+	require.ErrorIs(t, client.validateQuoteAssets(context.Background(), "btc", "usd"), ErrAssetTypeMismatch)
+	assert.Equal(t, 1, currencyCalls)
+}
+
+func TestValidateQuoteAssetsSkipsWhenCurrenciesFail(t *testing.T) {
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(bytes.NewBufferString(`bad gateway`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	assert.NoError(t, client.validateQuoteAssets(context.Background(), "btc", "usd"))
+}
+
+func TestGetAssetsByCountries(t *testing.T) {
+	responses := map[string]string{
+		"us": `{"message":{"assets":[{"fiat":"usd","paymentMethods":["creditcard"],"crypto":["btc"]}],"country":"US"}}`,
+		"nl": `{"message":{"assets":[{"fiat":"eur","paymentMethods":["ideal"],"crypto":["btc","eth"]}],"country":"NL"}}`,
+	}
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			country := req.URL.Query().Get("country")
+			assert.Equal(t, "buy", req.URL.Query().Get("type"))
+			mu.Lock()
+			requested = append(requested, country)
+			mu.Unlock()
+			body, ok := responses[country]
+			if !ok {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       io.NopCloser(bytes.NewBufferString(`unsupported country`)),
+					Header:     make(http.Header),
+				}
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	assets, err := client.GetAssetsByCountries(context.Background(), []string{"NL", "us", "nl", " "},
+		models.AssetRequest{Type: models.BuyTransaction})
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+	assert.Equal(t, "nl", assets[0].Country)
+	assert.Len(t, assets[0].Assets, 1)
+	assert.Equal(t, "us", assets[1].Country)
+	assert.Equal(t, "usd", assets[1].Assets[0].(map[string]interface{})["fiat"])
+	assert.ElementsMatch(t, []string{"nl", "us"}, requested)
+
+	_, err = client.GetAssetsByCountries(context.Background(), []string{"us", "xx"}, models.AssetRequest{Type: models.BuyTransaction})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "xx")
+}
+
+func TestWithMaxRetriesOverridesClientDefault(t *testing.T) {
+	tests := []struct {
+		name          string
+		clientRetries int
+		ctx           func() context.Context
+		expectedCalls int
+	}{
+		{name: "client default", clientRetries: 2, ctx: context.Background, expectedCalls: 3},
+		{name: "context disables retries", clientRetries: 2,
+			ctx: func() context.Context { return WithMaxRetries(context.Background(), 0) }, expectedCalls: 1},
+		{name: "context raises retries", clientRetries: 0,
+			ctx: func() context.Context { return WithMaxRetries(context.Background(), 3) }, expectedCalls: 4},
+		{name: "negative budget means none", clientRetries: 2,
+			ctx: func() context.Context { return WithMaxRetries(context.Background(), -1) }, expectedCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			client := &Client{
+				BaseURL:      "https://mockapi.com",
+				APIKey:       "test-api-key",
+				Logger:       zap.NewNop(),
+				MaxRetries:   tt.clientRetries,
+				RetryBackoff: time.Millisecond,
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					calls++
+					return &http.Response{
+						StatusCode: http.StatusBadGateway,
+						Body:       io.NopCloser(bytes.NewBufferString(`bad gateway`)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			_, err := client.GetCurrencies(tt.ctx(), "", "", "buy")
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestViableProviders(t *testing.T) {
+	method := func(paymentTypeID, ramp string, min, max float64) string {
+		return fmt.Sprintf(`{"paymentTypeId":%q,"details":{"currencyStatus":"SourceAndDestSupported",`+
+			`"limits":{%q:{"min":%v,"max":%v},"aggregatedLimit":{"min":1,"max":50000}}}}`, paymentTypeID, ramp, min, max)
+	}
+	quote := func(ramp, paymentMethod string, usable bool, methods ...string) string {
+		errs := ""
+		if !usable {
+			errs = `,"errors":[{"type":"LimitMismatch","errorId":6101,"message":"Amount out of range"}]`
+		}
+		return fmt.Sprintf(`{"ramp":%q,"paymentMethod":%q,"payout":0.001,"availablePaymentMethods":[%s]%s}`,
+			ramp, paymentMethod, strings.Join(methods, ","), errs)
+	}
+	moonpay := quote("moonpay", "creditcard", true, method("creditcard", "moonpay", 20, 5000))
+	transak := quote("transak", "applepay", false, method("applepay", "transak", 1000, 20000))
+	banxa := func(usable bool) string { return quote("banxa", "sepa", usable, method("sepa", "banxa", 300, 10000)) }
+	mercuryo := quote("mercuryo", "creditcard", true)
+	responses := map[string][]string{
+		"50":  {moonpay, transak, banxa(false), mercuryo},
+		"275": {moonpay, transak, banxa(false), mercuryo},
+		"500": {moonpay, transak, banxa(true), quote("mercuryo", "creditcard", false)},
+	}
+
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			assert.Equal(t, "de", req.URL.Query().Get("country"))
+			quotes, ok := responses[req.URL.Query().Get("amount")]
+			assert.True(t, ok, "unexpected amount %s", req.URL.Query().Get("amount"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString("[" + strings.Join(quotes, ",") + "]")),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	providers, err := client.ViableProviders(context.Background(), "eur", "btc", 50, 500, "de")
+	require.NoError(t, err)
+	assert.Equal(t, []ViableProvider{
+		{Ramp: "banxa", PaymentMethod: "sepa", Min: 300, Max: 500},
+		{Ramp: "mercuryo", PaymentMethod: "creditcard", Min: 50, Max: 275},
+		{Ramp: "moonpay", PaymentMethod: "creditcard", Min: 50, Max: 500},
+	}, providers, "transak's band starts above the range")
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := client.ViableProviders(context.Background(), "eur", "btc", 500, 50, "de")
+		require.Error(t, err)
+	})
+}
+
+// stubFXProvider returns fixed rates keyed by "FROM/TO" and counts lookups.
+type stubFXProvider struct {
+	rates map[string]float64
+	calls int
+}
+
+func (p *stubFXProvider) Rate(_ context.Context, from, to string) (float64, error) {
+	p.calls++
+	rate, ok := p.rates[strings.ToUpper(from)+"/"+strings.ToUpper(to)]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s/%s", from, to)
+	}
+	return rate, nil
+}
+
+func TestQuotesInBaseCurrency(t *testing.T) {
+	quotes := []models.QuoteResponse{
+		{Ramp: "moonpay", NetworkFee: 2, TransactionFee: 3, Payout: models.NewAmountFromFloat(0.002)},
+		{Ramp: "transak", NetworkFee: 1, TransactionFee: 4, Payout: models.NewAmountFromFloat(0.003),
+			FeeBreakdown: &models.FeeBreakdown{PartnerFee: models.NewAmountFromFloat(5)}},
+	}
+	fx := &stubFXProvider{rates: map[string]float64{"EUR/USD": 1.1, "BTC/USD": 60000}}
+
+	converted, err := QuotesInBaseCurrency(context.Background(), fx, quotes, "eur", "btc", "USD")
+	require.NoError(t, err)
+	require.Len(t, converted, 2)
+	assert.Equal(t, 2, fx.calls, "each rate is fetched once")
+
+	assert.Equal(t, "moonpay", converted[0].Ramp)
+	assert.Equal(t, "USD", converted[0].BaseCurrency)
+	assert.InDelta(t, 2.2, converted[0].BaseNetworkFee, 1e-9)
+	assert.InDelta(t, 3.3, converted[0].BaseTransactionFee, 1e-9)
+	assert.Equal(t, "5.5", converted[0].BaseTotalFees.String())
+	assert.Equal(t, "120", converted[0].BasePayout.String())
+	assert.Equal(t, "11", converted[1].BaseTotalFees.String())
+	assert.Equal(t, "180", converted[1].BasePayout.String())
+	assert.Equal(t, 2.0, converted[0].NetworkFee, "original fees are kept")
+
+	t.Run("base currency needs no rate", func(t *testing.T) {
+		fx := &stubFXProvider{rates: map[string]float64{"BTC/USD": 60000}}
+		converted, err := QuotesInBaseCurrency(context.Background(), fx, quotes[:1], "usd", "btc", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 1, fx.calls)
+		assert.Equal(t, "5", converted[0].BaseTotalFees.String())
+	})
+
+	t.Run("missing rate fails", func(t *testing.T) {
+		_, err := QuotesInBaseCurrency(context.Background(), &stubFXProvider{}, quotes, "eur", "btc", "USD")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "eur/USD")
+	})
+}
+
+func TestRecommendQuote(t *testing.T) {
+	quotes := []models.QuoteResponse{
+		{Ramp: "moonpay", Payout: models.NewAmountFromFloat(0.0102)},
+		{Ramp: "banxa", Payout: models.NewAmountFromFloat(0.0100), Recommendations: []string{"LowKyc"}},
+		{Ramp: "transak", Payout: models.NewAmountFromFloat(0.0110),
+			Errors: []models.QuoteError{{Type: "LimitMismatch", Message: "too small"}}},
+		{Ramp: "mercuryo", Payout: models.NewAmountFromFloat(0.0098)},
+	}
+
+	tests := []struct {
+		name         string
+		weights      SelectionWeights
+		expectedRamp string
+	}{
+		{name: "favouring price", weights: SelectionWeights{Price: 1, KYC: 0.01}, expectedRamp: "moonpay"},
+		{name: "favouring LowKyc", weights: SelectionWeights{Price: 1, KYC: 0.5}, expectedRamp: "banxa"},
+		{name: "favouring preferred ramp", weights: SelectionWeights{Price: 1, KYC: 0.5, Preference: 2,
+			PreferredRamps: []string{"Mercuryo", "banxa"}}, expectedRamp: "mercuryo"},
+		{name: "no weights keeps order", weights: SelectionWeights{}, expectedRamp: "moonpay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, ok := RecommendQuote(quotes, tt.weights)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedRamp, quote.Ramp)
+		})
+	}
+
+	_, ok := RecommendQuote(quotes[2:3], SelectionWeights{Price: 1})
+	assert.False(t, ok, "quotes with errors are never recommended")
+}
+
+func TestFastestQuote(t *testing.T) {
+	quotes := []models.QuoteResponse{
+		{Ramp: "banxa", PaymentMethod: "sepabanktransfer", Payout: models.NewAmountFromFloat(0.0110)},
+		{Ramp: "moonpay", PaymentMethod: "creditcard", Payout: models.NewAmountFromFloat(0.0098)},
+		{Ramp: "transak", PaymentMethod: "applepay", Payout: models.NewAmountFromFloat(0.0120),
+			Errors: []models.QuoteError{{Type: "LimitMismatch", Message: "too small"}}},
+		{Ramp: "mercuryo", PaymentMethod: "CreditCard", Payout: models.NewAmountFromFloat(0.0101)},
+		{Ramp: "alchemypay", PaymentMethod: "unknownmethod", Payout: models.NewAmountFromFloat(0.0200)},
+	}
+
+	tests := []struct {
+		name         string
+		ranking      SpeedRanking
+		expectedRamp string
+	}{
+		{name: "default ranking prefers cards over bank transfers", ranking: DefaultSpeedRanking, expectedRamp: "mercuryo"},
+		{name: "custom ranking", ranking: ParseSpeedRanking(" sepabanktransfer, ,creditcard"), expectedRamp: "banxa"},
+		{name: "unranked methods tie on payout", ranking: nil, expectedRamp: "alchemypay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, ok := FastestQuote(quotes, tt.ranking)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedRamp, quote.Ramp)
+		})
+	}
+
+	_, ok := FastestQuote(quotes[2:3], DefaultSpeedRanking)
+	assert.False(t, ok, "quotes with errors are never selected")
+}
+
+func TestStartCurrencyRefresherKeepsCacheWarm(t *testing.T) {
+	const interval = 5 * time.Minute
+	var mu sync.Mutex
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	calls := make(map[string]int)
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		total := 0
+		for _, n := range calls {
+			total += n
+		}
+		return total
+	}
+	ticks := make(chan time.Time)
+	stopped := make(chan struct{})
+	client := &Client{
+		BaseURL:    "https://mockapi.com",
+		APIKey:     "test-api-key",
+		Logger:     zap.NewNop(),
+		lifecycle:  newClientLifecycle(),
+		assetTypes: newAssetTypesCache(),
+		catalog:    newCatalogCache(),
+		Clock: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+		ticker: func(d time.Duration) (<-chan time.Time, func()) {
+			assert.Equal(t, interval, d)
+			return ticks, func() { close(stopped) }
+		},
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			mu.Lock()
+			calls[req.URL.RequestURI()]++
+			mu.Unlock()
+			body := `{"message":{"crypto":[{"id":"btc"}],"fiat":[{"id":"usd"}]}}`
+			if req.URL.Path == "/supported/onramps/all" {
+				body = `{"message":[{"id":"moonpay","displayName":"MoonPay"}]}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	ctx := context.Background()
+
+	// Starting warms the default buy and sell lists; a user's own corridor is cached on first use.
+	client.StartCurrencyRefresher(interval)
+	require.Eventually(t, func() bool { return callCount() == 4 }, time.Second, time.Millisecond)
+	_, err := client.GetCurrencies(ctx, "us", "", "buy")
+	require.NoError(t, err)
+	require.Equal(t, 5, callCount())
+
+	// Each tick renews the default lists without a foreground request; other corridors
+	// are left to expire.
+	mu.Lock()
+	now = start.Add(interval)
+	mu.Unlock()
+	ticks <- now
+	require.Eventually(t, func() bool { return callCount() == 9 }, time.Second, time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, calls["/supported?type=buy&country=us"])
+	assert.Equal(t, 2, calls["/supported/onramps/all?type=sell"])
+	mu.Unlock()
+
+	// Past the first fetch's expiry, the default lists are still answered from memory
+	// while the user's corridor is fetched again.
+	mu.Lock()
+	now = start.Add(DefaultCatalogTTL + time.Minute)
+	mu.Unlock()
+	_, err = client.GetCurrencies(ctx, "us", "", "buy")
+	require.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, 2, calls["/supported?type=buy&country=us"])
+	mu.Unlock()
+	_, err = client.GetCurrencies(ctx, "", "", "sell")
+	require.NoError(t, err)
+	metadata, err := client.GetOnrampMetadata(ctx, "buy")
+	require.NoError(t, err)
+	require.Len(t, metadata.Message, 1)
+	_, ok := client.assetTypes.get(client.now())
+	assert.True(t, ok, "quote validation list is renewed too")
+	assert.Equal(t, 10, callCount())
+
+	require.NoError(t, client.Close())
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("refresher did not stop on Close")
+	}
+}
+
+func TestCatalogCacheDisabledByDefault(t *testing.T) {
+	var calls atomic.Int32
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		catalog: newCatalogCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls.Add(1)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[],"fiat":[]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	for range 2 {
+		_, err := client.GetCurrencies(context.Background(), "", "", "buy")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), calls.Load(), "without the refresher every request reaches Onramper")
+}
+
+func TestCatalogRequestsRejectInvalidParams(t *testing.T) {
+	var calls atomic.Int32
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		etags:   newETagCache(),
+		catalog: newCatalogCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls.Add(1)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[],"fiat":[]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name                         string
+		ctx                          context.Context
+		country, subdivision, txType string
+	}{
+		{name: "long country", ctx: ctx, country: "usa", txType: "buy"},
+		{name: "query in country", ctx: ctx, country: "us&type=sell", txType: "buy"},
+		{name: "subdivision", ctx: ctx, country: "us", subdivision: "new-york", txType: "buy"},
+		{name: "type", ctx: ctx, txType: "swap"},
+		{name: "locale", ctx: WithLocale(ctx, "de_DE"), txType: "buy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetCurrencies(tt.ctx, tt.country, tt.subdivision, tt.txType)
+			require.ErrorIs(t, err, ErrInvalidCatalogParams)
+		})
+	}
+	_, err := client.GetOnrampMetadata(WithLocale(ctx, strings.Repeat("x", 64)), "buy")
+	require.ErrorIs(t, err, ErrInvalidCatalogParams)
+	assert.Zero(t, calls.Load(), "invalid requests never reach Onramper")
+
+	_, err = client.GetCurrencies(WithLocale(ctx, "pt-BR"), "br", "br-sp", "sell")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCatalogCachesAreBounded(t *testing.T) {
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		etags:   newETagCache(),
+		catalog: newCatalogCache(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			header := make(http.Header)
+			header.Set("ETag", `"v1"`)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[],"fiat":[]}}`)),
+				Header:     header,
+			}
+		}),
+	}
+	client.catalog.enable(DefaultCatalogTTL)
+
+	for first := 'a'; first <= 'z'; first++ {
+		for second := 'a'; second <= 'z'; second++ {
+			_, err := client.GetCurrencies(context.Background(), string([]rune{first, second}), "", "buy")
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, catalogCacheSize, client.catalog.entries.len())
+	assert.Equal(t, catalogCacheSize, client.etags.entries.len())
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRU[int](2)
+	cache.put("a", 1)
+	cache.put("b", 2)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+	cache.put("c", 3)
+
+	_, ok = cache.get("b")
+	assert.False(t, ok, "b was used least recently")
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	cache.put("a", 4)
+	value, _ = cache.get("a")
+	assert.Equal(t, 4, value)
+	assert.Equal(t, 2, cache.len())
+}
+
+func TestGetRawVariantsReturnBody(t *testing.T) {
+	quotesBody := `[{"ramp":"moonpay","rate":1.5,"payout":"0.01","quoteId":"q1","futureField":{"nested":true}}]`
+	currenciesBody := `{"message":{"crypto":[{"id":"btc","code":"BTC","icon":"https://cdn.onramper.com/icons/btc.svg"}],"fiat":[{"id":"usd"}]},"futureField":1}`
+	client := &Client{
+		BaseURL:     "https://mockapi.com",
+		APIKey:      "test-api-key",
+		Logger:      zap.NewNop(),
+		IconBaseURL: "https://assets.example.com",
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			body := quotesBody
+			if req.URL.Path == "/supported" {
+				body = currenciesBody
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	raw, quotes, err := client.GetQuotesRaw(context.Background(), "usd", "btc", &models.QuoteQueryParams{Amount: 100, Type: "buy"})
+	require.NoError(t, err)
+	assert.JSONEq(t, quotesBody, string(raw))
+	require.Len(t, quotes, 1)
+	assert.Equal(t, "q1", quotes[0].QuoteID)
+
+	raw, currencies, err := client.GetCurrenciesRaw(context.Background(), "", "", "buy")
+	require.NoError(t, err)
+	assert.JSONEq(t, currenciesBody, string(raw), "raw body is not rewritten")
+	require.Len(t, currencies.Message.Crypto, 1)
+	assert.NotContains(t, currencies.Message.Crypto[0].Icon, "cdn.onramper.com")
+}
+
+func TestGetQuotesTransactionType(t *testing.T) {
+	tests := []struct {
+		name         string
+		quoteType    string
+		expectedPath string
+		expectedType string
+		expectedErr  error
+	}{
+		{name: "empty defaults to buy", quoteType: "", expectedPath: "/quotes/usd/btc", expectedType: "buy"},
+		{name: "sell", quoteType: "sell", expectedPath: "/quotes/btc/usd", expectedType: "sell"},
+		{name: "case insensitive", quoteType: "Buy", expectedPath: "/quotes/usd/btc", expectedType: "buy"},
+		{name: "invalid", quoteType: "sel", expectedErr: ErrInvalidTransactionType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested *url.URL
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					requested = req.URL
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(`[{"ramp":"moonpay","payout":1}]`)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			params := &models.QuoteQueryParams{Amount: 100, Type: tt.quoteType}
+			_, err := client.GetQuotes(context.Background(), "usd", "btc", params)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, requested, "invalid types never reach Onramper")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPath, requested.Path)
+			assert.Equal(t, tt.expectedType, requested.Query().Get("type"))
+			assert.Equal(t, tt.quoteType, params.Type, "caller's params are not modified")
+		})
+	}
+}
+
+func TestGetQuotesInput(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedInput  string
+		expectedAmount string
+		expectedErr    error
+	}{
+		{name: "empty quotes the source amount", input: "", expectedInput: "", expectedAmount: "100.46"},
+		{name: "source", input: "source", expectedInput: "source", expectedAmount: "100.46"},
+		{name: "destination", input: "Destination", expectedInput: "destination", expectedAmount: "100.456"},
+		{name: "currency code", input: "BTC", expectedErr: ErrInvalidQuoteInput},
+		{name: "invalid", input: "target", expectedErr: ErrInvalidQuoteInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested *url.URL
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					requested = req.URL
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(`[{"ramp":"moonpay","payout":1}]`)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			params := &models.QuoteQueryParams{Amount: 100.456, Type: "buy", Input: tt.input}
+			_, err := client.GetQuotes(context.Background(), "usd", "btc", params)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, requested, "invalid input never reaches Onramper")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedInput, requested.Query().Get("input"))
+			assert.Equal(t, tt.expectedAmount, requested.Query().Get("amount"))
+		})
+	}
+}
+
+func TestLocaleSetsAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		ctx      context.Context
+		expected string
+	}{
+		{name: "no locale", ctx: context.Background(), expected: ""},
+		{name: "client default", locale: "de-DE", ctx: context.Background(), expected: "de-DE"},
+		{name: "context overrides default", locale: "de-DE", ctx: WithLocale(context.Background(), "fr-FR"), expected: "fr-FR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			client := &Client{
+				BaseURL: "https://mockapi.com",
+				APIKey:  "test-api-key",
+				Logger:  zap.NewNop(),
+				Locale:  tt.locale,
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					headers = append(headers, req.Header.Get("Accept-Language"))
+					body := `{"message":{}}`
+					if req.URL.Path == "/supported/onramps/all" {
+						body = `{"message":[]}`
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(body)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			_, err := client.GetCurrencies(tt.ctx, "", "", "buy")
+			require.NoError(t, err)
+			_, err = client.GetPaymentTypes(tt.ctx, "buy", false, "")
+			require.NoError(t, err)
+			_, err = client.GetOnrampMetadata(tt.ctx, "buy")
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.expected, tt.expected, tt.expected}, headers)
+		})
+	}
+}
+
+func TestConcurrentIdenticalRequestsShareOneUpstreamCall(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		flights: newRequestFlights(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			calls.Add(1)
+			<-release
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[{"id":"btc"}],"fiat":[{"id":"usd"}]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	const callers = 10
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	results := make([]models.SupportedCurrenciesResponse, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		go func() {
+			defer done.Done()
+			started.Done()
+			results[i], errs[i] = client.GetCurrencies(context.Background(), "us", "", "buy")
+		}()
+	}
+	started.Wait()
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // let the other callers join the flight
+	close(release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for i := range callers {
+		require.NoError(t, errs[i])
+		require.Len(t, results[i].Message.Crypto, 1)
+		assert.Equal(t, "btc", results[i].Message.Crypto[0].ID)
+	}
+
+	// Requests for a different partner key are not shared.
+	_, err := client.WithAPIKey("other-key").GetCurrencies(context.Background(), "us", "", "buy")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHungSharedRequestIsForgotten(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	client := &Client{
+		BaseURL:       "https://mockapi.com",
+		APIKey:        "test-api-key",
+		Logger:        zap.NewNop(),
+		FlightTimeout: 20 * time.Millisecond,
+		flights:       newRequestFlights(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			if calls.Add(1) == 1 {
+				<-release // hangs regardless of the request context
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[{"id":"btc"}],"fiat":[{"id":"usd"}]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := client.GetCurrencies(context.Background(), "us", "", "buy")
+	require.ErrorIs(t, err, context.DeadlineExceeded, "a caller without a deadline still gives up")
+
+	currencies, err := client.GetCurrencies(context.Background(), "us", "", "buy")
+	require.NoError(t, err, "the next request does not join the hung flight")
+	require.Len(t, currencies.Message.Crypto, 1)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSharedRequestHasDeadline(t *testing.T) {
+	var deadline time.Time
+	client := &Client{
+		BaseURL: "https://mockapi.com",
+		APIKey:  "test-api-key",
+		Logger:  zap.NewNop(),
+		flights: newRequestFlights(),
+		HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+			deadline, _ = req.Context().Deadline()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"message":{"crypto":[],"fiat":[]}}`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	before := time.Now()
+	_, err := client.GetCurrencies(context.Background(), "us", "", "buy")
+	require.NoError(t, err)
+	assert.WithinDuration(t, before.Add(DefaultFlightTimeout), deadline, time.Second)
+}
+
+func TestGetQuotesAmountInMinorUnits(t *testing.T) {
+	tests := []struct {
+		name           string
+		params         models.QuoteQueryParams
+		fiat           string
+		crypto         string
+		expectedAmount string
+		expectedErr    error
+	}{
+		{name: "cents to fiat", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 10000, Type: "buy", AmountInMinorUnits: true}, expectedAmount: "100"},
+		{name: "odd cents", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 12345, Type: "buy", AmountInMinorUnits: true}, expectedAmount: "123.45"},
+		{name: "satoshis to BTC", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 150000, Type: "sell", AmountInMinorUnits: true}, expectedAmount: "0.0015"},
+		{name: "major units unchanged", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 100, Type: "buy"}, expectedAmount: "100"},
+		{name: "yen have no minor unit", fiat: "JPY", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 15000, Type: "buy", AmountInMinorUnits: true}, expectedAmount: "15000"},
+		{name: "yen amounts are whole", fiat: "jpy", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 15000.4, Type: "buy"}, expectedAmount: "15000"},
+		{name: "fils to dinar", fiat: "kwd", crypto: "btc",
+			params: models.QuoteQueryParams{Amount: 12345, Type: "buy", AmountInMinorUnits: true}, expectedAmount: "12.345"},
+		{name: "unknown crypto decimals", crypto: "doge",
+			params: models.QuoteQueryParams{Amount: 100, Type: "sell", AmountInMinorUnits: true}, expectedErr: ErrUnknownDecimals},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var amount string
+			client := &Client{
+				BaseURL:    "https://mockapi.com",
+				APIKey:     "test-api-key",
+				Logger:     zap.NewNop(),
+				assetTypes: newAssetTypesCache(),
+				HTTPClient: newMockHTTPClient(func(req *http.Request) *http.Response {
+					body := `[{"ramp":"moonpay","payout":1}]`
+					if req.URL.Path == "/supported" {
+						body = `{"message":{"crypto":[{"id":"btc","code":"BTC","decimals":8}],"fiat":[{"id":"usd","code":"USD"}]}}`
+					} else {
+						amount = req.URL.Query().Get("amount")
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(body)),
+						Header:     make(http.Header),
+					}
+				}),
+			}
+
+			fiat := tt.fiat
+			if fiat == "" {
+				fiat = "usd"
+			}
+			_, err := client.GetQuotes(context.Background(), fiat, tt.crypto, &tt.params)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, amount, "no quote request is sent")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAmount, amount)
+		})
+	}
+}
+
+func TestDisableHTTP2(t *testing.T) {
+	client := NewClient("https://mockapi.com", "test-api-key", "secret", zap.NewNop()).(*Client)
+	original := client.HTTPClient
+	client.DisableHTTP2()
+
+	transport, ok := client.HTTPClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+	assert.NotSame(t, http.DefaultTransport, transport, "the shared default transport is not modified")
+	assert.Nil(t, original.Transport, "the previous HTTP client is not modified")
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	resp, err := client.HTTPClient.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
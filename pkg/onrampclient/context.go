@@ -0,0 +1,79 @@
+package onrampclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// ClientIPHeader carries the end-user IP to Onramper for geo and fraud checks.
+const ClientIPHeader = "X-Client-IP"
+
+type contextKey int
+
+const (
+	clientIPKey contextKey = iota
+	maxRetriesKey
+	localeKey
+)
+
+// WithClientIP returns a context that forwards ip to Onramper on supported calls.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	if ip == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext returns the end-user IP stored by WithClientIP.
+func ClientIPFromContext(ctx context.Context) (ip string, ok bool) {
+	ip, ok = ctx.Value(clientIPKey).(string)
+	return ip, ok
+}
+
+// setClientIPHeader forwards the end-user IP from the request context, if present.
+func setClientIPHeader(req *http.Request) {
+	if ip, ok := ClientIPFromContext(req.Context()); ok {
+		req.Header.Set(ClientIPHeader, ip)
+	}
+}
+
+// WithMaxRetries returns a context whose requests are retried up to n times per base URL,
+// overriding the client's MaxRetries in either direction. Zero disables retries.
+func WithMaxRetries(ctx context.Context, n int) context.Context {
+	if n < 0 {
+		n = 0
+	}
+	return context.WithValue(ctx, maxRetriesKey, n)
+}
+
+// MaxRetriesFromContext returns the retry budget stored by WithMaxRetries.
+func MaxRetriesFromContext(ctx context.Context) (n int, ok bool) {
+	n, ok = ctx.Value(maxRetriesKey).(int)
+	return n, ok
+}
+
+// WithLocale returns a context whose catalogue requests ask Onramper for locale,
+// e.g. "de-DE", overriding the client's Locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	if locale == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFromContext returns the locale stored by WithLocale.
+func LocaleFromContext(ctx context.Context) (locale string, ok bool) {
+	locale, ok = ctx.Value(localeKey).(string)
+	return locale, ok
+}
+
+// setLocaleHeader sends the request's locale, or the client's default, as Accept-Language.
+func (h Client) setLocaleHeader(req *http.Request) {
+	locale, ok := LocaleFromContext(req.Context())
+	if !ok {
+		locale = h.Locale
+	}
+	if locale != "" {
+		req.Header.Set("Accept-Language", locale)
+	}
+}
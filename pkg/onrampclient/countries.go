@@ -0,0 +1,25 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GetSupportedCountries returns the sorted ISO codes of countries Onramper supports for
+// transactionType. Onramper has no country listing endpoint, so the codes are the keys
+// of the per-country defaults.
+func (h Client) GetSupportedCountries(ctx context.Context, transactionType string) (countries []string, err error) {
+	defaults, err := h.GetDefaults(ctx, transactionType, "", "")
+	if err != nil {
+		err = fmt.Errorf("failed to fetch country defaults: %w", err)
+		return countries, err
+	}
+	countries = make([]string, 0, len(defaults.Message.Defaults))
+	for code := range defaults.Message.Defaults {
+		countries = append(countries, strings.ToLower(code))
+	}
+	sort.Strings(countries)
+	return countries, nil
+}
@@ -0,0 +1,238 @@
+package onrampclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultAssetTypesTTL is how long the currency list used to validate quote corridors is cached.
+const DefaultAssetTypesTTL = 10 * time.Minute
+
+// logCurrencyAnomalies warns about cryptocurrencies with implausible decimals or chain IDs.
+// They are still returned so a single bad entry does not hide the rest of the list.
+func (h Client) logCurrencyAnomalies(cryptos []models.CryptoCurrency) {
+	for _, crypto := range cryptos {
+		if err := crypto.Validate(); err != nil {
+			h.Logger.Warn("Unexpected cryptocurrency metadata",
+				zap.String("id", crypto.ID),
+				zap.Int("decimals", crypto.Decimals),
+				zap.Int64("chain_id", crypto.ChainID),
+				zap.Error(err))
+		}
+	}
+}
+
+// assetTypes classifies lowercased currency IDs and codes as fiat or crypto, and
+// remembers each cryptocurrency's decimals.
+type assetTypes struct {
+	fiat     map[string]struct{}
+	crypto   map[string]struct{}
+	decimals map[string]int
+}
+
+func newAssetTypes(currencies models.SupportedCurrencies) assetTypes {
+	types := assetTypes{
+		fiat:     make(map[string]struct{}, len(currencies.Fiat)),
+		crypto:   make(map[string]struct{}, len(currencies.Crypto)),
+		decimals: make(map[string]int, len(currencies.Crypto)),
+	}
+	for _, fiat := range currencies.Fiat {
+		types.fiat[strings.ToLower(fiat.ID)] = struct{}{}
+		types.fiat[strings.ToLower(fiat.Code)] = struct{}{}
+	}
+	for _, crypto := range currencies.Crypto {
+		types.crypto[strings.ToLower(crypto.ID)] = struct{}{}
+		types.crypto[strings.ToLower(crypto.Code)] = struct{}{}
+		if crypto.Decimals > 0 {
+			types.decimals[strings.ToLower(crypto.ID)] = crypto.Decimals
+		}
+	}
+	return types
+}
+
+// isOnly reports whether code is listed in set but not in other.
+func isOnly(code string, set, other map[string]struct{}) bool {
+	code = strings.ToLower(code)
+	_, inSet := set[code]
+	_, inOther := other[code]
+	return inSet && !inOther
+}
+
+// check returns ErrAssetTypeMismatch when fiat is only known as a crypto or crypto only
+// as a fiat. Codes missing from the list are accepted.
+func (t assetTypes) check(fiat, crypto string) error {
+	if isOnly(fiat, t.crypto, t.fiat) || isOnly(crypto, t.fiat, t.crypto) {
+		return fmt.Errorf("%w: fiat %q, crypto %q", ErrAssetTypeMismatch, fiat, crypto)
+	}
+	return nil
+}
+
+// assetTypesCache keeps the last classified currency list until it expires.
+type assetTypesCache struct {
+	mu        sync.Mutex
+	types     assetTypes
+	expiresAt time.Time
+}
+
+func newAssetTypesCache() *assetTypesCache {
+	return &assetTypesCache{}
+}
+
+func (c *assetTypesCache) get(now time.Time) (assetTypes, bool) {
+	if c == nil {
+		return assetTypes{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.types, c.types.fiat != nil && now.Before(c.expiresAt)
+}
+
+func (c *assetTypesCache) put(types assetTypes, expiresAt time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types, c.expiresAt = types, expiresAt
+}
+
+// cachedAssetTypes returns the cached currency classification, fetching it when stale.
+func (h Client) cachedAssetTypes(ctx context.Context) (assetTypes, error) {
+	types, ok := h.assetTypes.get(h.now())
+	if ok {
+		return types, nil
+	}
+	return h.refreshAssetTypes(ctx)
+}
+
+// validateQuoteAssets checks that fiat and crypto are not swapped, using the cached
+// currency list. When the list cannot be fetched the corridor is not validated.
+func (h Client) validateQuoteAssets(ctx context.Context, fiat, crypto string) error {
+	types, err := h.cachedAssetTypes(ctx)
+	if err != nil {
+		h.Logger.Warn("Skipping quote asset validation", zap.Error(err))
+		return nil
+	}
+	err = types.check(fiat, crypto)
+	if err != nil {
+		h.Logger.Warn("Quote fiat and crypto look swapped",
+			zap.String("fiat", fiat),
+			zap.String("crypto", crypto))
+	}
+	return err
+}
+
+// refreshAssetTypes fetches the currency list and caches it for DefaultAssetTypesTTL.
+func (h Client) refreshAssetTypes(ctx context.Context) (assetTypes, error) {
+	currencies, err := h.GetCurrencies(ctx, "", "", transactionTypeBuy)
+	if err != nil {
+		return assetTypes{}, err
+	}
+	types := newAssetTypes(currencies.Message)
+	h.assetTypes.put(types, h.now().Add(DefaultAssetTypesTTL))
+	return types, nil
+}
+
+// StartCurrencyRefresher serves currency and onramp metadata responses from memory and
+// renews the default buy and sell lists and the currency list used to validate quotes
+// now and then every interval in the background, so most user requests never wait on a
+// cold cache. Responses stay fresh for DefaultCatalogTTL, or two intervals if that is
+// longer; lists for other countries or locales are fetched again once they expire. The
+// refresher stops when the client is closed.
+func (h Client) StartCurrencyRefresher(interval time.Duration) {
+	if interval <= 0 || h.lifecycle == nil {
+		return
+	}
+	h.catalog.enable(max(DefaultCatalogTTL, 2*interval))
+	go func() {
+		ticks, stop := h.startTicker(interval)
+		defer stop()
+		for {
+			h.refreshCatalog(h.lifecycle.ctx)
+			select {
+			case <-h.lifecycle.ctx.Done():
+				return
+			case <-ticks:
+			}
+		}
+	}()
+}
+
+// startTicker returns the injected ticker, or a time.Ticker firing every interval.
+func (h Client) startTicker(interval time.Duration) (ticks <-chan time.Time, stop func()) {
+	if h.ticker != nil {
+		return h.ticker(interval)
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+// refreshCatalog refetches the default buy and sell currency lists and onramp metadata in
+// the client's locale, then rebuilds the quote validation list from the renewed currencies.
+func (h Client) refreshCatalog(ctx context.Context) {
+	var errs []error
+	for _, transactionType := range []string{transactionTypeBuy, transactionTypeSell} {
+		_, _, err := h.fetchCurrencies(ctx, "", "", transactionType, false)
+		errs = append(errs, err)
+		_, err = h.fetchOnrampMetadata(ctx, transactionType, false)
+		errs = append(errs, err)
+	}
+	_, err := h.refreshAssetTypes(ctx)
+	errs = append(errs, err)
+	if err = errors.Join(errs...); err != nil && !h.lifecycle.closed() {
+		h.Logger.Warn("Failed to refresh currency cache", zap.Error(err))
+	}
+}
+
+// GetCurrenciesBothDirections fetches buy and sell currencies concurrently and returns
+// only those supported in both directions, matched by ID so a token on one network does
+// not stand in for the same code on another. Entries keep the buy list's order and details.
+func (h Client) GetCurrenciesBothDirections(ctx context.Context, country string) (currencies models.SupportedCurrenciesResponse, err error) {
+	transactionTypes := []string{transactionTypeBuy, transactionTypeSell}
+	results := make([]models.SupportedCurrenciesResponse, len(transactionTypes))
+	errs := make([]error, len(transactionTypes))
+
+	var wg sync.WaitGroup
+	for i, transactionType := range transactionTypes {
+		wg.Add(1)
+		go func(i int, transactionType string) {
+			defer wg.Done()
+			results[i], errs[i] = h.GetCurrencies(ctx, country, "", transactionType)
+		}(i, transactionType)
+	}
+	wg.Wait()
+
+	for i, transactionType := range transactionTypes {
+		if errs[i] != nil {
+			err = fmt.Errorf("failed to fetch %s currencies: %w", transactionType, errs[i])
+			return currencies, err
+		}
+	}
+	buy, sell := results[0].Message, results[1].Message
+	currencies.Message.Crypto = intersectByID(buy.Crypto, sell.Crypto, func(c models.CryptoCurrency) string { return c.ID })
+	currencies.Message.Fiat = intersectByID(buy.Fiat, sell.Fiat, func(f models.FiatCurrency) string { return f.ID })
+	return currencies, err
+}
+
+// intersectByID returns the items of a whose ID, compared case-insensitively, also
+// appears in b.
+func intersectByID[T any](a, b []T, id func(T) string) []T {
+	inB := make(map[string]struct{}, len(b))
+	for _, item := range b {
+		inB[strings.ToLower(id(item))] = struct{}{}
+	}
+	both := make([]T, 0, len(a))
+	for _, item := range a {
+		if _, ok := inB[strings.ToLower(id(item))]; ok {
+			both = append(both, item)
+		}
+	}
+	return both
+}
@@ -0,0 +1,105 @@
+package onrampclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// requestFlights collapses identical concurrent GET requests into one upstream call.
+type requestFlights struct {
+	group singleflight.Group
+}
+
+func newRequestFlights() *requestFlights {
+	return &requestFlights{}
+}
+
+// sharedResponse is an upstream response whose body has been read so it can be
+// handed to every caller of a flight.
+type sharedResponse struct {
+	status     string
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// DefaultFlightTimeout bounds a GET request shared by concurrent callers.
+const DefaultFlightTimeout = 30 * time.Second
+
+// flightTimeout returns the configured shared request timeout, or the default.
+func (h Client) flightTimeout() time.Duration {
+	if h.FlightTimeout > 0 {
+		return h.FlightTimeout
+	}
+	return DefaultFlightTimeout
+}
+
+// flightKey identifies requests that may share a response: the same URL sent with the
+// same credentials, locale, end-user IP and cache validator.
+func flightKey(req *http.Request) string {
+	return strings.Join([]string{
+		req.URL.String(),
+		req.Header.Get("Authorization"),
+		req.Header.Get("Accept-Language"),
+		req.Header.Get(ClientIPHeader),
+		req.Header.Get("If-None-Match"),
+	}, "\n")
+}
+
+// do sends GET requests through the flight for their key, so concurrent identical
+// requests reach Onramper once and share the result. The shared call is not cancelled
+// by any one caller, but it is bounded by the flight timeout; each caller still stops
+// waiting when its own context is done. A flight that outlives its timeout is forgotten,
+// so later requests start afresh instead of joining it. Other methods, and clients
+// without flights, go straight to doOnce.
+func (h Client) do(req *http.Request) (*http.Response, error) {
+	if h.flights == nil || req.Method != http.MethodGet {
+		return h.doOnce(req)
+	}
+	key := flightKey(req)
+	timeout := h.flightTimeout()
+	results := h.flights.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), timeout)
+		defer cancel()
+		resp, err := h.doOnce(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := h.readBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		return sharedResponse{status: resp.Status, statusCode: resp.StatusCode, header: resp.Header, body: body}, nil
+	})
+	expired := time.NewTimer(timeout)
+	defer expired.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-expired.C:
+		h.flights.group.Forget(key)
+		return nil, fmt.Errorf("shared request timed out after %s: %w", timeout, context.DeadlineExceeded)
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		response := result.Val.(sharedResponse) //nolint:forcetypeassert // only sharedResponse is stored.
+		return &http.Response{
+			Status:        response.status,
+			StatusCode:    response.statusCode,
+			Header:        response.header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(response.body)),
+			ContentLength: int64(len(response.body)),
+			Request:       req,
+		}, nil
+	}
+}
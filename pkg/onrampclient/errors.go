@@ -0,0 +1,165 @@
+package onrampclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+var (
+	// ErrTransactionNotConfirmed is returned when a sell payout is requested before the sell was confirmed.
+	ErrTransactionNotConfirmed = errors.New("sell transaction not yet confirmed")
+	// ErrResponseTooLarge is returned when an upstream body exceeds the client's size cap.
+	ErrResponseTooLarge = errors.New("response body exceeds size limit")
+	// ErrTransactionNotFound is returned when Onramper does not know the transaction.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrInvalidTransactionID is returned when Onramper rejects a transaction ID as malformed.
+	ErrInvalidTransactionID = errors.New("invalid transaction ID")
+	// ErrAlreadyConfirmed is returned when a sell transaction was confirmed before.
+	ErrAlreadyConfirmed = errors.New("transaction already confirmed")
+	// ErrInvalidTransactionState is returned when a transaction cannot be confirmed in its current state.
+	ErrInvalidTransactionState = errors.New("transaction is in an invalid state")
+	// ErrNoQuotes is returned when Onramper has no quotes for a request.
+	ErrNoQuotes = errors.New("no quotes found")
+	// ErrUnknownPaymentMethod is returned when a quote asks for a payment method Onramper does not offer.
+	ErrUnknownPaymentMethod = errors.New("unknown payment method")
+	// ErrPaymentMethodUnsupported is returned when a known payment method has no quotes for the corridor.
+	ErrPaymentMethodUnsupported = errors.New("payment method unsupported for corridor")
+	// ErrAssetTypeMismatch is returned when a quote's fiat is a known cryptocurrency or its
+	// crypto a known fiat currency, which usually means the two were passed in reverse.
+	ErrAssetTypeMismatch = errors.New("fiat and crypto currencies are swapped")
+	// ErrInvalidTransactionType is returned when a quote's type is neither buy nor sell.
+	ErrInvalidTransactionType = errors.New("transaction type must be buy or sell")
+	// ErrInvalidQuoteInput is returned when a quote's input is neither source nor destination.
+	ErrInvalidQuoteInput = errors.New("quote input must be source or destination")
+	// ErrUnknownDecimals is returned when a minor-unit amount is in a cryptocurrency
+	// whose decimals Onramper does not list.
+	ErrUnknownDecimals = errors.New("currency decimals unknown")
+	// ErrQuoteNotFound is returned when a quote ID is unknown or was never returned by GetQuotes.
+	ErrQuoteNotFound = errors.New("quote not found")
+	// ErrQuoteExpired is returned when a quote ID refers to a quote that has expired.
+	ErrQuoteExpired = errors.New("quote expired")
+	// ErrUpstreamUnavailable is returned when Onramper answers 503, e.g. during planned maintenance.
+	ErrUpstreamUnavailable = errors.New("onramper temporarily unavailable")
+	// ErrUnknownPartner is returned when no client is registered for a partner ID.
+	ErrUnknownPartner = errors.New("unknown partner")
+	// ErrQuoteRejected is returned when Onramper answers a quote request with a client
+	// error other than a rejected API key, so asking again fails the same way.
+	ErrQuoteRejected = errors.New("onramper rejected the quote request")
+	// ErrUnauthorized is returned when Onramper answers 401 or 403, i.e. rejects the API key.
+	ErrUnauthorized = errors.New("onramper rejected the API key")
+	// ErrClientClosed is returned for requests started, or still running, when the client is closed.
+	ErrClientClosed = errors.New("onramper client closed")
+	// ErrInvalidCatalogParams is returned without calling Onramper when a currency or
+	// onramp metadata request has a malformed country, subdivision, type or locale.
+	ErrInvalidCatalogParams = errors.New("invalid catalogue request parameters")
+	// ErrCircuitOpen is returned without calling Onramper while an endpoint's breaker is open.
+	ErrCircuitOpen = errors.New("onramper circuit breaker open")
+)
+
+// confirmSellError maps a non-200 confirm response to a typed error where possible.
+func confirmSellError(statusCode int, body []byte) error {
+	message := string(body)
+	lower := strings.ToLower(message)
+	switch {
+	case statusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrTransactionNotFound, message)
+	case strings.Contains(lower, "already confirmed"):
+		return fmt.Errorf("%w: %s", ErrAlreadyConfirmed, message)
+	case statusCode == http.StatusConflict, statusCode == http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: %s", ErrInvalidTransactionState, message)
+	default:
+		return fmt.Errorf("failed to confirm sell transaction with status code: %d - message: %s", statusCode, message)
+	}
+}
+
+// authError wraps err with ErrUnauthorized when statusCode means the API key was rejected.
+func authError(statusCode int, err error) error {
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// quoteError maps a non-200 quotes response to a typed error where possible.
+func quoteError(statusCode int, body []byte) error {
+	err := fmt.Errorf("unable to get quotes: %d - %s", statusCode, string(body))
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return authError(statusCode, err)
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests:
+		return err
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError:
+		return fmt.Errorf("%w: %w", ErrQuoteRejected, err)
+	default:
+		return err
+	}
+}
+
+// transactionError maps a non-200 transaction lookup response to a typed error where possible.
+func transactionError(statusCode int, body []byte) error {
+	message := string(body)
+	switch statusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrTransactionNotFound, message)
+	case http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", ErrInvalidTransactionID, message)
+	default:
+		return fmt.Errorf("unable to get transaction: %d - %s", statusCode, message)
+	}
+}
+
+// UnavailableError is returned for an Onramper 503 and unwraps to ErrUpstreamUnavailable.
+type UnavailableError struct {
+	// RetryAfter is Onramper's Retry-After header, passed through verbatim.
+	RetryAfter string
+	// Message is the response body.
+	Message string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUpstreamUnavailable, e.Message)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrUpstreamUnavailable
+}
+
+// AllQuotesFailed is returned when Onramper answered with quotes but every one of them
+// carries provider errors.
+type AllQuotesFailed struct {
+	// Errors holds the distinct provider errors, in the order first seen.
+	Errors []models.QuoteError
+}
+
+func (e *AllQuotesFailed) Error() string {
+	reasons := make([]string, 0, len(e.Errors))
+	for _, quoteErr := range e.Errors {
+		reasons = append(reasons, fmt.Sprintf("%s (%s)", quoteErr.Type, quoteErr.Message))
+	}
+	return "all quotes failed: " + strings.Join(reasons, "; ")
+}
+
+// allQuotesFailed aggregates the distinct errors of quotes that all failed.
+// It returns nil if any quote is usable or there are no quotes at all.
+func allQuotesFailed(quotes []models.QuoteResponse) *AllQuotesFailed {
+	if len(quotes) == 0 || hasUsableQuote(quotes) {
+		return nil
+	}
+	aggregated := &AllQuotesFailed{}
+	seen := make(map[models.QuoteError]struct{})
+	for _, quote := range quotes {
+		for _, quoteErr := range quote.Errors {
+			key := models.QuoteError{Type: quoteErr.Type, Message: quoteErr.Message}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			aggregated.Errors = append(aggregated.Errors, quoteErr)
+		}
+	}
+	return aggregated
+}
@@ -0,0 +1,82 @@
+package onrampclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// etagCache keeps the last successful response of endpoints Onramper serves with an
+// ETag, so refetches are sent as conditional requests and a 304 is answered from
+// memory without downloading or decoding the body again. Responses without an ETag are
+// not cached, which makes this a no-op if Onramper does not send them.
+//
+// Entries are keyed by flightKey, so there is one per URL, credential and locale. Those
+// include the caller's country and locale, so the cache keeps only the catalogCacheSize
+// most recently used.
+type etagCache struct {
+	mu      sync.Mutex
+	entries *lru[etagEntry]
+}
+
+// etagEntry is a cached response: its ETag, the body as sent and the decoded value.
+type etagEntry struct {
+	etag  string
+	raw   json.RawMessage
+	value interface{}
+}
+
+func newETagCache() *etagCache {
+	return &etagCache{entries: newLRU[etagEntry](catalogCacheSize)}
+}
+
+func (c *etagCache) get(key string) (etagEntry, bool) {
+	if c == nil {
+		return etagEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.get(key)
+}
+
+func (c *etagCache) put(key string, entry etagEntry) {
+	if c == nil || entry.etag == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.put(key, entry)
+}
+
+// conditional makes req conditional on the cached response for it, if any, and returns
+// the key to store the new response under along with the cached entry.
+func (h Client) conditional(req *http.Request) (key string, cached etagEntry, ok bool) {
+	key = flightKey(req)
+	cached, ok = h.etags.get(key)
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	return key, cached, ok
+}
+
+// notModified reports whether resp confirms the cached entry is still current.
+func notModified(resp *http.Response, cached bool) bool {
+	return cached && resp.StatusCode == http.StatusNotModified
+}
+
+// cloneCurrencies copies the currency lists so the cached response and the one handed
+// to a caller can be modified independently.
+func cloneCurrencies(currencies models.SupportedCurrenciesResponse) models.SupportedCurrenciesResponse {
+	currencies.Message.Crypto = slices.Clone(currencies.Message.Crypto)
+	currencies.Message.Fiat = slices.Clone(currencies.Message.Fiat)
+	return currencies
+}
+
+// cloneMetadata copies the metadata list, as cloneCurrencies does for currencies.
+func cloneMetadata(metadata models.OnrampMetadataResponse) models.OnrampMetadataResponse {
+	metadata.Message = slices.Clone(metadata.Message)
+	return metadata
+}
@@ -0,0 +1,166 @@
+package onrampclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultRetryBackoff is the pause between retries against the same base URL.
+const DefaultRetryBackoff = 200 * time.Millisecond
+
+// baseURLHealth remembers which base URL last answered without a server error.
+type baseURLHealth struct {
+	mu      sync.Mutex
+	healthy int
+}
+
+func (b *baseURLHealth) current() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.healthy
+}
+
+func (b *baseURLHealth) markHealthy(index int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.healthy = index
+}
+
+// baseURLs returns the configured base URLs, primary first.
+func (h Client) baseURLs() []string {
+	if len(h.BaseURLs) > 0 {
+		return h.BaseURLs
+	}
+	return []string{h.BaseURL}
+}
+
+// primaryBaseURL is the base URL request URLs are built against.
+func (h Client) primaryBaseURL() string {
+	return h.baseURLs()[0]
+}
+
+// HealthyBaseURL returns the base URL requests are currently sent to first.
+func (h Client) HealthyBaseURL() string {
+	bases := h.baseURLs()
+	return bases[h.health.current()%len(bases)]
+}
+
+// retryBackoff returns the configured pause between retries, or the default.
+func (h Client) retryBackoff() time.Duration {
+	if h.RetryBackoff > 0 {
+		return h.RetryBackoff
+	}
+	return DefaultRetryBackoff
+}
+
+// maxRetries returns the request's retry budget from WithMaxRetries, or MaxRetries.
+func (h Client) maxRetries(req *http.Request) int {
+	if n, ok := MaxRetriesFromContext(req.Context()); ok {
+		return n
+	}
+	return h.MaxRetries
+}
+
+// send sends req, starting with the last healthy base URL. Idempotent requests that fail
+// with a transport error or a 5xx are retried MaxRetries times (or the context's
+// WithMaxRetries budget), then sent to the next base URL. Other requests are sent once,
+// since replaying them could duplicate side effects.
+// When every attempt fails, the last response (or error) is returned to the caller.
+func (h Client) send(req *http.Request) (*http.Response, error) {
+	suffix, ok := strings.CutPrefix(req.URL.String(), h.primaryBaseURL())
+	if !ok {
+		return h.HTTPClient.Do(req)
+	}
+	bases := h.baseURLs()
+	start := h.health.current() % len(bases)
+	attempts, retries := len(bases), h.maxRetries(req)
+	if !isIdempotent(req) {
+		attempts, retries = 1, 0
+	}
+
+	var (
+		lastResp *http.Response
+		lastErr  error
+	)
+	for i := 0; i < attempts; i++ {
+		index := (start + i) % len(bases)
+		target := bases[index] + suffix
+		for attempt := 0; attempt <= retries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-req.Context().Done():
+					closeBody(lastResp)
+					return nil, req.Context().Err()
+				case <-time.After(h.retryBackoff()):
+				}
+			}
+			attemptReq, err := cloneRequest(req, target)
+			if err != nil {
+				closeBody(lastResp)
+				return nil, err
+			}
+			resp, err := h.HTTPClient.Do(attemptReq)
+			if err == nil && resp.StatusCode < http.StatusInternalServerError {
+				closeBody(lastResp)
+				h.health.markHealthy(index)
+				return resp, nil
+			}
+			closeBody(lastResp)
+			lastResp, lastErr = resp, err
+			if req.Context().Err() != nil {
+				break
+			}
+		}
+		if i+1 < attempts {
+			h.Logger.Warn("Onramper base URL failing, trying next",
+				zap.String("base_url", bases[index]),
+				zap.Error(lastErr))
+		}
+	}
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, lastErr
+}
+
+// cloneRequest copies req for one attempt against target, rewinding the body if needed.
+func cloneRequest(req *http.Request, target string) (*http.Request, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request URL %q: %w", target, err)
+	}
+	clone := req.Clone(req.Context())
+	clone.URL = u
+	clone.Host = ""
+	if req.GetBody != nil {
+		clone.Body, err = req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+	}
+	return clone, nil
+}
+
+// isIdempotent reports whether req can be replayed without duplicating side effects:
+// GET and HEAD requests, and requests carrying an Idempotency-Key.
+func isIdempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,74 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// FXProvider supplies exchange rates for normalising quotes to a common base currency.
+type FXProvider interface {
+	// Rate returns how many units of to one unit of from is worth.
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// BaseQuote is a quote with its fees and payout converted to a base currency for
+// display. The original quote is kept unchanged.
+type BaseQuote struct {
+	models.QuoteResponse
+	BaseCurrency       string        `json:"baseCurrency"`
+	BaseNetworkFee     float64       `json:"baseNetworkFee"`
+	BaseTransactionFee float64       `json:"baseTransactionFee"`
+	BaseTotalFees      models.Amount `json:"baseTotalFees"`
+	BasePayout         models.Amount `json:"basePayout"`
+}
+
+// QuotesInBaseCurrency converts each quote's fees, charged in feeCurrency, and payout,
+// paid in payoutCurrency, to base using rates from fx. Each rate is fetched once per
+// call; a currency equal to base is not looked up.
+func QuotesInBaseCurrency(ctx context.Context, fx FXProvider, quotes []models.QuoteResponse, feeCurrency, payoutCurrency, base string) ([]BaseQuote, error) {
+	rates := make(map[string]float64, 2)
+	rateTo := func(currency string) (float64, error) {
+		if strings.EqualFold(currency, base) {
+			return 1, nil
+		}
+		key := strings.ToLower(currency)
+		if rate, ok := rates[key]; ok {
+			return rate, nil
+		}
+		rate, err := fx.Rate(ctx, currency, base)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch %s/%s rate: %w", currency, base, err)
+		}
+		if rate <= 0 {
+			return 0, fmt.Errorf("invalid %s/%s rate %v", currency, base, rate)
+		}
+		rates[key] = rate
+		return rate, nil
+	}
+
+	feeRate, err := rateTo(feeCurrency)
+	if err != nil {
+		return nil, err
+	}
+	payoutRate, err := rateTo(payoutCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]BaseQuote, 0, len(quotes))
+	for _, quote := range quotes {
+		converted = append(converted, BaseQuote{
+			QuoteResponse:      quote,
+			BaseCurrency:       base,
+			BaseNetworkFee:     quote.NetworkFee * feeRate,
+			BaseTransactionFee: quote.TransactionFee * feeRate,
+			BaseTotalFees:      models.Amount{Decimal: quote.TotalFees().Mul(decimal.NewFromFloat(feeRate))},
+			BasePayout:         models.Amount{Decimal: quote.Payout.Mul(decimal.NewFromFloat(payoutRate))},
+		})
+	}
+	return converted, nil
+}
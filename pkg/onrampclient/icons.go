@@ -0,0 +1,78 @@
+package onrampclient
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// rewriteIconURL replaces the CDN host of an absolute icon URL with IconBaseURL.
+func (h Client) rewriteIconURL(raw string) string {
+	if h.IconBaseURL == "" || raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Scheme = ""
+	u.Host = ""
+	u.User = nil
+	return strings.TrimRight(h.IconBaseURL, "/") + u.String()
+}
+
+// rewriteCurrencyIcons rewrites every icon in a currencies response.
+func (h Client) rewriteCurrencyIcons(currencies *models.SupportedCurrenciesResponse) {
+	if h.IconBaseURL == "" {
+		return
+	}
+	for i := range currencies.Message.Crypto {
+		currencies.Message.Crypto[i].Icon = h.rewriteIconURL(currencies.Message.Crypto[i].Icon)
+	}
+	for i := range currencies.Message.Fiat {
+		currencies.Message.Fiat[i].Icon = h.rewriteIconURL(currencies.Message.Fiat[i].Icon)
+	}
+}
+
+// rewriteMetadataIcons rewrites every icon in an onramp metadata response.
+func (h Client) rewriteMetadataIcons(metadata *models.OnrampMetadataResponse) {
+	if h.IconBaseURL == "" {
+		return
+	}
+	for i := range metadata.Message {
+		item := &metadata.Message[i]
+		item.Icon = h.rewriteIconURL(item.Icon)
+		item.Icons.SVG = h.rewriteIconURL(item.Icons.SVG)
+		item.Icons.PNG.Size32x32 = h.rewriteIconURL(item.Icons.PNG.Size32x32)
+		item.Icons.PNG.Size160x160 = h.rewriteIconURL(item.Icons.PNG.Size160x160)
+		h.rewriteVariantIcons(item.Icons.Mono)
+		h.rewriteVariantIcons(item.Icons.Colored)
+	}
+}
+
+// rewriteOnrampIcons rewrites every icon in a supported onramps response.
+func (h Client) rewriteOnrampIcons(onramps *models.OnrampResponse) {
+	if h.IconBaseURL == "" {
+		return
+	}
+	for i := range onramps.Message {
+		item := &onramps.Message[i]
+		item.Icon = h.rewriteIconURL(item.Icon)
+		item.Icons.SVG = h.rewriteIconURL(item.Icons.SVG)
+		item.Icons.PNG.Size32x32 = h.rewriteIconURL(item.Icons.PNG.Size32x32)
+		item.Icons.PNG.Size160x160 = h.rewriteIconURL(item.Icons.PNG.Size160x160)
+		h.rewriteVariantIcons(item.Icons.Mono)
+		h.rewriteVariantIcons(item.Icons.Colored)
+	}
+}
+
+// rewriteVariantIcons rewrites the icons of an optional themed variant.
+func (h Client) rewriteVariantIcons(variant *models.IconVariant) {
+	if variant == nil {
+		return
+	}
+	variant.SVG = h.rewriteIconURL(variant.SVG)
+	variant.PNG.Size32x32 = h.rewriteIconURL(variant.PNG.Size32x32)
+	variant.PNG.Size160x160 = h.rewriteIconURL(variant.PNG.Size160x160)
+}
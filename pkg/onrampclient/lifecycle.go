@@ -0,0 +1,99 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// clientLifecycle holds the base context every request is bound to; Close cancels it.
+type clientLifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newClientLifecycle() *clientLifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &clientLifecycle{ctx: ctx, cancel: cancel}
+}
+
+func (l *clientLifecycle) closed() bool {
+	return l != nil && l.ctx.Err() != nil
+}
+
+// bind derives req's context from the base context as well, so Close cancels it.
+// release must be called once the response body is done with.
+func (l *clientLifecycle) bind(req *http.Request) (bound *http.Request, release func()) {
+	if l == nil {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(l.ctx, cancel)
+	return req.WithContext(ctx), func() {
+		stop()
+		cancel()
+	}
+}
+
+// Close cancels in-flight requests and closes idle connections. Requests started
+// afterwards fail with ErrClientClosed.
+func (h Client) Close() error {
+	if h.lifecycle != nil {
+		h.lifecycle.cancel()
+	}
+	if h.HTTPClient != nil {
+		h.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// doOnce sends req bound to the client's lifetime; see send for retries and failover.
+// Requests to an endpoint whose circuit breaker is open fail with ErrCircuitOpen.
+// A final 503 is returned as an *UnavailableError rather than a response.
+func (h Client) doOnce(req *http.Request) (*http.Response, error) {
+	if h.lifecycle.closed() {
+		return nil, ErrClientClosed
+	}
+	endpoint := h.endpointOf(req)
+	if h.BreakerThreshold > 0 && !h.breakers.allow(endpoint, time.Now(), h.breakerCooldown()) {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, endpoint)
+	}
+	req, release := h.lifecycle.bind(req)
+	resp, err := h.send(req)
+	if h.BreakerThreshold > 0 && req.Context().Err() == nil {
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		h.breakers.record(endpoint, failed, time.Now(), h.BreakerThreshold)
+	}
+	if err != nil {
+		release()
+		if h.lifecycle.closed() {
+			return nil, fmt.Errorf("%w: %w", ErrClientClosed, err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		defer release()
+		defer resp.Body.Close()
+		body, readErr := h.readBody(resp)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return nil, &UnavailableError{RetryAfter: resp.Header.Get("Retry-After"), Message: string(body)}
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the request context when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
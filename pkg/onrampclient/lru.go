@@ -0,0 +1,56 @@
+package onrampclient
+
+import "container/list"
+
+// catalogCacheSize bounds the entries of the ETag and catalogue caches. Their keys carry
+// caller-chosen countries and locales, so without a bound every new combination would
+// stay in memory for the life of the client.
+const catalogCacheSize = 256
+
+// lru maps keys to values, keeping at most size of them: storing a new key when full
+// drops the least recently used one. It is not safe for concurrent use.
+type lru[V any] struct {
+	size  int
+	order *list.List // of lruItem[V], most recently used first
+	items map[string]*list.Element
+}
+
+type lruItem[V any] struct {
+	key   string
+	value V
+}
+
+func newLRU[V any](size int) *lru[V] {
+	return &lru[V]{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the value stored for key and marks it as the most recently used.
+func (l *lru[V]) get(key string) (value V, ok bool) {
+	element, ok := l.items[key]
+	if !ok {
+		return value, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(lruItem[V]).value, true //nolint:forcetypeassert // only lruItem[V] is stored.
+}
+
+// put stores value for key, dropping the least recently used entry if key is new and
+// the cache is full.
+func (l *lru[V]) put(key string, value V) {
+	if element, ok := l.items[key]; ok {
+		element.Value = lruItem[V]{key: key, value: value}
+		l.order.MoveToFront(element)
+		return
+	}
+	if l.order.Len() >= l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(lruItem[V]).key) //nolint:forcetypeassert // only lruItem[V] is stored.
+	}
+	l.items[key] = l.order.PushFront(lruItem[V]{key: key, value: value})
+}
+
+// len returns the number of entries.
+func (l *lru[V]) len() int {
+	return l.order.Len()
+}
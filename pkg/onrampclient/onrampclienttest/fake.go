@@ -0,0 +1,156 @@
+// Package onrampclienttest provides an in-memory OnRamperClient for tests.
+package onrampclienttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+)
+
+var _ rmp.OnRamperClient = (*FakeOnRamperClient)(nil)
+
+// FakeOnRamperClient is an OnRamperClient that returns canned responses. Each method
+// returns its response field and error field as set, and counts its calls. Set the
+// fields before handing the fake to the code under test.
+type FakeOnRamperClient struct {
+	Currencies            models.SupportedCurrenciesResponse
+	CurrenciesErr         error
+	PaymentTypes          models.PaymentTypesResponse
+	PaymentTypesErr       error
+	AllPaymentTypes       models.AllPaymentTypesResponse
+	AllPaymentTypesErr    error
+	PaymentsByCurrency    models.PaymentResponse
+	PaymentsByCurrencyErr error
+	Defaults              models.DefaultsResponse
+	DefaultsErr           error
+	SupportedCountries    []string
+	SupportedCountriesErr error
+	Assets                models.SupportedAssetsResponse
+	AssetsErr             error
+	Onramps               models.OnrampResponse
+	OnrampsErr            error
+	OnrampMetadata        models.OnrampMetadataResponse
+	OnrampMetadataErr     error
+	CryptoByFiat          models.CryptoFiatResponse
+	CryptoByFiatErr       error
+	Quotes                []models.QuoteResponse
+	QuotesErr             error
+	Quote                 models.QuoteResponse
+	QuoteErr              error
+	Transaction           models.TransactionResponse
+	TransactionErr        error
+	TransactionList       models.TransactionListResponse
+	TransactionListErr    error
+	Initiated             models.InitiateTransactionResponse
+	InitiatedErr          error
+	Confirmation          models.SellTransactionConfirmationResponse
+	ConfirmationErr       error
+	SellPayout            models.SellPayoutStatusResponse
+	SellPayoutErr         error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Calls returns how often the named method, e.g. "GetQuotes", was called.
+func (f *FakeOnRamperClient) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *FakeOnRamperClient) record(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+}
+
+func (f *FakeOnRamperClient) GetCurrencies(context.Context, string, string, string) (models.SupportedCurrenciesResponse, error) {
+	f.record("GetCurrencies")
+	return f.Currencies, f.CurrenciesErr
+}
+
+func (f *FakeOnRamperClient) GetPaymentTypes(context.Context, string, bool, string) (models.PaymentTypesResponse, error) {
+	f.record("GetPaymentTypes")
+	return f.PaymentTypes, f.PaymentTypesErr
+}
+
+func (f *FakeOnRamperClient) GetAllPaymentTypes(context.Context, string) (models.AllPaymentTypesResponse, error) {
+	f.record("GetAllPaymentTypes")
+	return f.AllPaymentTypes, f.AllPaymentTypesErr
+}
+
+func (f *FakeOnRamperClient) GetPaymentsByCurrency(context.Context, string, string, bool, string, string, string) (models.PaymentResponse, error) {
+	f.record("GetPaymentsByCurrency")
+	return f.PaymentsByCurrency, f.PaymentsByCurrencyErr
+}
+
+func (f *FakeOnRamperClient) GetDefaults(context.Context, string, string, string) (models.DefaultsResponse, error) {
+	f.record("GetDefaults")
+	return f.Defaults, f.DefaultsErr
+}
+
+func (f *FakeOnRamperClient) GetSupportedCountries(context.Context, string) ([]string, error) {
+	f.record("GetSupportedCountries")
+	return f.SupportedCountries, f.SupportedCountriesErr
+}
+
+func (f *FakeOnRamperClient) GetAssets(context.Context, *models.AssetRequest) (models.SupportedAssetsResponse, error) {
+	f.record("GetAssets")
+	return f.Assets, f.AssetsErr
+}
+
+func (f *FakeOnRamperClient) GetOnramps(context.Context, *models.OnrampsQuery) (models.OnrampResponse, error) {
+	f.record("GetOnramps")
+	return f.Onramps, f.OnrampsErr
+}
+
+func (f *FakeOnRamperClient) GetOnrampMetadata(context.Context, string) (models.OnrampMetadataResponse, error) {
+	f.record("GetOnrampMetadata")
+	return f.OnrampMetadata, f.OnrampMetadataErr
+}
+
+func (f *FakeOnRamperClient) GetCryptoByFiat(context.Context, string, string) (models.CryptoFiatResponse, error) {
+	f.record("GetCryptoByFiat")
+	return f.CryptoByFiat, f.CryptoByFiatErr
+}
+
+func (f *FakeOnRamperClient) GetQuotes(context.Context, string, string, *models.QuoteQueryParams) ([]models.QuoteResponse, error) {
+	f.record("GetQuotes")
+	return f.Quotes, f.QuotesErr
+}
+
+func (f *FakeOnRamperClient) GetQuoteByID(context.Context, string) (models.QuoteResponse, error) {
+	f.record("GetQuoteByID")
+	return f.Quote, f.QuoteErr
+}
+
+func (f *FakeOnRamperClient) GetTransactionByID(context.Context, string) (models.TransactionResponse, error) {
+	f.record("GetTransactionByID")
+	return f.Transaction, f.TransactionErr
+}
+
+func (f *FakeOnRamperClient) ListTransactions(context.Context, models.TransactionListQuery) (models.TransactionListResponse, error) {
+	f.record("ListTransactions")
+	return f.TransactionList, f.TransactionListErr
+}
+
+func (f *FakeOnRamperClient) InitiateTransaction(context.Context, models.InitiateTransactionRequest) (models.InitiateTransactionResponse, error) {
+	f.record("InitiateTransaction")
+	return f.Initiated, f.InitiatedErr
+}
+
+func (f *FakeOnRamperClient) ConfirmSellTransaction(context.Context, string, string) (models.SellTransactionConfirmationResponse, error) {
+	f.record("ConfirmSellTransaction")
+	return f.Confirmation, f.ConfirmationErr
+}
+
+func (f *FakeOnRamperClient) GetSellPayoutStatus(context.Context, string) (models.SellPayoutStatusResponse, error) {
+	f.record("GetSellPayoutStatus")
+	return f.SellPayout, f.SellPayoutErr
+}
@@ -0,0 +1,76 @@
+package onrampclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Endpoint identifies an Onramper API endpoint whose path can be overridden.
+type Endpoint string
+
+const (
+	EndpointCurrencies     Endpoint = "currencies"
+	EndpointPaymentTypes   Endpoint = "paymentTypes"
+	EndpointDefaults       Endpoint = "defaults"
+	EndpointAssets         Endpoint = "assets"
+	EndpointOnramps        Endpoint = "onramps"
+	EndpointOnrampMetadata Endpoint = "onrampMetadata"
+	EndpointCrypto         Endpoint = "crypto"
+	EndpointQuotes         Endpoint = "quotes"
+	EndpointTransactions   Endpoint = "transactions"
+	EndpointCheckoutIntent Endpoint = "checkoutIntent"
+	EndpointConfirmSell    Endpoint = "confirmSell"
+)
+
+// DefaultPaths returns the current Onramper path for every endpoint.
+func DefaultPaths() map[Endpoint]string {
+	return map[Endpoint]string{
+		EndpointCurrencies:     "/supported",
+		EndpointPaymentTypes:   "/supported/payment-types",
+		EndpointDefaults:       "/supported/defaults/all",
+		EndpointAssets:         "/supported/assets",
+		EndpointOnramps:        "/supported/onramps",
+		EndpointOnrampMetadata: "/supported/onramps/all",
+		EndpointCrypto:         "/supported/crypto",
+		EndpointQuotes:         "/quotes",
+		EndpointTransactions:   "/transactions",
+		EndpointCheckoutIntent: "/checkout/intent",
+		EndpointConfirmSell:    "/transactions/confirm",
+	}
+}
+
+// path returns the configured path for endpoint, falling back to the default.
+func (h Client) path(endpoint Endpoint) string {
+	if p, ok := h.Paths[endpoint]; ok && p != "" {
+		return p
+	}
+	return DefaultPaths()[endpoint]
+}
+
+// endpointURL joins the primary base URL with the path for endpoint.
+func (h Client) endpointURL(endpoint Endpoint) string {
+	return h.primaryBaseURL() + h.path(endpoint)
+}
+
+// ParseEndpointPaths parses overrides in the form "quotes=/v2/quotes,assets=/v2/assets".
+func ParseEndpointPaths(input string) (paths map[Endpoint]string, err error) {
+	paths = make(map[Endpoint]string)
+	if strings.TrimSpace(input) == "" {
+		return paths, err
+	}
+	defaults := DefaultPaths()
+	for _, pair := range strings.Split(input, ",") {
+		name, p, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || p == "" {
+			err = fmt.Errorf("invalid endpoint path override %q", pair)
+			return nil, err
+		}
+		endpoint := Endpoint(strings.TrimSpace(name))
+		if _, known := defaults[endpoint]; !known {
+			err = fmt.Errorf("unknown endpoint %q", name)
+			return nil, err
+		}
+		paths[endpoint] = "/" + strings.Trim(strings.TrimSpace(p), "/")
+	}
+	return paths, err
+}
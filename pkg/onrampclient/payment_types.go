@@ -0,0 +1,52 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// GetAllPaymentTypes fetches buy and sell payment types concurrently and merges them.
+// Each payment type appears once, with SupportedFor listing the transaction types it serves.
+func (h Client) GetAllPaymentTypes(ctx context.Context, country string) (paymentTypes models.AllPaymentTypesResponse, err error) {
+	transactionTypes := []string{transactionTypeBuy, transactionTypeSell}
+	results := make([]models.PaymentTypesResponse, len(transactionTypes))
+	errs := make([]error, len(transactionTypes))
+
+	var wg sync.WaitGroup
+	for i, transactionType := range transactionTypes {
+		wg.Add(1)
+		go func(i int, transactionType string) {
+			defer wg.Done()
+			results[i], errs[i] = h.GetPaymentTypes(ctx, transactionType, false, country)
+		}(i, transactionType)
+	}
+	wg.Wait()
+
+	for i, transactionType := range transactionTypes {
+		if errs[i] != nil {
+			err = fmt.Errorf("failed to fetch %s payment types: %w", transactionType, errs[i])
+			return paymentTypes, err
+		}
+	}
+	paymentTypes.Message = mergePaymentTypes(transactionTypes, results)
+	return paymentTypes, err
+}
+
+// mergePaymentTypes de-duplicates payment types across transaction types, in the order given.
+func mergePaymentTypes(transactionTypes []string, results []models.PaymentTypesResponse) map[string]models.CombinedPaymentType {
+	merged := make(map[string]models.CombinedPaymentType)
+	for i, transactionType := range transactionTypes {
+		for id, paymentType := range results[i].Message {
+			combined, ok := merged[id]
+			if !ok {
+				combined = models.CombinedPaymentType{PaymentType: paymentType}
+			}
+			combined.SupportedFor = append(combined.SupportedFor, transactionType)
+			merged[id] = combined
+		}
+	}
+	return merged
+}
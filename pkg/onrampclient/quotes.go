@@ -0,0 +1,223 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Decimal places used when sending quote amounts to Onramper: crypto amounts are sent
+// with up to eight, fiat amounts with their currency's minor unit.
+const (
+	defaultFiatDecimals  = 2
+	cryptoAmountDecimals = 8
+)
+
+// fiatMinorUnits lists the ISO 4217 decimals of fiat currencies whose minor unit is not
+// a hundredth. Onramper's currency list does not give fiat decimals.
+//
+//nolint:gochecknoglobals // Read-only lookup table.
+var fiatMinorUnits = map[string]int{
+	"bif": 0, "clp": 0, "djf": 0, "gnf": 0, "isk": 0, "jpy": 0, "kmf": 0, "krw": 0,
+	"pyg": 0, "rwf": 0, "ugx": 0, "vnd": 0, "vuv": 0, "xaf": 0, "xof": 0, "xpf": 0,
+	"bhd": 3, "iqd": 3, "jod": 3, "kwd": 3, "lyd": 3, "omr": 3, "tnd": 3,
+}
+
+// fiatDecimals returns the ISO 4217 decimals of fiat, two unless listed in fiatMinorUnits.
+func fiatDecimals(fiat string) int {
+	if decimals, ok := fiatMinorUnits[strings.ToLower(strings.TrimSpace(fiat))]; ok {
+		return decimals
+	}
+	return defaultFiatDecimals
+}
+
+// DefaultQuoteTTL is how long a quote without an expiresAt can be retrieved by ID.
+const DefaultQuoteTTL = 2 * time.Minute
+
+// Values of QuoteQueryParams.Input naming which currency the amount is in.
+const (
+	quoteInputSource      = "source"
+	quoteInputDestination = "destination"
+)
+
+// amountIsFiat reports whether the quote amount is denominated in the fiat currency.
+// The amount is in the source currency (fiat when buying, crypto when selling) unless
+// input is "destination".
+func amountIsFiat(quotesParam *models.QuoteQueryParams) bool {
+	isBuy := quotesParam.Type == transactionTypeBuy
+	inDestination := strings.EqualFold(strings.TrimSpace(quotesParam.Input), quoteInputDestination)
+	return isBuy != inDestination
+}
+
+// majorUnitAmount converts quotesParam's minor-unit amount, e.g. cents or satoshis, to
+// the major-unit amount Onramper expects. A fiat's decimals are its ISO 4217 minor unit;
+// a crypto's come from the cached currency list.
+func (h Client) majorUnitAmount(ctx context.Context, fiat, crypto string, quotesParam *models.QuoteQueryParams) (float64, error) {
+	decimals := fiatDecimals(fiat)
+	if !amountIsFiat(quotesParam) {
+		types, err := h.cachedAssetTypes(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load %s decimals: %w", crypto, err)
+		}
+		var ok bool
+		decimals, ok = types.decimals[strings.ToLower(crypto)]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownDecimals, crypto)
+		}
+	}
+	return decimal.NewFromFloat(quotesParam.Amount).Shift(-int32(decimals)).InexactFloat64(), nil
+}
+
+// formatQuoteAmount renders quotesParam's amount in plain decimal notation, rounded to
+// fiat's decimals or eight places for crypto, without trailing zeros.
+func formatQuoteAmount(fiat string, quotesParam *models.QuoteQueryParams) string {
+	places := cryptoAmountDecimals
+	if amountIsFiat(quotesParam) {
+		places = fiatDecimals(fiat)
+	}
+	return decimal.NewFromFloat(quotesParam.Amount).Round(int32(places)).String()
+}
+
+// normalizeQuoteParams returns a copy of quotesParam with Type lowercased and defaulted
+// to buy, so an empty or mistyped type never falls through to a sell quote, and Input
+// lowercased and checked. An empty Input is sent as is and quotes the source amount.
+func normalizeQuoteParams(quotesParam *models.QuoteQueryParams) (*models.QuoteQueryParams, error) {
+	params := *quotesParam
+	params.Type = strings.ToLower(strings.TrimSpace(params.Type))
+	switch params.Type {
+	case "":
+		params.Type = transactionTypeBuy
+	case transactionTypeBuy, transactionTypeSell:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTransactionType, quotesParam.Type)
+	}
+	params.Input = strings.ToLower(strings.TrimSpace(params.Input))
+	switch params.Input {
+	case "", quoteInputSource, quoteInputDestination:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidQuoteInput, quotesParam.Input)
+	}
+	return &params, nil
+}
+
+// validatePaymentMethod checks the requested payment method against the methods Onramper
+// supports for the transaction type. If the list cannot be fetched the quote request
+// proceeds unvalidated rather than failing.
+func (h Client) validatePaymentMethod(ctx context.Context, quotesParam *models.QuoteQueryParams) error {
+	transactionType := quotesParam.Type
+	if transactionType == "" {
+		transactionType = transactionTypeBuy
+	}
+	paymentTypes, err := h.GetPaymentTypes(ctx, transactionType, quotesParam.IsRecurringPayment, quotesParam.Country)
+	if err != nil {
+		h.Logger.Warn("Skipping payment method validation",
+			zap.String("payment_method", quotesParam.PaymentMethod),
+			zap.Error(err))
+		return nil
+	}
+	if _, ok := paymentTypes.Message[quotesParam.PaymentMethod]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPaymentMethod, quotesParam.PaymentMethod)
+	}
+	return nil
+}
+
+// hasUsableQuote reports whether any quote came back without provider errors.
+func hasUsableQuote(quotes []models.QuoteResponse) bool {
+	for _, quote := range quotes {
+		if len(quote.Errors) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetQuoteByID returns a quote from a recent GetQuotes call. Onramper has no endpoint to
+// fetch a single quote, so quotes are remembered until they expire.
+func (h Client) GetQuoteByID(ctx context.Context, quoteID string) (quote models.QuoteResponse, err error) {
+	quoteID = strings.TrimSpace(quoteID)
+	if quoteID == "" {
+		err = fmt.Errorf("%w: quote ID is required", ErrQuoteNotFound)
+		return quote, err
+	}
+	return h.quotes.get(quoteID, time.Now())
+}
+
+// quoteTTL returns the configured quote lifetime, or the default.
+func (h Client) quoteTTL() time.Duration {
+	if h.QuoteTTL > 0 {
+		return h.QuoteTTL
+	}
+	return DefaultQuoteTTL
+}
+
+// quoteStore remembers usable quotes by ID until they expire.
+type quoteStore struct {
+	mu     sync.Mutex
+	quotes map[string]storedQuote
+}
+
+type storedQuote struct {
+	quote     models.QuoteResponse
+	expiresAt time.Time
+}
+
+func newQuoteStore() *quoteStore {
+	return &quoteStore{quotes: make(map[string]storedQuote)}
+}
+
+// put stores quotes that have an ID and no provider errors, and drops expired entries.
+func (s *quoteStore) put(quotes []models.QuoteResponse, now time.Time, ttl time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, stored := range s.quotes {
+		if !now.Before(stored.expiresAt) {
+			delete(s.quotes, id)
+		}
+	}
+	for _, quote := range quotes {
+		if quote.QuoteID == "" || len(quote.Errors) > 0 {
+			continue
+		}
+		expiresAt := now.Add(ttl)
+		if quote.ExpiresAt != nil {
+			expiresAt = *quote.ExpiresAt
+		}
+		s.quotes[quote.QuoteID] = storedQuote{quote: quote, expiresAt: expiresAt}
+	}
+}
+
+// size returns the number of stored quotes, including any not yet pruned.
+func (s *quoteStore) size() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.quotes)
+}
+
+func (s *quoteStore) get(quoteID string, now time.Time) (models.QuoteResponse, error) {
+	if s == nil {
+		return models.QuoteResponse{}, fmt.Errorf("%w: %s", ErrQuoteNotFound, quoteID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.quotes[quoteID]
+	if !ok {
+		return models.QuoteResponse{}, fmt.Errorf("%w: %s", ErrQuoteNotFound, quoteID)
+	}
+	if !now.Before(stored.expiresAt) {
+		delete(s.quotes, quoteID)
+		return models.QuoteResponse{}, fmt.Errorf("%w: %s", ErrQuoteExpired, quoteID)
+	}
+	return stored.quote, nil
+}
@@ -0,0 +1,76 @@
+package onrampclient
+
+import (
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// SelectionWeights controls how RecommendQuote ranks quotes. Each weight scales a
+// score between 0 and 1, so a weight of 2 counts twice as much as a weight of 1.
+type SelectionWeights struct {
+	// Price weights the payout relative to the best payout among usable quotes.
+	Price float64
+	// KYC weights quotes that Onramper tags as LowKyc.
+	KYC float64
+	// Preference weights the ramp's position in PreferredRamps.
+	Preference float64
+	// PreferredRamps lists ramps from most to least preferred.
+	PreferredRamps []string
+}
+
+// RecommendQuote returns the quote with the highest score and whether any quote was
+// usable. Quotes with provider errors are skipped and ties go to the earlier quote.
+//
+// A quote scores Price*price + KYC*kyc + Preference*preference, where
+//   - price is its payout divided by the highest payout, or 0 when no payout is positive;
+//   - kyc is 1 if it is tagged LowKyc and 0 otherwise;
+//   - preference is 1 for the first preferred ramp, falling linearly towards 0 for the
+//     last, and 0 for ramps not listed.
+func RecommendQuote(quotes []models.QuoteResponse, weights SelectionWeights) (models.QuoteResponse, bool) {
+	var bestPayout float64
+	for _, quote := range quotes {
+		if len(quote.Errors) == 0 {
+			bestPayout = max(bestPayout, quote.Payout.InexactFloat64())
+		}
+	}
+
+	best := -1
+	var bestScore float64
+	for i, quote := range quotes {
+		if len(quote.Errors) > 0 {
+			continue
+		}
+		score := weights.score(quote, bestPayout)
+		if best < 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return models.QuoteResponse{}, false
+	}
+	return quotes[best], true
+}
+
+// score blends the weighted components of quote as documented on RecommendQuote.
+func (w SelectionWeights) score(quote models.QuoteResponse, bestPayout float64) float64 {
+	var price float64
+	if bestPayout > 0 {
+		price = max(quote.Payout.InexactFloat64(), 0) / bestPayout
+	}
+	var kyc float64
+	if !quote.RequiresFullKYC() {
+		kyc = 1
+	}
+	return w.Price*price + w.KYC*kyc + w.Preference*w.preference(quote.Ramp)
+}
+
+// preference scores ramp by its position in PreferredRamps.
+func (w SelectionWeights) preference(ramp string) float64 {
+	for i, preferred := range w.PreferredRamps {
+		if strings.EqualFold(preferred, ramp) {
+			return 1 - float64(i)/float64(len(w.PreferredRamps))
+		}
+	}
+	return 0
+}
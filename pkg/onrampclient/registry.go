@@ -0,0 +1,81 @@
+package onrampclient
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// ClientRegistry holds one Onramper client per partner for multi-tenant deployments,
+// each with the partner's own API key. Requests without a partner use the default client.
+type ClientRegistry struct {
+	mu            sync.RWMutex
+	defaultClient OnRamperClient
+	partners      map[string]OnRamperClient
+}
+
+// NewClientRegistry returns a registry that falls back to defaultClient.
+func NewClientRegistry(defaultClient OnRamperClient) *ClientRegistry {
+	return &ClientRegistry{
+		defaultClient: defaultClient,
+		partners:      make(map[string]OnRamperClient),
+	}
+}
+
+// Register sets the client used for partnerID.
+func (r *ClientRegistry) Register(partnerID string, client OnRamperClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partners[partnerID] = client
+}
+
+// Client returns the client for partnerID, or the default client when partnerID is empty.
+func (r *ClientRegistry) Client(partnerID string) (OnRamperClient, error) {
+	if partnerID == "" {
+		return r.defaultClient, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	client, ok := r.partners[partnerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPartner, partnerID)
+	}
+	return client, nil
+}
+
+// Clients returns every client in the registry by partner ID, the default client under "".
+func (r *ClientRegistry) Clients() map[string]OnRamperClient {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clients := maps.Clone(r.partners)
+	clients[""] = r.defaultClient
+	return clients
+}
+
+// ParsePartnerAPIKeys parses partner keys in the form "partnerA=key1,partnerB=key2".
+func ParsePartnerAPIKeys(input string) (keys map[string]string, err error) {
+	keys = make(map[string]string)
+	if strings.TrimSpace(input) == "" {
+		return keys, err
+	}
+	for _, pair := range strings.Split(input, ",") {
+		partner, key, found := strings.Cut(strings.TrimSpace(pair), "=")
+		partner, key = strings.TrimSpace(partner), strings.TrimSpace(key)
+		if !found || partner == "" || key == "" {
+			err = fmt.Errorf("invalid partner API key entry for %q", partner)
+			return nil, err
+		}
+		keys[partner] = key
+	}
+	return keys, err
+}
+
+// WithAPIKey returns a copy of the client that authenticates with apiKey. The copy
+// shares the transport, failover state and lifecycle, but keeps its own quotes.
+func (h Client) WithAPIKey(apiKey string) *Client {
+	clone := h
+	clone.APIKey = apiKey
+	clone.quotes = newQuoteStore()
+	return &clone
+}
@@ -0,0 +1,73 @@
+package onrampclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// DefaultMaxResponseBytes caps how much of an upstream response body is read.
+const DefaultMaxResponseBytes int64 = 4 << 20
+
+// maxBodySnippet is how much of a body is echoed back in decode errors.
+const maxBodySnippet = 256
+
+// secretFieldPattern matches JSON string fields that look like credentials.
+//
+//nolint:gochecknoglobals // compiled once, read-only.
+var secretFieldPattern = regexp.MustCompile(
+	`(?i)("[^"]*(?:api[_-]?key|secret|token|password|signature|authorization)[^"]*"\s*:\s*)"[^"]*"`)
+
+// maxResponseBytes returns the configured body cap, or the default.
+func (h Client) maxResponseBytes() int64 {
+	if h.MaxResponseBytes > 0 {
+		return h.MaxResponseBytes
+	}
+	return DefaultMaxResponseBytes
+}
+
+// readBody reads the response body up to the size cap.
+func (h Client) readBody(resp *http.Response) ([]byte, error) {
+	limit := h.maxResponseBytes()
+	// Read one byte past the cap so an oversized body can be told apart from one that fits exactly.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return body, nil
+}
+
+// decodeJSON reads the size-capped response body and decodes it into v.
+// Decode failures name the endpoint and carry a redacted snippet of the body,
+// so upstream schema changes can be diagnosed from the logs.
+func (h Client) decodeJSON(resp *http.Response, endpoint Endpoint, v interface{}) error {
+	_, err := h.decodeRawJSON(resp, endpoint, v)
+	return err
+}
+
+// decodeRawJSON is decodeJSON that also returns the body exactly as Onramper sent it.
+func (h Client) decodeRawJSON(resp *http.Response, endpoint Endpoint, v interface{}) (json.RawMessage, error) {
+	body, err := h.readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s endpoint %s: %w (body: %q)", endpoint, h.path(endpoint), err, bodySnippet(body))
+	}
+	return body, nil
+}
+
+// bodySnippet redacts credential-like fields and truncates the body for error messages.
+func bodySnippet(body []byte) string {
+	redacted := secretFieldPattern.ReplaceAll(body, []byte(`${1}"[REDACTED]"`))
+	if len(redacted) > maxBodySnippet {
+		return string(redacted[:maxBodySnippet]) + "..."
+	}
+	return string(redacted)
+}
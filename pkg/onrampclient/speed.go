@@ -0,0 +1,65 @@
+package onrampclient
+
+import (
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// SpeedRanking lists payment method IDs from fastest to slowest settlement. Onramper
+// reports no settlement times, so FastestQuote infers speed from a quote's payment
+// method through this ranking.
+type SpeedRanking []string
+
+// DefaultSpeedRanking puts wallets and cards, which settle instantly, ahead of instant
+// bank rails and those ahead of regular bank transfers.
+var DefaultSpeedRanking = SpeedRanking{
+	"applepay", "googlepay", "creditcard", "debitcard",
+	"pix", "payid", "sepainstant", "mobilemoney",
+	"sepabanktransfer", "banktransfer",
+}
+
+// ParseSpeedRanking reads a comma-separated ranking such as "applepay,creditcard,pix".
+// Empty entries are ignored.
+func ParseSpeedRanking(raw string) SpeedRanking {
+	var ranking SpeedRanking
+	for _, method := range strings.Split(raw, ",") {
+		method = strings.TrimSpace(method)
+		if method != "" {
+			ranking = append(ranking, method)
+		}
+	}
+	return ranking
+}
+
+// FastestQuote returns the quote whose payment method ranks first in ranking and whether
+// any quote was usable. Quotes with provider errors are skipped, payment methods missing
+// from ranking rank after every listed one, and among equally fast quotes the higher
+// payout wins, then the earlier quote.
+func FastestQuote(quotes []models.QuoteResponse, ranking SpeedRanking) (models.QuoteResponse, bool) {
+	best := -1
+	var bestRank int
+	for i, quote := range quotes {
+		if len(quote.Errors) > 0 {
+			continue
+		}
+		rank := ranking.rank(quote.PaymentMethod)
+		if best < 0 || rank < bestRank || (rank == bestRank && quote.Payout.GreaterThan(quotes[best].Payout)) {
+			best, bestRank = i, rank
+		}
+	}
+	if best < 0 {
+		return models.QuoteResponse{}, false
+	}
+	return quotes[best], true
+}
+
+// rank returns the position of paymentMethod in r, or len(r) when it is not listed.
+func (r SpeedRanking) rank(paymentMethod string) int {
+	for i, method := range r {
+		if strings.EqualFold(method, paymentMethod) {
+			return i
+		}
+	}
+	return len(r)
+}
@@ -0,0 +1,110 @@
+package onrampclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// ViableProvider is a ramp and payment method that can serve part of an amount range.
+type ViableProvider struct {
+	Ramp          string `json:"ramp"`
+	PaymentMethod string `json:"paymentMethod"`
+	// Min and Max bound the part of the requested range the provider serves: its reported
+	// limits clipped to the range, or else the sampled amounts it returned quotes for.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// viableKey identifies a ramp's payment method.
+type viableKey struct {
+	ramp          string
+	paymentMethod string
+}
+
+// ViableProviders discovers which ramps and payment methods can buy crypto with fiat
+// amounts between minAmount and maxAmount in country. It quotes the range's bounds and
+// midpoint concurrently; providers reporting limits for a payment method are viable when
+// those limits overlap the range, even if the sampled amounts fell outside them, and
+// the rest are viable when they returned a usable quote for a sampled amount.
+func (h Client) ViableProviders(ctx context.Context, fiat, crypto string, minAmount, maxAmount float64, country string) (providers []ViableProvider, err error) {
+	if minAmount <= 0 || maxAmount < minAmount {
+		err = fmt.Errorf("invalid amount range %v-%v", minAmount, maxAmount)
+		return providers, err
+	}
+	amounts := []float64{minAmount}
+	if maxAmount > minAmount {
+		amounts = append(amounts, (minAmount+maxAmount)/2, maxAmount)
+	}
+	results := make([][]models.QuoteResponse, len(amounts))
+	errs := make([]error, len(amounts))
+
+	var wg sync.WaitGroup
+	for i, amount := range amounts {
+		wg.Add(1)
+		go func(i int, amount float64) {
+			defer wg.Done()
+			results[i], errs[i] = h.GetQuotes(ctx, fiat, crypto, &models.QuoteQueryParams{
+				Amount:  amount,
+				Type:    transactionTypeBuy,
+				Country: country,
+			})
+		}(i, amount)
+	}
+	wg.Wait()
+
+	limits := make(map[viableKey]models.LimitRange)
+	quoted := make(map[viableKey]models.LimitRange)
+	for i, amount := range amounts {
+		if errs[i] != nil {
+			err = fmt.Errorf("failed to fetch quotes for amount %v: %w", amount, errs[i])
+			return providers, err
+		}
+		for _, quote := range results[i] {
+			for _, method := range quote.AvailablePaymentMethods {
+				methodLimits, ok := method.Limits()
+				if !ok {
+					continue
+				}
+				if limit, ok := methodLimits.ProviderLimits[quote.Ramp]; ok {
+					limits[viableKey{quote.Ramp, method.PaymentTypeID}] = limit
+				}
+			}
+			if len(quote.Errors) > 0 || quote.PaymentMethod == "" {
+				continue
+			}
+			key := viableKey{quote.Ramp, quote.PaymentMethod}
+			band, seen := quoted[key]
+			if !seen {
+				band = models.LimitRange{Min: amount, Max: amount}
+			}
+			quoted[key] = models.LimitRange{Min: math.Min(band.Min, amount), Max: math.Max(band.Max, amount)}
+		}
+	}
+
+	for key, limit := range limits {
+		low, high := math.Max(limit.Min, minAmount), maxAmount
+		if limit.Max > 0 {
+			high = math.Min(limit.Max, maxAmount)
+		}
+		if low <= high {
+			providers = append(providers, ViableProvider{Ramp: key.ramp, PaymentMethod: key.paymentMethod, Min: low, Max: high})
+		}
+	}
+	for key, band := range quoted {
+		if _, ok := limits[key]; !ok {
+			providers = append(providers, ViableProvider{Ramp: key.ramp, PaymentMethod: key.paymentMethod, Min: band.Min, Max: band.Max})
+		}
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].Ramp != providers[j].Ramp {
+			return providers[i].Ramp < providers[j].Ramp
+		}
+		return providers[i].PaymentMethod < providers[j].PaymentMethod
+	})
+	return providers, err
+}
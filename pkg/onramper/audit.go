@@ -0,0 +1,132 @@
+package onramper
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Audited actions.
+const (
+	AuditActionInitiate     = "initiate"
+	AuditActionConfirmSell  = "confirm_sell"
+	AuditActionStatusChange = "status_change"
+)
+
+// Audit actors: who caused the recorded change.
+const (
+	AuditActorUser    = "user"
+	AuditActorWebhook = "webhook"
+)
+
+// AuditEntry records one money-moving operation for compliance.
+type AuditEntry struct {
+	Action        string
+	Actor         string
+	UserID        string
+	TransactionID string
+	Onramp        string
+	Type          string
+	Amount        models.Amount
+	Currency      string
+	Status        string
+	Time          time.Time
+}
+
+// AuditLog receives an entry for every money-moving operation.
+type AuditLog interface {
+	Record(ctx context.Context, entry AuditEntry)
+}
+
+// ZapAuditLog writes audit entries as structured log lines.
+type ZapAuditLog struct {
+	logger *zap.Logger
+}
+
+// NewZapAuditLog returns an AuditLog writing to logger, which should be kept apart from
+// the application log, e.g. by name or sink.
+func NewZapAuditLog(logger *zap.Logger) *ZapAuditLog {
+	return &ZapAuditLog{logger: logger}
+}
+
+// Record writes entry as one log line.
+func (l *ZapAuditLog) Record(_ context.Context, entry AuditEntry) {
+	l.logger.Info("audit",
+		zap.String("action", entry.Action),
+		zap.String("actor", entry.Actor),
+		zap.String("user_id", entry.UserID),
+		zap.String("transaction_id", entry.TransactionID),
+		zap.String("onramp", entry.Onramp),
+		zap.String("type", entry.Type),
+		zap.String("amount", entry.Amount.String()),
+		zap.String("currency", entry.Currency),
+		zap.String("status", entry.Status),
+		zap.Time("time", entry.Time),
+	)
+}
+
+// WithAuditLog sends audit entries to log instead of the manager's "audit" logger.
+func WithAuditLog(log AuditLog) ManagerOption {
+	return func(h *OnramperManager) {
+		h.auditLog = log
+	}
+}
+
+// audit stamps entry with the current time and records it.
+func (h *OnramperManager) audit(ctx context.Context, entry AuditEntry) {
+	entry.Time = h.clock().UTC()
+	log := h.auditLog
+	if log == nil {
+		log = NewZapAuditLog(h.Logger.Named("audit"))
+	}
+	log.Record(ctx, entry)
+}
+
+// confirmSellAuditEntry describes the confirmation of a sell, filled in from the stored
+// transaction with the user who opened it and the amount of crypto being sold. If the
+// transaction cannot be loaded the entry is recorded without them.
+func (h *OnramperManager) confirmSellAuditEntry(ctx context.Context, transactionID, status string) AuditEntry {
+	entry := AuditEntry{
+		Action:        AuditActionConfirmSell,
+		Actor:         AuditActorUser,
+		TransactionID: transactionID,
+		Type:          strings.ToUpper(string(models.SellTransaction)),
+		Status:        status,
+	}
+	if h.dbClient == nil {
+		return entry
+	}
+	stored, err := h.dbClient.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		h.Logger.Error("Failed to load confirmed sell for audit",
+			zap.String("transaction_id", transactionID), zap.Error(err))
+		return entry
+	}
+	entry.UserID = stored.UserID
+	entry.Currency = stored.SourceCurrency
+	if stored.InAmount != nil {
+		entry.Amount = *stored.InAmount
+	}
+	if stored.InitiateParams != nil {
+		entry.Onramp = stored.InitiateParams.Onramp
+	}
+	return entry
+}
+
+// initiatedAuditEntry describes a checkout session stored for userID.
+func initiatedAuditEntry(userID string, onrampTx *models.WebhookPayload) AuditEntry {
+	return AuditEntry{
+		Action:        AuditActionInitiate,
+		Actor:         AuditActorUser,
+		UserID:        userID,
+		TransactionID: onrampTx.TransactionID,
+		Onramp:        onrampTx.Onramp,
+		Type:          onrampTx.TransactionType,
+		Amount:        onrampTx.InAmount,
+		Currency:      onrampTx.SourceCurrency,
+		Status:        onrampTx.Status,
+	}
+}
@@ -0,0 +1,117 @@
+package onramper
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestInitiateTransactionAuditEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+	response.Message.SessionInformation.Onramp = "moonpay"
+	response.Message.SessionInformation.Source = "eur"
+	response.Message.SessionInformation.Destination = "eth"
+	response.Message.SessionInformation.Amount = 100
+	response.Message.SessionInformation.Type = "buy"
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+	mockDB := new(MockQueryClient)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user_456").Return("user_456", nil)
+	core, logs := observer.New(zap.InfoLevel)
+	manager := &OnramperManager{
+		Logger:         zap.New(core),
+		onramperClient: mockClient,
+		dbClient:       mockDB,
+		now:            func() time.Time { return now },
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+		bytes.NewBufferString(`{"onramp":"moonpay","source":"eur","destination":"eth","amount":100,"type":"buy",`+
+			`"network":"ethereum","wallet":{"address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	manager.InitiateTransaction(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries := logs.FilterMessage("audit").AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "audit", entries[0].LoggerName)
+	assert.Equal(t, map[string]interface{}{
+		"action":         AuditActionInitiate,
+		"actor":          AuditActorUser,
+		"user_id":        "user_456",
+		"transaction_id": "01H9KBT5C21JY0BAX4VTW9EP3V",
+		"onramp":         "moonpay",
+		"type":           "BUY",
+		"amount":         "100",
+		"currency":       "eur",
+		"status":         "pending",
+		"time":           now,
+	}, entries[0].ContextMap())
+}
+
+func TestConfirmSellAuditEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	amount, err := models.NewAmount("0.0015")
+	require.NoError(t, err)
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("ConfirmSellTransaction", mock.Anything, "tx_sell", "0xabc").
+		Return(models.SellTransactionConfirmationResponse{Status: "confirmed"}, nil)
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetTransactionByID", mock.Anything, "tx_sell").Return(models.FiatTransaction{
+		UserID:         "user_456",
+		TransactionID:  "tx_sell",
+		SourceCurrency: "btc",
+		InAmount:       &amount,
+		InitiateParams: &models.InitiateTransactionRequest{Onramp: "moonpay"},
+	}, nil)
+	core, logs := observer.New(zap.InfoLevel)
+	manager := &OnramperManager{
+		Logger:         zap.New(core),
+		onramperClient: mockClient,
+		dbClient:       mockDB,
+		now:            func() time.Time { return now },
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "transaction_id", Value: "tx_sell"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/transactions/confirm/tx_sell?txHash=0xabc", nil)
+
+	manager.ConfirmSellTransaction(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries := logs.FilterMessage("audit").AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, map[string]interface{}{
+		"action":         AuditActionConfirmSell,
+		"actor":          AuditActorUser,
+		"user_id":        "user_456",
+		"transaction_id": "tx_sell",
+		"onramp":         "moonpay",
+		"type":           "SELL",
+		"amount":         "0.0015",
+		"currency":       "btc",
+		"status":         "confirmed",
+		"time":           now,
+	}, entries[0].ContextMap())
+}
@@ -0,0 +1,144 @@
+package onramper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+// GetCorridor answers in one response whether source fiat and destination crypto can be
+// bought and sold in a country, with the onramps and payment methods (and their limits)
+// for each direction. The four Onramper calls behind it run concurrently. A section whose
+// call fails is left empty and reported in warnings; only when every call fails does the
+// whole response fail.
+func (h *OnramperManager) GetCorridor(c *gin.Context) {
+	var query models.CorridorQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		h.Logger.Error("Invalid query parameters", zap.Error(err))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "source and destination are required"})
+		return
+	}
+
+	availability := models.CorridorAvailability{
+		Source:      query.Source,
+		Destination: query.Destination,
+		Country:     query.Country,
+	}
+	fetch := &corridorFetch{client: h.clientFor(c)}
+	ctx := c.Request.Context()
+	// Sells run the other way: crypto source, fiat destination.
+	fetch.direction(ctx, &availability.Buy, models.BuyTransaction, query.Source, query.Destination, query.Country)
+	fetch.direction(ctx, &availability.Sell, models.SellTransaction, query.Destination, query.Source, query.Country)
+	failures := fetch.wait()
+
+	if len(failures) == fetch.sections {
+		h.Logger.Error("Failed to fetch corridor availability",
+			zap.String("source", query.Source),
+			zap.String("destination", query.Destination),
+			zap.Error(failures[0].err))
+		h.respondUpstreamError(c, failures[0].err, http.StatusBadGateway, gin.H{"error": "Failed to fetch corridor availability"})
+		return
+	}
+	for _, failure := range failures {
+		h.Logger.Warn("Failed to fetch corridor section",
+			zap.String("section", failure.warning.Section),
+			zap.String("source", query.Source),
+			zap.String("destination", query.Destination),
+			zap.Error(failure.err))
+		availability.Warnings = append(availability.Warnings, failure.warning)
+	}
+	h.respond(c, http.StatusOK, availability)
+}
+
+// corridorFetch runs the section fetches of a corridor and collects their failures.
+type corridorFetch struct {
+	client   rmp.OnRamperClient
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	sections int
+	failures []corridorFailure
+}
+
+// corridorFailure is a failed section: the warning returned to the caller and the error
+// that is only logged.
+type corridorFailure struct {
+	warning models.CorridorWarning
+	err     error
+}
+
+// wait waits for every section and returns the failures ordered by section.
+func (f *corridorFetch) wait() []corridorFailure {
+	f.wg.Wait()
+	sort.Slice(f.failures, func(i, j int) bool {
+		return f.failures[i].warning.Section < f.failures[j].warning.Section
+	})
+	return f.failures
+}
+
+// section fetches one corridor section in the background, recording a warning if it fails.
+func (f *corridorFetch) section(name, description string, fetch func() error) {
+	f.sections++
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		err := fetch()
+		if err == nil {
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.failures = append(f.failures, corridorFailure{
+			warning: models.CorridorWarning{Section: name, Message: "Failed to fetch " + description},
+			err:     err,
+		})
+	}()
+}
+
+// direction starts fetching the onramps and payment methods of one direction into
+// direction. A direction is available when at least one onramp serves it.
+func (f *corridorFetch) direction(
+	ctx context.Context,
+	direction *models.CorridorDirection,
+	transactionType models.TransactionType,
+	source, destination, country string,
+) {
+	direction.Onramps = []models.Onramp{}
+	direction.PaymentMethods = []models.PaymentMethod{}
+	f.section(string(transactionType)+".onramps", string(transactionType)+" onramps", func() error {
+		response, err := f.client.GetOnramps(ctx, &models.OnrampsQuery{
+			TransactionType: string(transactionType),
+			Source:          source,
+			Destination:     destination,
+			Country:         country,
+		})
+		if err != nil {
+			return err
+		}
+		if response.Message != nil {
+			direction.Onramps = response.Message
+		}
+		direction.Available = len(response.Message) > 0
+		return nil
+	})
+	f.section(string(transactionType)+".paymentMethods", string(transactionType)+" payment methods", func() error {
+		response, err := f.client.GetPaymentsByCurrency(ctx, source, string(transactionType), false, destination, country, "")
+		if err != nil {
+			return err
+		}
+		if response.Error != "" {
+			return fmt.Errorf("onramper returned an error for %s payment methods: %s", transactionType, response.Error)
+		}
+		if response.Message != nil {
+			direction.PaymentMethods = response.Message
+		}
+		return nil
+	})
+}
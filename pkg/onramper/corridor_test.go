@@ -0,0 +1,124 @@
+package onramper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestGetCorridor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	onrampsFor := func(transactionType, source, destination string) interface{} {
+		return mock.MatchedBy(func(q *models.OnrampsQuery) bool {
+			return q.TransactionType == transactionType && q.Source == source && q.Destination == destination && q.Country == "US"
+		})
+	}
+	card := models.PaymentMethod{
+		PaymentTypeID: "creditcard",
+		Name:          "Credit Card",
+		Details:       models.PaymentDetails{Limits: map[string]models.PaymentLimit{"moonpay": {Min: 20, Max: 5000}}},
+	}
+
+	t.Run("merges buy and sell availability", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetOnramps", mock.Anything, onrampsFor("buy", "USD", "BTC")).
+			Return(models.OnrampResponse{Message: []models.Onramp{{Onramp: "moonpay"}}}, nil)
+		mockClient.On("GetOnramps", mock.Anything, onrampsFor("sell", "BTC", "USD")).
+			Return(models.OnrampResponse{}, nil)
+		mockClient.On("GetPaymentsByCurrency", mock.Anything, "USD", "buy", false, "BTC", "US", "").
+			Return(models.PaymentResponse{Message: []models.PaymentMethod{card}}, nil)
+		mockClient.On("GetPaymentsByCurrency", mock.Anything, "BTC", "sell", false, "USD", "US", "").
+			Return(models.PaymentResponse{}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/supported/corridor?source=USD&destination=BTC&country=US", nil)
+
+		manager.GetCorridor(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var got models.CorridorAvailability
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "USD", got.Source)
+		assert.Equal(t, "BTC", got.Destination)
+		assert.True(t, got.Buy.Available)
+		require.Len(t, got.Buy.Onramps, 1)
+		assert.Equal(t, "moonpay", got.Buy.Onramps[0].Onramp)
+		require.Len(t, got.Buy.PaymentMethods, 1)
+		assert.Equal(t, models.PaymentLimit{Min: 20, Max: 5000}, got.Buy.PaymentMethods[0].Details.Limits["moonpay"])
+		assert.False(t, got.Sell.Available)
+		assert.Empty(t, got.Sell.Onramps)
+		assert.Contains(t, w.Body.String(), `"sell":{"available":false,"onramps":[],"paymentMethods":[]}`)
+		assert.NotContains(t, w.Body.String(), "warnings")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("failed payment methods are reported as warnings", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetOnramps", mock.Anything, onrampsFor("buy", "USD", "BTC")).
+			Return(models.OnrampResponse{Message: []models.Onramp{{Onramp: "moonpay"}}}, nil)
+		mockClient.On("GetOnramps", mock.Anything, onrampsFor("sell", "BTC", "USD")).
+			Return(models.OnrampResponse{Message: []models.Onramp{{Onramp: "transak"}}}, nil)
+		mockClient.On("GetPaymentsByCurrency", mock.Anything, "USD", "buy", false, "BTC", "US", "").
+			Return(models.PaymentResponse{}, errors.New("upstream down"))
+		mockClient.On("GetPaymentsByCurrency", mock.Anything, "BTC", "sell", false, "USD", "US", "").
+			Return(models.PaymentResponse{Error: "unsupported"}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/supported/corridor?source=USD&destination=BTC&country=US", nil)
+
+		manager.GetCorridor(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var got models.CorridorAvailability
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.True(t, got.Buy.Available)
+		require.Len(t, got.Buy.Onramps, 1)
+		assert.Equal(t, "moonpay", got.Buy.Onramps[0].Onramp)
+		assert.True(t, got.Sell.Available)
+		assert.Empty(t, got.Buy.PaymentMethods)
+		assert.Equal(t, []models.CorridorWarning{
+			{Section: "buy.paymentMethods", Message: "Failed to fetch buy payment methods"},
+			{Section: "sell.paymentMethods", Message: "Failed to fetch sell payment methods"},
+		}, got.Warnings)
+		assert.NotContains(t, w.Body.String(), "upstream down", "upstream errors are only logged")
+	})
+
+	t.Run("every section failing fails the corridor", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetOnramps", mock.Anything, mock.Anything).Return(models.OnrampResponse{}, errors.New("upstream down"))
+		mockClient.On("GetPaymentsByCurrency", mock.Anything, mock.Anything, mock.Anything, false, mock.Anything, "US", "").
+			Return(models.PaymentResponse{}, errors.New("upstream down"))
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/supported/corridor?source=USD&destination=BTC&country=US", nil)
+
+		manager.GetCorridor(c)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("source and destination are required", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/supported/corridor?source=USD", nil)
+
+		manager.GetCorridor(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockClient.AssertNotCalled(t, "GetOnramps", mock.Anything, mock.Anything)
+	})
+}
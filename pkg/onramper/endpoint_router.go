@@ -1,6 +1,7 @@
 package onramper
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -12,16 +13,34 @@ import (
 )
 
 // SetupRouter initializes API routes for the Fiat Ramp Service.
-func SetupRouter(client *rmp.Client, dbClient *database.GraphQLClient, webhookSecret string) (*gin.Engine, error) {
+func SetupRouter(client *rmp.Client, dbClient *database.GraphQLClient, webhookSecret string, opts ...ManagerOption) (*gin.Engine, error) {
 	router := gin.New()
 	logger := zap.L()
 
+	// Create OnramperManager
+	onramperManager := NewOnramperManager(
+		client,        // APIClient (*rmp.Client)
+		dbClient,      // dbClient
+		logger,        // logger
+		webhookSecret, // webhookSecret
+		client,        // onramperClient (rmp.OnRamperClient interface)
+	)
+	for _, opt := range opts {
+		opt(onramperManager)
+	}
+	// Only trusted proxies may report the client IP that is forwarded to Onramper.
+	if err := router.SetTrustedProxies(onramperManager.trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	// Add middleware
-	router.Use(gin.Recovery()) // Default panic recovery
+	router.Use(assignRequestID)
+	router.Use(onramperManager.recoverPanic()) // Logs the stack and request ID, responds 500
 	router.Use(func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		logger.Info("Request",
+			zap.String("request_id", requestIDFromContext(c.Request.Context())),
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.Int("status", c.Writer.Status()),
@@ -36,7 +55,8 @@ func SetupRouter(client *rmp.Client, dbClient *database.GraphQLClient, webhookSe
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+PartnerHeader+", "+FeatureFlagsHeader+", "+RequestIDHeader)
+		c.Writer.Header().Set("Access-Control-Expose-Headers", FilteredQuotesHeader+", "+RequestIDHeader)
 
 		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -46,36 +66,56 @@ func SetupRouter(client *rmp.Client, dbClient *database.GraphQLClient, webhookSe
 		c.Next()
 	})
 
-	// Health Check Endpoint
+	// Health Check Endpoint; ?verbose=true adds breaker states for ops dashboards
 	router.GET("/health", func(c *gin.Context) {
 		logger.Info("Health check requested")
-		c.JSON(http.StatusOK, gin.H{"message": "Fiat Ramp Service is running"})
+		body := gin.H{"message": "Fiat Ramp Service is running"}
+		if c.Query("verbose") == "true" {
+			if stats, ok := onramperManager.clientStats(); ok {
+				breakers := make(map[rmp.Endpoint]string, len(stats.Breakers))
+				for endpoint, breaker := range stats.Breakers {
+					breakers[endpoint] = breaker.State
+				}
+				body["breakers"] = breakers
+			}
+		}
+		c.JSON(http.StatusOK, body)
 	})
-
-	// Create OnramperManager
-	onramperManager := NewOnramperManager(
-		client,        // APIClient (*rmp.Client)
-		dbClient,      // dbClient
-		logger,        // logger
-		webhookSecret, // webhookSecret
-		client,        // onramperClient (rmp.OnRamperClient interface)
-	)
+	router.Use(onramperManager.resolvePartner)
+	router.Use(onramperManager.limitPartnerRate)
+	router.Use(parseFeatureFlags)
+	router.Use(onramperManager.forwardLocale)
 
 	// Define API routes
 	router.GET("/supported", onramperManager.GetCurrencies)
 	router.GET("/supported/payment-types", onramperManager.GetPaymentTypes)
+	router.GET("/supported/payment-types/all", onramperManager.GetAllPaymentTypes)
+	router.GET("/supported/countries", onramperManager.GetSupportedCountries)
 	router.GET("supported/payment-types/:source", onramperManager.GetPaymentsByCurrency)
 	router.GET("supported/defaults/:all", onramperManager.GetDefaults)
-	router.POST("checkout/intent", onramperManager.InitiateTransaction)
+	router.POST("checkout/intent", onramperManager.requireDatabase, onramperManager.InitiateTransaction)
 	router.GET("/transactions_list", onramperManager.ListTransactions)
+	router.GET("/transactions/export", onramperManager.ExportTransactions)
 	router.GET("/transactions/:transaction_id", onramperManager.GetTransactionByID)
+	router.GET("/transactions/:transaction_id/payout", onramperManager.GetSellPayoutStatus)
+	router.POST("/transactions/:transaction_id/refresh", onramperManager.requireDatabase, onramperManager.RefreshCheckoutSession)
+	router.GET("/transactions/onramp/:onramp_transaction_id", onramperManager.GetTransactionByOnrampID)
+	router.GET("/users/:user_id/transactions", onramperManager.requireOperator, onramperManager.GetUserTransactions)
+	router.GET("/wallets/:wallet_address/transactions", onramperManager.requireOperator, onramperManager.GetWalletTransactions)
 	router.GET("/quotes/:source/:destination", onramperManager.GetQuotes)
+	router.GET("/quotes/:source/:destination/stream", onramperManager.StreamQuotes)
+	router.GET("/quote/:quote_id", onramperManager.GetQuoteByID)
 	router.GET("/supported/assets", onramperManager.GetAssets)
 	router.GET("/supported/onramps", onramperManager.GetOnramps)
 	router.GET("/supported/onramps/all", onramperManager.GetOnrampMetadata)
 	router.GET("/supported/crypto", onramperManager.GetCryptoByFiat)
-	router.POST("/transactions/confirm", onramperManager.ConfirmSellTransaction)
-	router.POST("/webhook/onramper", onramperManager.WebhookHandler)
+	router.GET("/supported/corridor", onramperManager.GetCorridor)
+	router.POST("/transactions/confirm/:transaction_id", onramperManager.requireDatabase, onramperManager.ConfirmSellTransaction)
+	for _, path := range onramperManager.WebhookPaths() {
+		router.POST(path, onramperManager.requireDatabase, onramperManager.WebhookHandler)
+	}
+	router.POST("/webhook/validate", onramperManager.requireOperator, onramperManager.ValidateWebhook)
+	router.GET("/debug/state", onramperManager.requireOperator, onramperManager.DebugState)
 
 	return router, nil
 }
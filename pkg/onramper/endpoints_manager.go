@@ -2,13 +2,19 @@ package onramper
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/metrics"
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
 	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
 	"github.com/subdialia/fiat-ramp-service/pkg/utils"
@@ -23,11 +29,109 @@ type OnramperManager struct {
 	Logger *zap.Logger
 
 	// Database client (dependency injection)
-	dbClient *database.GraphQLClient
+	dbClient database.QueryClient
 	// Webhook secret.
 	WebhookSecret string
 	// Onramper API Client.
 	onramperClient rmp.OnRamperClient
+	// KYC transition metrics.
+	kycMetrics *metrics.KYCMetrics
+	// Minimum age of a pending transaction before it is reconciled.
+	PendingReconcileAge time.Duration
+	// Clock used for time-based decisions; defaults to time.Now.
+	now func() time.Time
+	// Webhook pipeline; nil means DefaultWebhookProcessors.
+	webhookProcessors []WebhookProcessor
+	// Key casing for JSON responses.
+	ResponseCase JSONCase
+	// Return the inner "message" of Onramper envelopes instead of the whole envelope.
+	UnwrapMessage bool
+	// Bearer token for operator-only endpoints; empty disables them.
+	OperatorToken string
+	// Per-partner Onramper clients; nil serves every request with onramperClient.
+	clients *rmp.ClientRegistry
+	// Transaction status to KYC status mapping; nil means DefaultKYCStatusMapping.
+	KYCStatusMapping KYCStatusMapping
+	// Leave KYC untouched for statuses missing from the mapping instead of failing.
+	SkipUnmappedKYCStatuses bool
+	// Pause between quote refreshes on the quote stream (DefaultQuoteStreamInterval when zero).
+	QuoteStreamInterval time.Duration
+	// How long a quote stream runs before it is closed (DefaultQuoteStreamMaxDuration when zero).
+	QuoteStreamMaxDuration time.Duration
+	// Largest page size forwarded to Onramper's transaction list (DefaultMaxListLimit when zero).
+	MaxListLimit int
+	// Audit trail of money-moving operations; nil logs them to Logger's "audit" logger.
+	auditLog AuditLog
+	// Per-partner inbound rate limits; nil disables them.
+	rateLimits *tokenBuckets
+	// Payment method per country for buys that omit one; nil leaves it to Onramper.
+	DefaultPaymentMethods DefaultPaymentMethods
+	// Decimals per currency that response amounts are rounded to; nil leaves them as sent.
+	AmountPrecision AmountPrecision
+	// Paths receiving Onramper's webhooks; empty means DefaultWebhookPath.
+	webhookPaths []string
+	// Proxies whose X-Forwarded-For is believed; empty trusts none and uses the peer address.
+	trustedProxies []string
+}
+
+// PartnerHeader selects the partner whose Onramper client serves a request.
+const PartnerHeader = "X-Partner-ID"
+
+// FilteredQuotesHeader reports how many error-only quotes ?includeErrors=false removed.
+const FilteredQuotesHeader = "X-Filtered-Quotes"
+
+// partnerClientKey and partnerIDKey are the gin context keys of the client and partner
+// resolved by resolvePartner.
+const (
+	partnerClientKey = "onramperClient"
+	partnerIDKey     = "partnerID"
+)
+
+// ManagerOption customises the manager built by SetupRouter.
+type ManagerOption func(*OnramperManager)
+
+// WithResponseCase makes every manager response use casing for object keys.
+func WithResponseCase(casing JSONCase) ManagerOption {
+	return func(h *OnramperManager) {
+		h.ResponseCase = casing
+	}
+}
+
+// WithUnwrappedMessages makes envelope endpoints return only the inner "message" payload.
+func WithUnwrappedMessages(unwrap bool) ManagerOption {
+	return func(h *OnramperManager) {
+		h.UnwrapMessage = unwrap
+	}
+}
+
+// WithOperatorToken enables operator-only endpoints for requests bearing token.
+func WithOperatorToken(token string) ManagerOption {
+	return func(h *OnramperManager) {
+		h.OperatorToken = token
+	}
+}
+
+// WithTrustedProxies believes X-Forwarded-For only from proxies (IPs or CIDRs), so the
+// end-user IP sent to Onramper cannot be set by the caller. Without any, the address of
+// the connecting peer is used.
+func WithTrustedProxies(proxies ...string) ManagerOption {
+	return func(h *OnramperManager) {
+		h.trustedProxies = proxies
+	}
+}
+
+// WithClientRegistry serves each request with the client registered for its partner header.
+func WithClientRegistry(registry *rmp.ClientRegistry) ManagerOption {
+	return func(h *OnramperManager) {
+		h.clients = registry
+	}
+}
+
+// WithMaxListLimit caps the page size clients may request from ListTransactions.
+func WithMaxListLimit(limit int) ManagerOption {
+	return func(h *OnramperManager) {
+		h.MaxListLimit = limit
+	}
 }
 
 func NewOnramperManager(
@@ -40,13 +144,164 @@ func NewOnramperManager(
 	if logger == nil {
 		panic("logger cannot be nil")
 	}
-	return &OnramperManager{
+	manager := &OnramperManager{
 		APIClient:      apiClient,
-		dbClient:       dbClient,
 		Logger:         logger,
 		WebhookSecret:  webhookSecret,
 		onramperClient: onramperClient,
+		kycMetrics:     metrics.KYC,
+	}
+	// Avoid wrapping a nil pointer in a non-nil interface.
+	if dbClient != nil {
+		manager.dbClient = dbClient
+	}
+	return manager
+}
+
+// clock returns the current time from the injected clock, or time.Now.
+func (h *OnramperManager) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
+
+// resolvePartner picks the Onramper client for the request's partner header,
+// rejecting partners without a registered client.
+func (h *OnramperManager) resolvePartner(c *gin.Context) {
+	if h.clients == nil {
+		c.Next()
+		return
+	}
+	partnerID := c.GetHeader(PartnerHeader)
+	client, err := h.clients.Client(partnerID)
+	if err != nil {
+		h.Logger.Warn("Unknown partner", zap.String("partner_id", partnerID))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Unknown partner"})
+		c.Abort()
+		return
+	}
+	c.Set(partnerClientKey, client)
+	c.Set(partnerIDKey, partnerID)
+	c.Next()
+}
+
+// clientFor returns the client resolved for the request, or the default client.
+func (h *OnramperManager) clientFor(c *gin.Context) rmp.OnRamperClient {
+	if client, ok := c.Get(partnerClientKey); ok {
+		if partnerClient, ok := client.(rmp.OnRamperClient); ok {
+			return partnerClient
+		}
 	}
+	return h.onramperClient
+}
+
+// partnerFor returns the partner resolved for the request, or "" for the default client.
+func partnerFor(c *gin.Context) string {
+	return c.GetString(partnerIDKey)
+}
+
+// partnerClient returns the client registered for partnerID, or the default client when
+// partnerID is empty or no registry is configured.
+func (h *OnramperManager) partnerClient(partnerID string) (rmp.OnRamperClient, error) {
+	if h.clients == nil || partnerID == "" {
+		return h.onramperClient, nil
+	}
+	return h.clients.Client(partnerID)
+}
+
+// forwardLocale asks Onramper to localise names and labels for the request's
+// ?locale= parameter, e.g. "de-DE". Without it the client's default locale applies;
+// a locale that is not a language tag is rejected with 400.
+func (h *OnramperManager) forwardLocale(c *gin.Context) {
+	if locale := strings.TrimSpace(c.Query("locale")); locale != "" {
+		if !rmp.ValidLocale(locale) {
+			h.respond(c, http.StatusBadRequest, gin.H{"error": "invalid locale"})
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(rmp.WithLocale(c.Request.Context(), locale))
+	}
+	c.Next()
+}
+
+// requireDatabase rejects write requests with 501 when the service runs without a database.
+func (h *OnramperManager) requireDatabase(c *gin.Context) {
+	if h.dbClient == nil {
+		h.respond(c, http.StatusNotImplemented, gin.H{"error": "Not available in read-only mode"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// requireOperator rejects requests without the operator bearer token. Operator endpoints
+// are hidden with 404 when no token is configured.
+func (h *OnramperManager) requireOperator(c *gin.Context) {
+	if h.OperatorToken == "" {
+		h.respond(c, http.StatusNotFound, gin.H{"error": "Not found"})
+		c.Abort()
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.OperatorToken)) != 1 {
+		h.Logger.Warn("Rejected operator request", zap.String("path", c.Request.URL.Path))
+		h.respond(c, http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// respondUpstreamError answers a failed Onramper call. An Onramper 503 (e.g. planned
+// maintenance) is passed on as 503 with its Retry-After; anything else gets status and body.
+func (h *OnramperManager) respondUpstreamError(c *gin.Context, err error, status int, body interface{}) {
+	var unavailable *rmp.UnavailableError
+	if errors.As(err, &unavailable) {
+		if unavailable.RetryAfter != "" {
+			c.Header("Retry-After", unavailable.RetryAfter)
+		}
+		h.respond(c, http.StatusServiceUnavailable, gin.H{"error": "Onramper is temporarily unavailable"})
+		return
+	}
+	if errors.Is(err, rmp.ErrCircuitOpen) {
+		h.respond(c, http.StatusServiceUnavailable, gin.H{"error": "Onramper is temporarily unavailable"})
+		return
+	}
+	h.respond(c, status, body)
+}
+
+// statsReporter is implemented by clients that expose cache and breaker state.
+type statsReporter interface {
+	Stats() rmp.ClientStats
+}
+
+// clientStats returns the default client's stats, if it reports any.
+func (h *OnramperManager) clientStats() (stats rmp.ClientStats, ok bool) {
+	if client, isClient := h.onramperClient.(*rmp.Client); isClient && client == nil {
+		return stats, false
+	}
+	reporter, ok := h.onramperClient.(statsReporter)
+	if !ok {
+		return stats, false
+	}
+	return reporter.Stats(), true
+}
+
+// DebugState reports the Onramper client's cache and circuit breaker state for operators.
+func (h *OnramperManager) DebugState(c *gin.Context) {
+	stats, ok := h.clientStats()
+	if !ok {
+		h.respond(c, http.StatusNotImplemented, gin.H{"error": "Client does not report state"})
+		return
+	}
+	h.respond(c, http.StatusOK, stats)
+}
+
+// isJSONRequest reports whether the request declares a JSON body.
+func isJSONRequest(c *gin.Context) bool {
+	contentType := c.ContentType()
+	return contentType == gin.MIMEJSON || strings.HasSuffix(contentType, "+json")
 }
 
 // GetCurrencies fetches supported currencies from Onramper API.
@@ -61,13 +316,17 @@ func (h *OnramperManager) GetCurrencies(c *gin.Context) {
 		zap.String("subdivision", subdivision),
 	)
 
-	response, err := h.onramperClient.GetCurrencies(c.Request.Context(), country, subdivision, transactionType)
+	response, err := h.clientFor(c).GetCurrencies(c.Request.Context(), country, subdivision, transactionType)
+	if errors.Is(err, rmp.ErrInvalidCatalogParams) {
+		h.respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		h.respondUpstreamError(c, err, http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
 	// Return JSON response
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
 }
 func (h *OnramperManager) GetPaymentTypes(c *gin.Context) {
 	transactionType := c.DefaultQuery("type", "buy")
@@ -80,14 +339,41 @@ func (h *OnramperManager) GetPaymentTypes(c *gin.Context) {
 		zap.String("country", country),
 		zap.Bool("isRecurringPayment", isRecurringPayment),
 	)
-	response, err := h.onramperClient.GetPaymentTypes(c.Request.Context(), transactionType, isRecurringPayment, country)
+	response, err := h.clientFor(c).GetPaymentTypes(c.Request.Context(), transactionType, isRecurringPayment, country)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		h.respondUpstreamError(c, err, http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
 	// Return JSON response
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
+}
+
+// GetAllPaymentTypes returns buy and sell payment types merged into one list.
+func (h *OnramperManager) GetAllPaymentTypes(c *gin.Context) {
+	country := c.Query("country")
+
+	response, err := h.clientFor(c).GetAllPaymentTypes(c.Request.Context(), country)
+	if err != nil {
+		h.Logger.Error("Failed to fetch all payment types", zap.String("country", country), zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch payment types"})
+		return
+	}
+	h.respondMessage(c, response, response.Message)
 }
+
+// GetSupportedCountries lists the ISO codes of countries Onramper supports.
+func (h *OnramperManager) GetSupportedCountries(c *gin.Context) {
+	transactionType := c.DefaultQuery("type", "buy")
+
+	countries, err := h.clientFor(c).GetSupportedCountries(c.Request.Context(), transactionType)
+	if err != nil {
+		h.Logger.Error("Failed to fetch supported countries", zap.String("type", transactionType), zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch supported countries"})
+		return
+	}
+	h.respond(c, http.StatusOK, gin.H{"countries": countries})
+}
+
 func (h *OnramperManager) GetPaymentsByCurrency(c *gin.Context) {
 	sourceCurrency := c.Param("source")
 
@@ -108,7 +394,7 @@ func (h *OnramperManager) GetPaymentsByCurrency(c *gin.Context) {
 		zap.String("subdivision", subdivision),
 	)
 
-	response, err := h.onramperClient.GetPaymentsByCurrency(
+	response, err := h.clientFor(c).GetPaymentsByCurrency(
 		c.Request.Context(),
 		sourceCurrency,
 		transactionType,
@@ -120,10 +406,10 @@ func (h *OnramperManager) GetPaymentsByCurrency(c *gin.Context) {
 	if err != nil {
 		if strings.Contains(err.Error(), "access forbidden") {
 			h.Logger.Error("Access forbidden: invalid API key or insufficient permissions", zap.Error(err))
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access forbidden: invalid API key or insufficient permissions"})
+			h.respond(c, http.StatusForbidden, gin.H{"error": "Access forbidden: invalid API key or insufficient permissions"})
 		} else {
 			h.Logger.Error("Failed to fetch payment types", zap.Error(err))
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch payment methods"})
+			h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch payment methods"})
 		}
 		return
 	}
@@ -131,13 +417,13 @@ func (h *OnramperManager) GetPaymentsByCurrency(c *gin.Context) {
 	// Check for errors in the PaymentResponse model
 	if response.Error != "" {
 		h.Logger.Error("Onramper API returned an error", zap.String("error", response.Error))
-		c.JSON(http.StatusBadGateway, gin.H{"error": response.Error})
+		h.respond(c, http.StatusBadGateway, gin.H{"error": response.Error})
 		return
 	}
 
 	// Log the response for debugging
 	h.Logger.Info("Payment types response", zap.Any("response", response))
-	c.JSON(http.StatusOK, response.Message)
+	h.respond(c, http.StatusOK, response.Message)
 }
 func (h *OnramperManager) GetDefaults(c *gin.Context) {
 	transactionType := c.DefaultQuery("type", "buy")
@@ -150,20 +436,42 @@ func (h *OnramperManager) GetDefaults(c *gin.Context) {
 		zap.String("subdivision", subdivision),
 	)
 
-	response, err := h.onramperClient.GetDefaults(c.Request.Context(), transactionType, country, subdivision)
+	response, err := h.clientFor(c).GetDefaults(c.Request.Context(), transactionType, country, subdivision)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		h.respondUpstreamError(c, err, http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// Select a single country's defaults, falling back to the recommended setting.
+	if country != "" && utils.ParseBoolOrDefault(h.Logger, c.Query("fallback"), false) {
+		h.respond(c, http.StatusOK, selectCountryDefaults(response.Message, country))
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
+}
+
+// selectCountryDefaults returns the defaults for country, or the recommended setting when none exist.
+func selectCountryDefaults(message models.DefaultsMessage, country string) models.DefaultSelection {
+	setting, ok := message.Defaults[strings.ToLower(country)]
+	if !ok {
+		return models.DefaultSelection{
+			Country:  country,
+			Setting:  message.Recommended,
+			Fallback: true,
+		}
+	}
+	return models.DefaultSelection{
+		Country: country,
+		Setting: setting,
+	}
 }
 func (h *OnramperManager) GetAssets(c *gin.Context) {
-	h.Logger.Info("Raw query parameters", zap.String("query", c.Request.URL.RawQuery))
+	h.Logger.Info("Raw query parameters", rawQueryField(c.Request.URL.RawQuery))
 	var params models.AssetRequest
 	err := c.ShouldBindQuery(&params)
 	if err != nil {
 		h.Logger.Error("Invalid query parameters", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
 		return
 	}
 
@@ -176,15 +484,15 @@ func (h *OnramperManager) GetAssets(c *gin.Context) {
 		zap.String("paymentMethods", params.PaymentMethods),
 	)
 
-	response, err := h.onramperClient.GetAssets(c.Request.Context(), &params)
+	response, err := h.clientFor(c).GetAssets(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch supported assets"})
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch supported assets"})
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
 }
 func (h *OnramperManager) GetOnramps(c *gin.Context) {
-	h.Logger.Info("Raw query parameters", zap.String("query", c.Request.URL.RawQuery))
+	h.Logger.Info("Raw query parameters", rawQueryField(c.Request.URL.RawQuery))
 
 	var query models.OnrampsQuery
 	err := c.ShouldBindQuery(&query)
@@ -197,29 +505,36 @@ func (h *OnramperManager) GetOnramps(c *gin.Context) {
 				"destination": c.Query("destination"),
 			}),
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "source and destination are required; type must be buy or sell"})
 		return
 	}
-	response, err := h.onramperClient.GetOnramps(c.Request.Context(), &query)
+	if query.TransactionType == "" {
+		query.TransactionType = string(models.BuyTransaction)
+	}
+	response, err := h.clientFor(c).GetOnramps(c.Request.Context(), &query)
 	if err != nil {
 		h.Logger.Error("Failed to fetch supported onramps", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch supported onramps"})
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch supported onramps"})
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
 }
 func (h *OnramperManager) GetOnrampMetadata(c *gin.Context) {
-	h.Logger.Info("Raw query parameters", zap.String("query", c.Request.URL.RawQuery))
+	h.Logger.Info("Raw query parameters", rawQueryField(c.Request.URL.RawQuery))
 	transactionType := c.DefaultQuery("type", "buy")
 	h.Logger.Info("Query parameters", zap.String("type", transactionType))
 
-	response, err := h.onramperClient.GetOnrampMetadata(c.Request.Context(), transactionType)
+	response, err := h.clientFor(c).GetOnrampMetadata(c.Request.Context(), transactionType)
+	if errors.Is(err, rmp.ErrInvalidCatalogParams) {
+		h.respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.Logger.Error("Failed to fetch onramp metadata", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch onramp metadata"})
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch onramp metadata"})
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
 }
 func (h *OnramperManager) GetCryptoByFiat(c *gin.Context) {
 	source := c.Query("source")
@@ -227,7 +542,7 @@ func (h *OnramperManager) GetCryptoByFiat(c *gin.Context) {
 
 	if source == "" {
 		h.Logger.Error("Missing required query parameter: source")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "source is required"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "source is required"})
 		return
 	}
 
@@ -236,99 +551,455 @@ func (h *OnramperManager) GetCryptoByFiat(c *gin.Context) {
 		zap.String("country", country),
 	)
 
-	response, err := h.onramperClient.GetCryptoByFiat(c.Request.Context(), source, country)
+	response, err := h.clientFor(c).GetCryptoByFiat(c.Request.Context(), source, country)
 	if err != nil {
 		h.Logger.Error("Failed to fetch crypto currencies", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch crypto currencies"})
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch crypto currencies"})
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respondMessage(c, response, response.Message)
 }
 func (h *OnramperManager) GetQuotes(c *gin.Context) {
-	fiat := c.Param("source")
-	crypto := c.Param("destination")
+	fiat, crypto, queryParams, ok := h.bindQuoteRequest(c)
+	if !ok {
+		return
+	}
+
+	ctx := rmp.WithClientIP(c.Request.Context(), c.ClientIP())
+	quotes, err := h.clientFor(c).GetQuotes(ctx, fiat, crypto, &queryParams)
+	if err != nil {
+		h.Logger.Error("Failed to fetch quotes", zap.Error(err))
+		h.respondQuoteError(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, h.AmountPrecision.roundQuotes(h.presentQuotes(c, quotes), fiat, crypto, queryParams.Type))
+}
+
+// respondQuoteError answers a request whose quotes could not be fetched.
+func (h *OnramperManager) respondQuoteError(c *gin.Context, err error) {
+	var allFailed *rmp.AllQuotesFailed
+	switch {
+	case errors.Is(err, rmp.ErrUnknownPaymentMethod):
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Unknown payment method"})
+	case errors.Is(err, rmp.ErrAssetTypeMismatch):
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "fiat and crypto appear to be swapped"})
+	case errors.Is(err, rmp.ErrInvalidTransactionType):
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "type must be buy or sell"})
+	case errors.Is(err, rmp.ErrInvalidQuoteInput):
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "input must be source or destination"})
+	case errors.Is(err, rmp.ErrUnknownDecimals):
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Cannot convert minor units for this currency"})
+	case errors.Is(err, rmp.ErrPaymentMethodUnsupported):
+		h.respond(c, http.StatusUnprocessableEntity, gin.H{"error": "Payment method unsupported for corridor"})
+	case errors.As(err, &allFailed):
+		h.respond(c, http.StatusUnprocessableEntity, gin.H{"error": "No quotes available", "reasons": allFailed.Errors})
+	default:
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch quotes"})
+	}
+}
+
+// presentQuotes applies the request's expiry and error filters and, when the request
+// names a preferred ramp, lists the recommended quote first.
+func (h *OnramperManager) presentQuotes(c *gin.Context, quotes []models.QuoteResponse) []models.QuoteResponse {
+	if c.Query("excludeExpired") == "true" {
+		quotes = dropExpiredQuotes(quotes, h.clock())
+	}
+	if c.Query("includeErrors") == "false" {
+		var filtered int
+		quotes, filtered = dropFailedQuotes(quotes)
+		c.Header(FilteredQuotesHeader, strconv.Itoa(filtered))
+	}
+	limit, _ := strconv.Atoi(c.Query("limit")) // validated by bindQuoteRequest
+	if limit > 0 {
+		sortQuotesByPayout(quotes)
+	}
+	// Onramper's order stands unless the request asks for a preferred ramp.
+	if flags := FeatureFlagsFromContext(c.Request.Context()); flags[FlagPreferredRamp] != "" {
+		quotes = promoteQuote(quotes, recommendQuote(quotes, flags))
+	}
+	if limit > 0 && len(quotes) > limit {
+		quotes = quotes[:limit]
+	}
+	return quotes
+}
+
+// sortQuotesByPayout orders usable quotes by payout, highest first, followed by
+// quotes with provider errors. Quotes with equal payouts keep their order.
+func sortQuotesByPayout(quotes []models.QuoteResponse) {
+	sort.SliceStable(quotes, func(i, j int) bool {
+		iUsable, jUsable := len(quotes[i].Errors) == 0, len(quotes[j].Errors) == 0
+		if iUsable != jUsable {
+			return iUsable
+		}
+		return quotes[i].Payout.GreaterThan(quotes[j].Payout)
+	})
+}
+
+// bindQuoteRequest reads the corridor and query parameters of a quote request,
+// responding with 400 and returning false when they are invalid.
+func (h *OnramperManager) bindQuoteRequest(c *gin.Context) (fiat, crypto string, queryParams models.QuoteQueryParams, ok bool) {
+	fiat = c.Param("source")
+	crypto = c.Param("destination")
 
 	if fiat == "" || crypto == "" {
 		h.Logger.Error("Missing fiat or crypto parameter")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "fiat and crypto are required"})
-		return
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "fiat and crypto are required"})
+		return fiat, crypto, queryParams, false
 	}
 
-	var queryParams models.QuoteQueryParams
 	err := c.ShouldBindQuery(&queryParams)
 	if err != nil {
 		h.Logger.Error("Invalid query parameters", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
-		return
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return fiat, crypto, queryParams, false
+	}
+	// A zero amount would be silently dropped from the upstream request.
+	queryParams.Amount = utils.ParseFloatOrDefault(h.Logger, c.Query("amount"), 0)
+	if queryParams.Amount <= 0 {
+		h.Logger.Error("Missing or invalid amount", zap.String("amount", c.Query("amount")))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "amount must be a positive number"})
+		return fiat, crypto, queryParams, false
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			h.Logger.Error("Invalid quote limit", zap.String("limit", limit))
+			h.respond(c, http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return fiat, crypto, queryParams, false
+		}
+	}
+
+	// Sell quotes are requested as /quotes/{crypto}/{fiat}.
+	if strings.EqualFold(queryParams.Type, "sell") {
+		fiat, crypto = crypto, fiat
 	}
 
-	h.Logger.Info("Quote query parameters", zap.Any("params", queryParams))
+	queryParams.PaymentMethod = h.paymentMethodOrDefault(queryParams.Country, queryParams.Type, queryParams.PaymentMethod)
+	h.Logger.Info("Quote query parameters", quoteParamsField(queryParams))
+	return fiat, crypto, queryParams, true
+}
+
+// dropExpiredQuotes returns the quotes that are still valid at now.
+func dropExpiredQuotes(quotes []models.QuoteResponse, now time.Time) []models.QuoteResponse {
+	valid := make([]models.QuoteResponse, 0, len(quotes))
+	for _, quote := range quotes {
+		if !quote.IsExpired(now) {
+			valid = append(valid, quote)
+		}
+	}
+	return valid
+}
 
-	quotes, err := h.onramperClient.GetQuotes(c.Request.Context(), fiat, crypto, &queryParams)
+// dropFailedQuotes returns the quotes without provider errors and how many were dropped.
+func dropFailedQuotes(quotes []models.QuoteResponse) (viable []models.QuoteResponse, dropped int) {
+	viable = make([]models.QuoteResponse, 0, len(quotes))
+	for _, quote := range quotes {
+		if len(quote.Errors) == 0 {
+			viable = append(viable, quote)
+		}
+	}
+	return viable, len(quotes) - len(viable)
+}
+
+// GetQuoteByID returns a quote from a recent quote listing so clients can check it before initiating.
+func (h *OnramperManager) GetQuoteByID(c *gin.Context) {
+	quoteID := c.Param("quote_id")
+
+	quote, err := h.clientFor(c).GetQuoteByID(c.Request.Context(), quoteID)
 	if err != nil {
-		h.Logger.Error("Failed to fetch quotes", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch quotes"})
+		h.Logger.Error("Failed to fetch quote", zap.String("quote_id", quoteID), zap.Error(err))
+		switch {
+		case errors.Is(err, rmp.ErrQuoteExpired):
+			h.respond(c, http.StatusGone, gin.H{"error": "Quote has expired"})
+		case errors.Is(err, rmp.ErrQuoteNotFound):
+			h.respond(c, http.StatusNotFound, gin.H{"error": "Quote not found"})
+		default:
+			h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch quote"})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, quotes)
+	h.respond(c, http.StatusOK, quote)
 }
 func (h *OnramperManager) GetTransactionByID(c *gin.Context) {
 	transactionID := c.Param("transaction_id")
 
 	if transactionID == "" {
 		h.Logger.Error("Missing transaction ID")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
 		return
 	}
 
-	response, err := h.onramperClient.GetTransactionByID(c.Request.Context(), transactionID)
+	response, err := h.clientFor(c).GetTransactionByID(c.Request.Context(), transactionID)
 	if err != nil {
-		h.Logger.Error("Failed to fetch transaction", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch transaction"})
+		h.respondTransactionError(c, transactionID, err)
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.respond(c, http.StatusOK, h.withStoredCheckoutDetails(c.Request.Context(), response))
 }
+
+// GetTransactionByOnrampID looks up a transaction by the provider's onramp transaction id.
+// Onramper only supports lookups by its own transactionId, so the id is resolved through
+// the database first. When both ids are known, callers should use GetTransactionByID:
+// the internal transactionId is authoritative.
+func (h *OnramperManager) GetTransactionByOnrampID(c *gin.Context) {
+	onrampTxID := c.Param("onramp_transaction_id")
+	if onrampTxID == "" {
+		h.Logger.Error("Missing onramp transaction ID")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Onramp transaction ID is required"})
+		return
+	}
+	if h.dbClient == nil {
+		h.Logger.Error("Database client is not configured")
+		h.respond(c, http.StatusServiceUnavailable, gin.H{"error": "Transaction lookup is unavailable"})
+		return
+	}
+
+	transactionID, err := h.dbClient.GetTransactionIDByOnrampID(c.Request.Context(), onrampTxID)
+	if errors.Is(err, database.ErrTransactionNotFound) {
+		h.Logger.Warn("Unknown onramp transaction ID", zap.String("onramp_transaction_id", onrampTxID))
+		h.respond(c, http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to resolve onramp transaction ID",
+			zap.String("onramp_transaction_id", onrampTxID),
+			zap.Error(err))
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Failed to resolve transaction"})
+		return
+	}
+
+	response, err := h.clientFor(c).GetTransactionByID(c.Request.Context(), transactionID)
+	if err != nil {
+		h.respondTransactionError(c, transactionID, err)
+		return
+	}
+	h.respond(c, http.StatusOK, h.withStoredCheckoutDetails(c.Request.Context(), response))
+}
+
+// respondTransactionError answers a failed transaction lookup: 404 for a transaction
+// Onramper does not know, 400 for an ID it rejects, and 502 for anything else.
+func (h *OnramperManager) respondTransactionError(c *gin.Context, transactionID string, err error) {
+	switch {
+	case errors.Is(err, rmp.ErrTransactionNotFound):
+		h.Logger.Warn("Transaction not found", zap.String("transaction_id", transactionID))
+		h.respond(c, http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	case errors.Is(err, rmp.ErrInvalidTransactionID):
+		h.Logger.Warn("Invalid transaction ID", zap.String("transaction_id", transactionID), zap.Error(err))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+	default:
+		h.Logger.Error("Failed to fetch transaction", zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch transaction"})
+	}
+}
+
+// withStoredCheckoutDetails adds the redirect URL and session expiry persisted at initiation.
+// Onramper does not return them, so a missing row only costs the extra fields.
+func (h *OnramperManager) withStoredCheckoutDetails(ctx context.Context, tx models.TransactionResponse) models.TransactionDetailsResponse {
+	details := models.TransactionDetailsResponse{TransactionResponse: h.AmountPrecision.roundTransaction(tx)}
+	if h.dbClient == nil {
+		return details
+	}
+	stored, err := h.dbClient.GetTransactionByID(ctx, tx.TransactionID)
+	if err != nil {
+		if !errors.Is(err, database.ErrTransactionNotFound) {
+			h.Logger.Warn("Failed to load stored checkout details",
+				zap.String("transaction_id", tx.TransactionID),
+				zap.Error(err))
+		}
+		return details
+	}
+	details.RedirectURL = stored.RedirectURL
+	details.SessionExpiresAt = stored.SessionExpiresAt
+	return details
+}
+
+// Page sizes forwarded to Onramper's transaction list.
+const (
+	DefaultListLimit    = 50
+	DefaultMaxListLimit = 100
+)
+
+// maxListLimit returns the configured list page size cap, or the default.
+func (h *OnramperManager) maxListLimit() int {
+	if h.MaxListLimit > 0 {
+		return h.MaxListLimit
+	}
+	return DefaultMaxListLimit
+}
+
 func (h *OnramperManager) ListTransactions(c *gin.Context) {
 	var query models.TransactionListQuery
 	err := c.ShouldBindQuery(&query)
 	if err != nil {
 		h.Logger.Error("Invalid query parameters", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	if query.Limit < 0 {
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "limit must not be negative"})
 		return
 	}
 	// Default limit if not provided
 	if query.Limit == 0 {
-		query.Limit = 50
+		query.Limit = DefaultListLimit
+	}
+	if maxLimit := h.maxListLimit(); query.Limit > maxLimit {
+		h.Logger.Warn("Clamping transaction list limit",
+			zap.Int("requested", query.Limit),
+			zap.Int("max", maxLimit))
+		query.Limit = maxLimit
 	}
-	response, err := h.onramperClient.ListTransactions(c.Request.Context(), query)
+	response, err := h.clientFor(c).ListTransactions(c.Request.Context(), query)
 	if err != nil {
 		h.Logger.Error("Failed to list transactions", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to list transactions"})
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to list transactions"})
+		return
+	}
+	h.respond(c, http.StatusOK, response)
+}
+
+// Page sizes for user transaction history served from the database.
+const (
+	defaultUserTransactionsLimit = 50
+	maxUserTransactionsLimit     = 100
+)
+
+// GetUserTransactions returns a user's transaction history from the database,
+// without a round trip to Onramper.
+func (h *OnramperManager) GetUserTransactions(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		h.Logger.Error("Missing user ID")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	limit := defaultUserTransactionsLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxUserTransactionsLimit {
+			h.respond(c, http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxUserTransactionsLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+	if h.dbClient == nil {
+		h.Logger.Error("Database client is not configured")
+		h.respond(c, http.StatusServiceUnavailable, gin.H{"error": "Transaction history is unavailable"})
+		return
+	}
+
+	transactions, err := h.dbClient.GetTransactionsByUser(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.Logger.Error("Failed to fetch user transactions", zap.String("user_id", userID), zap.Error(err))
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		return
+	}
+	if transactions == nil {
+		transactions = []models.FiatTransaction{}
+	}
+	h.respond(c, http.StatusOK, gin.H{"transactions": transactions, "limit": limit})
+}
+
+// GetWalletTransactions returns every stored transaction paying out to a wallet address,
+// for support investigating a user by their address. It is an operator endpoint, as the
+// rows may belong to several users.
+func (h *OnramperManager) GetWalletTransactions(c *gin.Context) {
+	walletAddress := strings.TrimSpace(c.Param("wallet_address"))
+	if walletAddress == "" {
+		h.Logger.Error("Missing wallet address")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "wallet_address is required"})
+		return
+	}
+	if h.dbClient == nil {
+		h.Logger.Error("Database client is not configured")
+		h.respond(c, http.StatusServiceUnavailable, gin.H{"error": "Transaction history is unavailable"})
+		return
+	}
+
+	transactions, err := h.dbClient.GetTransactionsByWallet(c.Request.Context(), walletAddress)
+	if err != nil {
+		h.Logger.Error("Failed to fetch wallet transactions", zap.String("wallet_address", maskValue(walletAddress)), zap.Error(err))
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	if transactions == nil {
+		transactions = []models.FiatTransaction{}
+	}
+	h.respond(c, http.StatusOK, gin.H{"transactions": transactions})
 }
 func (h *OnramperManager) ConfirmSellTransaction(c *gin.Context) {
-	txType := c.Param("type")
+	transactionID := c.Param("transaction_id")
+	if transactionID == "" {
+		h.Logger.Error("Missing transaction ID")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+		return
+	}
+
+	// The hash keys the confirm's idempotency, so a retry without it, or with another,
+	// would be a second confirm of the same sell.
+	txHash := strings.TrimSpace(c.Query("txHash"))
+	if txHash == "" {
+		h.Logger.Error("Missing txHash", zap.String("transaction_id", transactionID))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "txHash is required"})
+		return
+	}
 
 	h.Logger.Info("Received confirm sell transaction request",
-		zap.String("txType", txType),
+		zap.String("transaction_id", transactionID),
 	)
 
-	response, err := h.onramperClient.ConfirmSellTransaction(c.Request.Context(), txType)
+	response, err := h.clientFor(c).ConfirmSellTransaction(c.Request.Context(), transactionID, txHash)
 	if err != nil {
 		h.Logger.Error("Failed to confirm sell transaction", zap.Error(err))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to confirm sell transaction"})
+		switch {
+		case errors.Is(err, rmp.ErrTransactionNotFound):
+			h.respond(c, http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		case errors.Is(err, rmp.ErrAlreadyConfirmed):
+			h.respond(c, http.StatusConflict, gin.H{"error": "Transaction already confirmed"})
+		case errors.Is(err, rmp.ErrInvalidTransactionState):
+			h.respond(c, http.StatusUnprocessableEntity, gin.H{"error": "Transaction cannot be confirmed in its current state"})
+		default:
+			h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to confirm sell transaction"})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, response)
+	h.audit(c.Request.Context(), h.confirmSellAuditEntry(c.Request.Context(), transactionID, response.Status))
+	h.respond(c, http.StatusOK, response)
+}
+func (h *OnramperManager) GetSellPayoutStatus(c *gin.Context) {
+	transactionID := c.Param("transaction_id")
+	if transactionID == "" {
+		h.Logger.Error("Missing transaction ID")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+		return
+	}
+
+	response, err := h.clientFor(c).GetSellPayoutStatus(c.Request.Context(), transactionID)
+	if err != nil {
+		if errors.Is(err, rmp.ErrTransactionNotConfirmed) {
+			h.Logger.Warn("Payout requested for unconfirmed sell", zap.String("transaction_id", transactionID))
+			h.respond(c, http.StatusConflict, gin.H{"error": "Sell transaction has not been confirmed"})
+			return
+		}
+		h.Logger.Error("Failed to fetch sell payout status", zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to fetch sell payout status"})
+		return
+	}
+	h.respond(c, http.StatusOK, response)
 }
 func (h *OnramperManager) InitiateTransaction(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
 		h.Logger.Error("Missing user_id")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	if !isJSONRequest(c) {
+		h.Logger.Error("Unsupported content type", zap.String("content_type", c.ContentType()))
+		h.respond(c, http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/json"})
 		return
 	}
 	// Parse request body
@@ -336,50 +1007,70 @@ func (h *OnramperManager) InitiateTransaction(c *gin.Context) {
 	err := c.ShouldBindJSON(&payload)
 	if err != nil {
 		h.Logger.Error("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
-	if payload.Wallet.Address == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet address required"})
+	payload.PaymentMethod = h.paymentMethodOrDefault(payload.Country, payload.Type, payload.PaymentMethod)
+	// The wallet belongs to the crypto side: the destination when buying, the source when selling.
+	isSell := strings.EqualFold(payload.Type, "sell")
+	cryptoID := payload.Destination
+	if isSell {
+		cryptoID = payload.Source
+	}
+	err = utils.ValidateWalletAddress(payload.Network, cryptoID, payload.Wallet.Address)
+	if err != nil {
+		h.Logger.Error("Invalid wallet address",
+			zap.String("network", payload.Network),
+			zap.String("crypto", cryptoID),
+			zap.Error(err))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if isSell {
+		err = validateSellPayout(payload)
+		if err != nil {
+			h.Logger.Error("Invalid sell payout details", zap.Error(err))
+			h.respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	// Apply optional widget theming from the query string
+	theme, err := parseThemeQuery(c)
+	if err != nil {
+		h.Logger.Error("Invalid theme parameters", zap.Error(err))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if theme != nil {
+		if payload.SupportedParams == nil {
+			payload.SupportedParams = &models.SupportedParams{}
+		}
+		payload.SupportedParams.Theme = theme
+	}
 	// Call client to initiate transaction
-	response, err := h.onramperClient.InitiateTransaction(c.Request.Context(), payload)
+	ctx := rmp.WithClientIP(c.Request.Context(), c.ClientIP())
+	response, err := h.clientFor(c).InitiateTransaction(ctx, payload)
 	if err != nil {
 		h.Logger.Error("Failed to initiate transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initiate transaction"})
+		h.respondUpstreamError(c, err, http.StatusInternalServerError, gin.H{"error": "Failed to initiate transaction"})
 		return
 	}
 	txInfo := response.Message.TransactionInformation
-	sess := response.Message.SessionInformation
 
 	if txInfo.TransactionID == "" {
 		h.Logger.Error("Empty transaction ID in Onramper response")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Missing transaction ID in response"})
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Missing transaction ID in response"})
+		return
 	}
 
-	// Build payload for DB
-	onrampTx := &models.WebhookPayload{
-		Country:             sess.Country,
-		InAmount:            sess.Amount,
-		Onramp:              sess.Onramp,
-		OnrampTransactionID: txInfo.TransactionID,
-		OutAmount:           0.0,
-		PaymentMethod:       sess.PaymentMethod,
-		SourceCurrency:      sess.Source,
-		Status:              utils.MapTransactionStatus(response.Message.Status),
-		StatusDate:          time.Now().UTC(),
-		TargetCurrency:      sess.Destination,
-		TransactionID:       txInfo.TransactionID,
-		TransactionType:     strings.ToUpper(sess.Type),
-		TransactionHash:     "",
-		WalletAddress:       sess.Wallet.Address,
-	}
+	// Build payload for DB; the partner is kept so reconciliation asks the same client
+	onrampTx := initiatedTransaction(payload, response)
+	onrampTx.PartnerID = partnerFor(c)
 
 	// Insert into DB
 	if h.dbClient == nil {
 		h.Logger.Error("Database client is nil")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
 
@@ -390,7 +1081,7 @@ func (h *OnramperManager) InitiateTransaction(c *gin.Context) {
 			zap.String("transaction_status", response.Message.Status),
 			zap.String("transaction_id", txInfo.TransactionID),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"Error": "Failed to save transaction"})
+		h.respond(c, http.StatusInternalServerError, gin.H{"Error": "Failed to save transaction"})
 		return
 	}
 	// verify user ID match
@@ -400,16 +1091,134 @@ func (h *OnramperManager) InitiateTransaction(c *gin.Context) {
 			zap.String("return", returnedUserID),
 		)
 	}
+	h.audit(ctx, initiatedAuditEntry(userID, onrampTx))
 	h.Logger.Info("Transaction initiated successfully",
 		zap.String("transaction_id", txInfo.TransactionID),
 		zap.String("user_id", userID),
 		zap.String("status", response.Message.Status),
 	)
 	// Return response
-	c.JSON(http.StatusOK, gin.H{
+	h.respond(c, http.StatusOK, gin.H{
 		"status":         response.Message.Status,
 		"transaction_id": txInfo.TransactionID,
 		"user_id":        userID,
 		"redirect_url":   txInfo.URL,
 	})
 }
+
+// validateSellPayout checks the details needed to pay out a sell: the crypto amount,
+// the fiat currency and the payout method.
+func validateSellPayout(payload models.InitiateTransactionRequest) error {
+	switch {
+	case payload.Amount <= 0:
+		return errors.New("amount of crypto to sell must be positive")
+	case payload.Destination == "":
+		return errors.New("destination fiat currency is required for sell")
+	case payload.PaymentMethod == "":
+		return errors.New("payout method is required for sell")
+	}
+	return nil
+}
+
+// initiatedTransaction builds the record stored for a new checkout session. Buys are
+// recorded as Onramper echoes the session. Sells are recorded from the request, so the
+// crypto sold is the source, the fiat paid out the target and the wallet the one the
+// crypto is sent from, whatever orientation the session reports.
+func initiatedTransaction(payload models.InitiateTransactionRequest, response models.InitiateTransactionResponse) *models.WebhookPayload {
+	txInfo := response.Message.TransactionInformation
+	sess := response.Message.SessionInformation
+	onrampTx := &models.WebhookPayload{
+		Country:             sess.Country,
+		InAmount:            models.NewAmountFromFloat(sess.Amount),
+		Onramp:              sess.Onramp,
+		OnrampTransactionID: txInfo.TransactionID,
+		OutAmount:           models.Amount{},
+		PaymentMethod:       sess.PaymentMethod,
+		SourceCurrency:      sess.Source,
+		Status:              utils.MapTransactionStatus(response.Message.Status),
+		StatusDate:          time.Now().UTC(),
+		TargetCurrency:      sess.Destination,
+		TransactionID:       txInfo.TransactionID,
+		TransactionType:     strings.ToUpper(sess.Type),
+		TransactionHash:     "",
+		WalletAddress:       sess.Wallet.Address,
+		RedirectURL:         txInfo.URL,
+		InitiateParams:      &payload,
+	}
+	if sess.ExpiringTime > 0 {
+		expiresAt := time.Unix(sess.ExpiringTime, 0).UTC()
+		onrampTx.SessionExpiresAt = &expiresAt
+	}
+	// Onramper sometimes omits these from the session; the request says what was asked for.
+	if onrampTx.Onramp == "" {
+		onrampTx.Onramp = payload.Onramp
+	}
+	if onrampTx.PaymentMethod == "" {
+		onrampTx.PaymentMethod = payload.PaymentMethod
+	}
+	if !strings.EqualFold(payload.Type, "sell") {
+		return onrampTx
+	}
+	onrampTx.TransactionType = strings.ToUpper(string(models.SellTransaction))
+	onrampTx.SourceCurrency = payload.Source
+	onrampTx.TargetCurrency = payload.Destination
+	onrampTx.InAmount = models.NewAmountFromFloat(payload.Amount)
+	onrampTx.PaymentMethod = payload.PaymentMethod
+	onrampTx.WalletAddress = payload.Wallet.Address
+	if payload.Country != "" {
+		onrampTx.Country = payload.Country
+	}
+	return onrampTx
+}
+
+// parseThemeQuery builds a checkout theme from query parameters, or nil when none are set.
+func parseThemeQuery(c *gin.Context) (*models.CheckoutTheme, error) {
+	theme := &models.CheckoutTheme{}
+	found := false
+
+	if name := c.Query("theme"); name != "" {
+		if name != "dark" && name != "light" {
+			return nil, fmt.Errorf("theme must be dark or light, got %q", name)
+		}
+		theme.ThemeName = name
+		theme.IsDark = name == "dark"
+		found = true
+	}
+
+	colors := []struct {
+		param  string
+		target *string
+	}{
+		{"primaryColor", &theme.PrimaryColor},
+		{"secondaryColor", &theme.SecondaryColor},
+		{"primaryTextColor", &theme.PrimaryTextColor},
+		{"secondaryTextColor", &theme.SecondaryTextColor},
+		{"cardColor", &theme.CardColor},
+	}
+	for _, color := range colors {
+		value := c.Query(color.param)
+		if value == "" {
+			continue
+		}
+		if !utils.IsHexColor(value) {
+			return nil, fmt.Errorf("%s must be a hex color, got %q", color.param, value)
+		}
+		// Onramper expects bare hex digits.
+		*color.target = strings.TrimPrefix(value, "#")
+		found = true
+	}
+
+	if radius := c.Query("borderRadius"); radius != "" {
+		value, err := strconv.Atoi(radius)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("borderRadius must be a non-negative integer, got %q", radius)
+		}
+		theme.BorderRadius = &value
+		found = true
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return theme, nil
+}
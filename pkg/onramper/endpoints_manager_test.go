@@ -5,15 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"github.com/subdialia/fiat-ramp-service/pkg/onrampclient/onrampclienttest"
 	"go.uber.org/zap"
 )
 
@@ -22,6 +28,91 @@ type MockOnramperClient struct {
 	mock.Mock
 }
 
+func (m *MockOnramperClient) GetCurrencies(ctx context.Context, country string, subdivision string, transactionType string) (models.SupportedCurrenciesResponse, error) {
+	args := m.Called(ctx, country, subdivision, transactionType)
+	return args.Get(0).(models.SupportedCurrenciesResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetPaymentTypes(ctx context.Context, transactionType string, isRecurringPayment bool, country string) (models.PaymentTypesResponse, error) {
+	args := m.Called(ctx, transactionType, isRecurringPayment, country)
+	return args.Get(0).(models.PaymentTypesResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetPaymentsByCurrency(ctx context.Context, sourceCurrency string, transactionType string, isRecurringPayment bool, destination string, country string, subdivision string) (models.PaymentResponse, error) {
+	args := m.Called(ctx, sourceCurrency, transactionType, isRecurringPayment, destination, country, subdivision)
+	return args.Get(0).(models.PaymentResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetDefaults(ctx context.Context, transactionType string, country string, subdivision string) (models.DefaultsResponse, error) {
+	args := m.Called(ctx, transactionType, country, subdivision)
+	return args.Get(0).(models.DefaultsResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetAssets(ctx context.Context, paymentParam *models.AssetRequest) (models.SupportedAssetsResponse, error) {
+	args := m.Called(ctx, paymentParam)
+	return args.Get(0).(models.SupportedAssetsResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetOnramps(ctx context.Context, params *models.OnrampsQuery) (models.OnrampResponse, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(models.OnrampResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetOnrampMetadata(ctx context.Context, transactionType string) (models.OnrampMetadataResponse, error) {
+	args := m.Called(ctx, transactionType)
+	return args.Get(0).(models.OnrampMetadataResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetCryptoByFiat(ctx context.Context, source string, country string) (models.CryptoFiatResponse, error) {
+	args := m.Called(ctx, source, country)
+	return args.Get(0).(models.CryptoFiatResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetAllPaymentTypes(ctx context.Context, country string) (models.AllPaymentTypesResponse, error) {
+	args := m.Called(ctx, country)
+	return args.Get(0).(models.AllPaymentTypesResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetQuoteByID(ctx context.Context, quoteID string) (models.QuoteResponse, error) {
+	args := m.Called(ctx, quoteID)
+	return args.Get(0).(models.QuoteResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetSupportedCountries(ctx context.Context, transactionType string) ([]string, error) {
+	args := m.Called(ctx, transactionType)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetQuotes(ctx context.Context, fiat string, crypto string, quotesParam *models.QuoteQueryParams) ([]models.QuoteResponse, error) {
+	args := m.Called(ctx, fiat, crypto, quotesParam)
+	return args.Get(0).([]models.QuoteResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetTransactionByID(ctx context.Context, transactionID string) (models.TransactionResponse, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(models.TransactionResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) ListTransactions(ctx context.Context, query models.TransactionListQuery) (models.TransactionListResponse, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(models.TransactionListResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) InitiateTransaction(ctx context.Context, payload models.InitiateTransactionRequest) (models.InitiateTransactionResponse, error) {
+	args := m.Called(ctx, payload)
+	return args.Get(0).(models.InitiateTransactionResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) ConfirmSellTransaction(ctx context.Context, transactionID string, txHash string) (models.SellTransactionConfirmationResponse, error) {
+	args := m.Called(ctx, transactionID, txHash)
+	return args.Get(0).(models.SellTransactionConfirmationResponse), args.Error(1)
+}
+
+func (m *MockOnramperClient) GetSellPayoutStatus(ctx context.Context, transactionID string) (models.SellPayoutStatusResponse, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(models.SellPayoutStatusResponse), args.Error(1)
+}
+
 func TestGetCurrencies(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -90,22 +181,185 @@ func TestGetDefaults(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+func TestGetDefaultsFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockResponse := models.DefaultsResponse{
+		Message: models.DefaultsMessage{
+			Recommended: models.DefaultSetting{Source: "NGN", Target: "BTC", Amount: 30000, Provider: "yellowcard"},
+			Defaults: models.CountryDefaults{
+				"au": {Source: "AUD", Target: "BTC", Amount: 100, Provider: "banxa"},
+			},
+		},
+	}
+	tests := []struct {
+		name             string
+		country          string
+		expectedSource   string
+		expectedFallback bool
+	}{
+		{name: "country present", country: "AU", expectedSource: "AUD", expectedFallback: false},
+		{name: "country missing", country: "FR", expectedSource: "NGN", expectedFallback: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetDefaults", mock.Anything, "buy", tt.country, "").Return(mockResponse, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/defaults/all?country="+tt.country+"&fallback=true", nil)
+
+			manager.GetDefaults(c)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var selection models.DefaultSelection
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &selection))
+			assert.Equal(t, tt.country, selection.Country)
+			assert.Equal(t, tt.expectedSource, selection.Setting.Source)
+			assert.Equal(t, tt.expectedFallback, selection.Fallback)
+		})
+	}
+}
 func TestConfirmSellTransaction(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockResponse := json.RawMessage(`{"status":"confirmed"}`)
 
 	t.Run("success", func(t *testing.T) {
 		mockClient := new(MockOnramperClient)
-		mockClient.On("ConfirmSellTransaction", context.Background(), "sell").
+		mockClient.On("ConfirmSellTransaction", context.Background(), "sell", "").
 			Return(mockResponse, nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
-		c.Params = gin.Params{{Key: "type", Value: "sell"}}
+		c.Params = gin.Params{{Key: "transaction_id", Value: "sell"}}
 		c.Request = httptest.NewRequest(http.MethodPost, "/transction/confirm/offramp", nil)
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+func TestConfirmSellTransactionErrorMapping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		clientErr      error
+		expectedStatus int
+	}{
+		{name: "not found", clientErr: rmp.ErrTransactionNotFound, expectedStatus: http.StatusNotFound},
+		{name: "already confirmed", clientErr: rmp.ErrAlreadyConfirmed, expectedStatus: http.StatusConflict},
+		{name: "invalid state", clientErr: rmp.ErrInvalidTransactionState, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "upstream failure", clientErr: errors.New("api error"), expectedStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("ConfirmSellTransaction", mock.Anything, "sell", "0xabc").
+				Return(models.SellTransactionConfirmationResponse{}, fmt.Errorf("wrapped: %w", tt.clientErr))
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "transaction_id", Value: "sell"}}
+			c.Request = httptest.NewRequest(http.MethodPost, "/transactions/confirm/sell?txHash=0xabc", nil)
+
+			manager.ConfirmSellTransaction(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestConfirmSellTransactionRequiresTxHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, query := range []string{"", "?txHash=", "?txHash=%20"} {
+		mockClient := new(MockOnramperClient)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "transaction_id", Value: "sell"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/transactions/confirm/sell"+query, nil)
+
+		manager.ConfirmSellTransaction(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.JSONEq(t, `{"error":"txHash is required"}`, w.Body.String())
+		mockClient.AssertNotCalled(t, "ConfirmSellTransaction", mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+func TestGetTransactionByOnrampID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		onrampTxID     string
+		resolvedID     string
+		resolveErr     error
+		expectedStatus int
+	}{
+		{name: "resolved", onrampTxID: "OR-2123428075629314", resolvedID: "01H6DQWMRC8FA9MBM0HS5NABCD", expectedStatus: http.StatusOK},
+		{name: "unknown onramp id", onrampTxID: "OR-missing", resolveErr: database.ErrTransactionNotFound, expectedStatus: http.StatusNotFound},
+		{name: "database failure", onrampTxID: "OR-2123428075629314", resolveErr: errors.New("db down"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockDB := new(MockQueryClient)
+			mockDB.On("GetTransactionIDByOnrampID", mock.Anything, tt.onrampTxID).Return(tt.resolvedID, tt.resolveErr)
+			if tt.resolveErr == nil {
+				mockClient.On("GetTransactionByID", mock.Anything, tt.resolvedID).
+					Return(models.TransactionResponse{TransactionID: tt.resolvedID, OnrampTransactionID: tt.onrampTxID}, nil)
+				mockDB.On("GetTransactionByID", mock.Anything, tt.resolvedID).
+					Return(models.FiatTransaction{}, database.ErrTransactionNotFound)
+			}
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "onramp_transaction_id", Value: tt.onrampTxID}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/transactions/onramp/"+tt.onrampTxID, nil)
+
+			manager.GetTransactionByOnrampID(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), tt.resolvedID)
+			}
+			mockDB.AssertExpectations(t)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAllPaymentTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	merged := models.AllPaymentTypesResponse{Message: map[string]models.CombinedPaymentType{
+		"creditcard": {
+			PaymentType:  models.PaymentType{PaymentTypeID: "creditcard", Name: "Credit Card"},
+			SupportedFor: []string{"buy", "sell"},
+		},
+	}}
+	tests := []struct {
+		name           string
+		clientErr      error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "upstream failure", clientErr: errors.New("api error"), expectedStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetAllPaymentTypes", mock.Anything, "us").Return(merged, tt.clientErr)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/payment-types/all?country=us", nil)
+
+			manager.GetAllPaymentTypes(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"supportedFor":["buy","sell"]`)
+			}
+		})
+	}
+}
 func TestListTransactions(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	statusDate := time.Date(2023, 3, 3, 9, 5, 3, 806000000, time.UTC)
@@ -122,8 +376,8 @@ func TestListTransactions(t *testing.T) {
 				APIKey:                "pk_prod_01GTC8JT9MDSW8G11HPPKSVBTJ",
 				SourceCurrency:        "eur",
 				Country:               "nl",
-				InAmount:              100,
-				OutAmount:             0.0644,
+				InAmount:              models.NewAmountFromFloat(100),
+				OutAmount:             models.NewAmountFromFloat(0.0644),
 				TxID:                  "01GTKAZ20PCES058TDY7WJY2PZ",
 				ExternalTransactionID: "b305dc1c-2784-4cd6-9ceb-541fab881378",
 				SK:                    "2023-03-03T09:05:03.806Z",
@@ -146,6 +400,44 @@ func TestListTransactions(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+
+func TestListTransactionsLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		query          string
+		maxLimit       int
+		expectedStatus int
+		expectedLimit  int
+	}{
+		{name: "default", query: "", expectedStatus: http.StatusOK, expectedLimit: DefaultListLimit},
+		{name: "within cap", query: "?limit=80", expectedStatus: http.StatusOK, expectedLimit: 80},
+		{name: "clamped to default cap", query: "?limit=100000", expectedStatus: http.StatusOK, expectedLimit: DefaultMaxListLimit},
+		{name: "clamped to configured cap", query: "?limit=80", maxLimit: 25, expectedStatus: http.StatusOK, expectedLimit: 25},
+		{name: "negative", query: "?limit=-1", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("ListTransactions", mock.Anything, models.TransactionListQuery{Limit: tt.expectedLimit}).
+				Return(models.TransactionListResponse{Limit: tt.expectedLimit}, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, MaxListLimit: tt.maxLimit}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/transactions"+tt.query, nil)
+
+			manager.ListTransactions(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				mockClient.AssertExpectations(t)
+			} else {
+				mockClient.AssertNotCalled(t, "ListTransactions", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
 func TestGetCryptoByFiat(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -213,6 +505,350 @@ func TestGetQuotesBuy(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+func TestGetQuotesForwardsClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetQuotes",
+		mock.MatchedBy(func(ctx context.Context) bool {
+			ip, ok := rmp.ClientIPFromContext(ctx)
+			return ok && ip == "203.0.113.7"
+		}),
+		"USD", "BTC", mock.Anything,
+	).Return([]models.QuoteResponse{{Ramp: "moonpay"}}, nil)
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100", nil)
+	c.Request.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	c.Params = gin.Params{
+		{Key: "source", Value: "USD"},
+		{Key: "destination", Value: "BTC"},
+	}
+
+	manager.GetQuotes(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockClient.AssertExpectations(t)
+}
+func TestClientIPTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(rmp.ClientIPHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"ramp":"moonpay","payout":0.0015}]`))
+	}))
+	defer upstream.Close()
+	client, ok := rmp.NewClient(upstream.URL, "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		expectedIP string
+	}{
+		{name: "untrusted peer cannot set the client IP", remoteAddr: "198.51.100.9:4242", expectedIP: "198.51.100.9"},
+		{name: "trusted proxy reports the client IP", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.2:4242", expectedIP: "203.0.113.7"},
+		{name: "spoofed entry before a trusted proxy is ignored", proxies: []string{"10.0.0.0/8"}, remoteAddr: "198.51.100.9:4242", expectedIP: "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := SetupRouter(client, nil, "test-secret", WithTrustedProxies(tt.proxies...))
+			require.NoError(t, err)
+			forwarded = ""
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tt.expectedIP, forwarded)
+		})
+	}
+
+	_, err := SetupRouter(client, nil, "test-secret", WithTrustedProxies("not-an-ip"))
+	assert.Error(t, err)
+}
+func TestGetQuotesAmountValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, query := range []string{"", "?amount=", "?amount=abc", "?amount=-5"} {
+		t.Run("amount "+query, func(t *testing.T) {
+			manager := &OnramperManager{Logger: zap.NewNop()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC"+query, nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+func TestGetQuotesExcludeExpired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Second)
+	valid := now.Add(time.Minute)
+	quotes := []models.QuoteResponse{
+		{Ramp: "moonpay", ExpiresAt: &expired},
+		{Ramp: "transak", ExpiresAt: &valid},
+		{Ramp: "banxa"},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedRamps []string
+	}{
+		{name: "keeps all by default", query: "?amount=100", expectedRamps: []string{"moonpay", "transak", "banxa"}},
+		{name: "drops expired", query: "?amount=100&excludeExpired=true", expectedRamps: []string{"transak", "banxa"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).Return(quotes, nil)
+			manager := &OnramperManager{
+				Logger:         zap.NewNop(),
+				onramperClient: mockClient,
+				now:            func() time.Time { return now },
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC"+tt.query, nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			var got []models.QuoteResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			ramps := make([]string, 0, len(got))
+			for _, quote := range got {
+				ramps = append(ramps, quote.Ramp)
+			}
+			assert.Equal(t, tt.expectedRamps, ramps)
+		})
+	}
+}
+
+func TestGetQuotesLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	quotes := []models.QuoteResponse{
+		{Ramp: "moonpay", Payout: models.NewAmountFromFloat(0.002)},
+		{Ramp: "fonbnk", Errors: []models.QuoteError{{Type: "NoSupportedPaymentFound"}}},
+		{Ramp: "transak", Payout: models.NewAmountFromFloat(0.004)},
+		{Ramp: "banxa", Payout: models.NewAmountFromFloat(0.003)},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedRamps  []string
+	}{
+		{name: "returns all without limit", query: "?amount=100", expectedStatus: http.StatusOK,
+			expectedRamps: []string{"moonpay", "fonbnk", "transak", "banxa"}},
+		{name: "returns top quotes by payout", query: "?amount=100&limit=2", expectedStatus: http.StatusOK,
+			expectedRamps: []string{"transak", "banxa"}},
+		{name: "sorts errored quotes last", query: "?amount=100&limit=10", expectedStatus: http.StatusOK,
+			expectedRamps: []string{"transak", "banxa", "moonpay", "fonbnk"}},
+		{name: "rejects zero", query: "?amount=100&limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "rejects non-numeric", query: "?amount=100&limit=ten", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+				Return(append([]models.QuoteResponse(nil), quotes...), nil)
+			manager := &OnramperManager{
+				Logger:         zap.NewNop(),
+				onramperClient: mockClient,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC"+tt.query, nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				mockClient.AssertNotCalled(t, "GetQuotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			var got []models.QuoteResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			ramps := make([]string, 0, len(got))
+			for _, quote := range got {
+				ramps = append(ramps, quote.Ramp)
+			}
+			assert.Equal(t, tt.expectedRamps, ramps)
+		})
+	}
+}
+
+func TestGetQuotesIncludeErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fixture := `[
+		{"ramp":"moonpay","paymentMethod":"creditcard","payout":0.00398,"quoteId":"01H985NH79FW951SKERQ45JMYXmoonpay"},
+		{"ramp":"fonbnk","paymentMethod":"creditcard","quoteId":"01H985NH79FW951SKERQ45JMYXfonbnk",
+			"errors":[{"type":"NoSupportedPaymentFound","errorId":6103,"message":"No supported payments found"}]}
+	]`
+	var quotes []models.QuoteResponse
+	require.NoError(t, json.Unmarshal([]byte(fixture), &quotes))
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedRamps    []string
+		expectedFiltered string
+	}{
+		{name: "errors included by default", query: "?amount=100", expectedRamps: []string{"moonpay", "fonbnk"}},
+		{name: "errors stripped", query: "?amount=100&includeErrors=false", expectedRamps: []string{"moonpay"}, expectedFiltered: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).Return(quotes, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC"+tt.query, nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			var got []models.QuoteResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			ramps := make([]string, 0, len(got))
+			for _, quote := range got {
+				ramps = append(ramps, quote.Ramp)
+			}
+			assert.Equal(t, tt.expectedRamps, ramps)
+			assert.Equal(t, tt.expectedFiltered, w.Header().Get(FilteredQuotesHeader))
+		})
+	}
+}
+func TestGetQuotesWithFakeClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := &onrampclienttest.FakeOnRamperClient{
+		Quotes: []models.QuoteResponse{{Ramp: "moonpay", Payout: models.NewAmountFromFloat(0.004)}},
+	}
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: fake}
+	router := gin.New()
+	router.GET("/quotes/:source/:destination", manager.GetQuotes)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"rate":0,"networkFee":0,"transactionFee":0,"payout":0.004,"availablePaymentMethods":null,
+		"ramp":"moonpay","paymentMethod":"","quoteId":"","recommendations":null}]`, w.Body.String())
+
+	fake.QuotesErr = rmp.ErrNoQuotes
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, 2, fake.Calls("GetQuotes"))
+}
+func TestGetQuotesPaymentMethodErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		clientErr      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{name: "unknown method", clientErr: rmp.ErrUnknownPaymentMethod, expectedStatus: http.StatusBadRequest, expectedError: "Unknown payment method"},
+		{name: "unsupported for corridor", clientErr: rmp.ErrPaymentMethodUnsupported, expectedStatus: http.StatusUnprocessableEntity, expectedError: "Payment method unsupported for corridor"},
+		{name: "no quotes", clientErr: rmp.ErrNoQuotes, expectedStatus: http.StatusBadGateway, expectedError: "Failed to fetch quotes"},
+		{
+			name:           "all quotes failed",
+			clientErr:      &rmp.AllQuotesFailed{Errors: []models.QuoteError{{Type: "NoSupportedPaymentFound", ErrorID: 6103, Message: "No supported payments found"}}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "NoSupportedPaymentFound",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+				Return([]models.QuoteResponse(nil), fmt.Errorf("%w: pix", tt.clientErr))
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100&paymentMethod=pix", nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedError)
+		})
+	}
+}
+func TestGetQuotesCorridorOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		path           string
+		query          string
+		expectedFiat   string
+		expectedCrypto string
+	}{
+		{name: "buy is fiat to crypto", path: "/quotes/USD/BTC", query: "?amount=100&type=buy", expectedFiat: "USD", expectedCrypto: "BTC"},
+		{name: "sell is crypto to fiat", path: "/quotes/BTC/USD", query: "?amount=0.1&type=sell", expectedFiat: "USD", expectedCrypto: "BTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, tt.expectedFiat, tt.expectedCrypto, mock.Anything).
+				Return([]models.QuoteResponse{{Ramp: "moonpay"}}, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			router := gin.New()
+			router.GET("/quotes/:source/:destination", manager.GetQuotes)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path+tt.query, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mockClient.AssertExpectations(t)
+		})
+	}
+
+	t.Run("swapped assets are a bad request", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetQuotes", mock.Anything, "BTC", "USD", mock.Anything).
+			Return([]models.QuoteResponse(nil), fmt.Errorf("%w: fiat \"BTC\", crypto \"USD\"", rmp.ErrAssetTypeMismatch))
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		router := gin.New()
+		router.GET("/quotes/:source/:destination", manager.GetQuotes)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quotes/BTC/USD?amount=100&type=buy", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
 func TestGetQuotesSell(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockResponse := json.RawMessage(`{"quotes":[{"rate":0.8}]}`)
@@ -349,14 +985,37 @@ func TestGetTransactionByID(t *testing.T) {
 		manager.GetTransactionByID(c)
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
-	t.Run("client error", func(t *testing.T) {
-		mockClient := new(MockOnramperClient)
-		mockClient.On("GetTransactionByID", context.Background(), "tx_12345").
-			Return(nil, errors.New("api error"))
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/tx_12345", nil)
-		c.Params = gin.Params{{Key: "transaction_id", Value: "tx_12345"}}
+	t.Run("client errors", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			err            error
+			expectedStatus int
+			expectedError  string
+		}{
+			{name: "not found", err: fmt.Errorf("%w: unknown", rmp.ErrTransactionNotFound),
+				expectedStatus: http.StatusNotFound, expectedError: "Transaction not found"},
+			{name: "invalid id", err: fmt.Errorf("%w: malformed", rmp.ErrInvalidTransactionID),
+				expectedStatus: http.StatusBadRequest, expectedError: "Invalid transaction ID"},
+			{name: "upstream failure", err: errors.New("api error"),
+				expectedStatus: http.StatusBadGateway, expectedError: "Failed to fetch transaction"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockClient := new(MockOnramperClient)
+				mockClient.On("GetTransactionByID", mock.Anything, "tx_12345").
+					Return(models.TransactionResponse{}, tt.err)
+				manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = httptest.NewRequest(http.MethodGet, "/transactions/tx_12345", nil)
+				c.Params = gin.Params{{Key: "transaction_id", Value: "tx_12345"}}
+
+				manager.GetTransactionByID(c)
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, tt.expectedError), w.Body.String())
+			})
+		}
 	})
 }
 func TestGetOnramps(t *testing.T) {
@@ -391,6 +1050,47 @@ func TestGetOnramps(t *testing.T) {
 		c.Request = httptest.NewRequest(http.MethodGet, "/onramps?type=sell", nil)
 	})
 }
+func TestGetOnrampsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "missing source", query: "?type=buy&destination=BTC"},
+		{name: "missing destination", query: "?type=buy&source=USD"},
+		{name: "missing both", query: "?type=buy"},
+		{name: "invalid type", query: "?type=swap&source=USD&destination=BTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &OnramperManager{Logger: zap.NewNop()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/onramps"+tt.query, nil)
+
+			manager.GetOnramps(c)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+	t.Run("type defaults to buy", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetOnramps", mock.Anything, &models.OnrampsQuery{
+			TransactionType: "buy",
+			Source:          "USD",
+			Destination:     "BTC",
+		}).Return(models.OnrampResponse{}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/supported/onramps?source=USD&destination=BTC", nil)
+
+		manager.GetOnramps(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockClient.AssertExpectations(t)
+	})
+}
 func TestInitiateTransaction(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockResponse := struct {
@@ -430,3 +1130,892 @@ func TestInitiateTransaction(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+func TestGetSellPayoutStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	arrival := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		response       models.SellPayoutStatusResponse
+		clientErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "payout in progress",
+			response:       models.SellPayoutStatusResponse{TransactionID: "tx_sell", PayoutStatus: "processing", ExpectedArrival: &arrival},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not yet confirmed",
+			clientErr:      fmt.Errorf("%w: awaiting confirmation", rmp.ErrTransactionNotConfirmed),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "upstream failure",
+			clientErr:      errors.New("api error"),
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetSellPayoutStatus", mock.Anything, "tx_sell").Return(tt.response, tt.clientErr)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/transactions/tx_sell/payout", nil)
+			c.Params = gin.Params{{Key: "transaction_id", Value: "tx_sell"}}
+
+			manager.GetSellPayoutStatus(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+func TestCheckoutDetailsPersistence(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expiresAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	t.Run("initiate stores redirect URL and expiry", func(t *testing.T) {
+		var response models.InitiateTransactionResponse
+		response.Message.Status = "in_progress"
+		response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+		response.Message.TransactionInformation.URL = "https://buy.moonpay.com/checkout"
+		response.Message.SessionInformation.ExpiringTime = expiresAt.Unix()
+
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+		mockDB := new(MockQueryClient)
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.MatchedBy(func(tx *models.WebhookPayload) bool {
+			return tx.RedirectURL == "https://buy.moonpay.com/checkout" &&
+				tx.SessionExpiresAt != nil && tx.SessionExpiresAt.Equal(expiresAt)
+		}), "user_456").Return("user_456", nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+			bytes.NewBufferString(`{"wallet":{"address":"0x123"}}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		manager.InitiateTransaction(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("initiate falls back to requested onramp and payment method", func(t *testing.T) {
+		var response models.InitiateTransactionResponse
+		response.Message.Status = "in_progress"
+		response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+		response.Message.SessionInformation.Type = "buy"
+
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+		// The upsert goes through the GraphQL client, so the test sees the mutation Hasura gets.
+		var mutation struct {
+			Variables struct {
+				Object        map[string]json.RawMessage `json:"object"`
+				UpdateColumns []string                   `json:"update_columns"`
+			} `json:"variables"`
+		}
+		hasura := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&mutation))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"insert_terrace_schema_fiat_transactions_one":` +
+				`{"user_id":"user_456","transaction_id":"01H9KBT5C21JY0BAX4VTW9EP3V","transaction_status":"pending"}}}`))
+		}))
+		defer hasura.Close()
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient,
+			dbClient: database.NewGraphQLClient(hasura.URL, "secret", zap.NewNop())}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+			bytes.NewBufferString(`{"onramp":"moonpay","paymentMethod":"creditcard","wallet":{"address":"0x123"}}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		manager.InitiateTransaction(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `"moonpay"`, string(mutation.Variables.Object["onramp"]))
+		assert.JSONEq(t, `"creditcard"`, string(mutation.Variables.Object["payment_method"]))
+		assert.Contains(t, mutation.Variables.UpdateColumns, "onramp")
+		assert.Contains(t, mutation.Variables.UpdateColumns, "payment_method")
+	})
+
+	t.Run("transaction lookup returns stored details", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetTransactionByID", mock.Anything, "01H9KBT5C21JY0BAX4VTW9EP3V").
+			Return(models.TransactionResponse{TransactionID: "01H9KBT5C21JY0BAX4VTW9EP3V", Status: "pending"}, nil)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "01H9KBT5C21JY0BAX4VTW9EP3V").Return(models.FiatTransaction{
+			TransactionID:    "01H9KBT5C21JY0BAX4VTW9EP3V",
+			RedirectURL:      "https://buy.moonpay.com/checkout",
+			SessionExpiresAt: &expiresAt,
+		}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/01H9KBT5C21JY0BAX4VTW9EP3V", nil)
+		c.Params = gin.Params{{Key: "transaction_id", Value: "01H9KBT5C21JY0BAX4VTW9EP3V"}}
+
+		manager.GetTransactionByID(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var got models.TransactionDetailsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "pending", got.Status)
+		assert.Equal(t, "https://buy.moonpay.com/checkout", got.RedirectURL)
+		require.NotNil(t, got.SessionExpiresAt)
+		assert.True(t, got.SessionExpiresAt.Equal(expiresAt))
+	})
+}
+func TestInitiateTransactionWalletValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "valid checksummed EVM address",
+			body:           `{"destination":"eth","network":"ethereum","wallet":{"address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "checksum-invalid EVM address",
+			body:           `{"destination":"eth","network":"ethereum","wallet":{"address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "EIP-55 checksum",
+		},
+		{
+			name:           "sell validates the source asset",
+			body:           `{"type":"sell","source":"usdc_polygon","destination":"eur","wallet":{"address":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "40 hex characters",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+			mockDB := new(MockQueryClient)
+			mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user_456").Return("user_456", nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			manager.InitiateTransaction(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+func TestInitiateSellTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+	response.Message.SessionInformation.Type = "sell"
+
+	const wallet = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name: "stores crypto as source and fiat as target",
+			body: `{"type":"sell","source":"eth","destination":"eur","amount":0.5,"paymentMethod":"sepabanktransfer",` +
+				`"network":"ethereum","wallet":{"address":"` + wallet + `"}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing payout method",
+			body:           `{"type":"sell","source":"eth","destination":"eur","amount":0.5,"network":"ethereum","wallet":{"address":"` + wallet + `"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "payout method is required",
+		},
+		{
+			name:           "missing amount",
+			body:           `{"type":"sell","source":"eth","destination":"eur","paymentMethod":"sepabanktransfer","network":"ethereum","wallet":{"address":"` + wallet + `"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "amount of crypto to sell",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+			mockDB := new(MockQueryClient)
+			mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.MatchedBy(func(tx *models.WebhookPayload) bool {
+				return tx.TransactionType == "SELL" &&
+					tx.SourceCurrency == "eth" &&
+					tx.TargetCurrency == "eur" &&
+					tx.InAmount.Equal(models.NewAmountFromFloat(0.5)) &&
+					tx.PaymentMethod == "sepabanktransfer" &&
+					tx.WalletAddress == wallet
+			}), "user_456").Return("user_456", nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			manager.InitiateTransaction(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+				return
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+func TestInitiateTransactionTheme(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+	response.Message.TransactionInformation.URL = "https://buy.moonpay.com/..."
+
+	t.Run("theme propagated", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, mock.MatchedBy(func(payload models.InitiateTransactionRequest) bool {
+			if payload.SupportedParams == nil || payload.SupportedParams.Theme == nil {
+				return false
+			}
+			theme := payload.SupportedParams.Theme
+			return theme.IsDark && theme.ThemeName == "dark" && theme.PrimaryColor == "241D1C" &&
+				theme.BorderRadius != nil && *theme.BorderRadius == 8
+		})).Return(response, nil)
+		mockDB := new(MockQueryClient)
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user_456").Return("user_456", nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost,
+			"/checkout/intent?user_id=user_456&theme=dark&primaryColor=%23241D1C&borderRadius=8",
+			bytes.NewBufferString(`{"wallet":{"address":"0x123"}}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		manager.InitiateTransaction(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockClient.AssertExpectations(t)
+	})
+	t.Run("invalid color", func(t *testing.T) {
+		manager := &OnramperManager{Logger: zap.NewNop()}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost,
+			"/checkout/intent?user_id=user_456&primaryColor=blue",
+			bytes.NewBufferString(`{"wallet":{"address":"0x123"}}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		manager.InitiateTransaction(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+func TestResponseCase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("snake case transaction lookup", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetTransactionByID", mock.Anything, "tx_123").Return(models.TransactionResponse{
+			TransactionID:       "tx_123",
+			OnrampTransactionID: "OR-123",
+			InAmount:            models.NewAmountFromFloat(68),
+		}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, ResponseCase: CaseSnake}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/tx_123", nil)
+		c.Params = gin.Params{{Key: "transaction_id", Value: "tx_123"}}
+
+		manager.GetTransactionByID(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.JSONEq(t, `"tx_123"`, string(body["transaction_id"]))
+		assert.JSONEq(t, `"OR-123"`, string(body["onramp_transaction_id"]))
+		assert.Equal(t, "68", string(body["in_amount"]))
+		assert.NotContains(t, body, "transactionId")
+	})
+
+	t.Run("camel case initiate response", func(t *testing.T) {
+		var response models.InitiateTransactionResponse
+		response.Message.Status = "in_progress"
+		response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+		response.Message.TransactionInformation.URL = "https://buy.moonpay.com/checkout"
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil)
+		mockDB := new(MockQueryClient)
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user_456").Return("user_456", nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, dbClient: mockDB, ResponseCase: CaseCamel}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+			bytes.NewBufferString(`{"wallet":{"address":"0x123"}}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		manager.InitiateTransaction(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"status": "in_progress",
+			"transactionId": "01H9KBT5C21JY0BAX4VTW9EP3V",
+			"userId": "user_456",
+			"redirectUrl": "https://buy.moonpay.com/checkout"
+		}`, w.Body.String())
+	})
+}
+func TestKeyCaseConversion(t *testing.T) {
+	tests := []struct {
+		key   string
+		snake string
+		camel string
+	}{
+		{key: "paymentTypeId", snake: "payment_type_id", camel: "paymentTypeId"},
+		{key: "transaction_id", snake: "transaction_id", camel: "transactionId"},
+		{key: "TxId", snake: "tx_id", camel: "txId"},
+		{key: "redirectURL", snake: "redirect_url", camel: "redirectURL"},
+		{key: "USD", snake: "usd", camel: "USD"},
+		{key: "creditcard", snake: "creditcard", camel: "creditcard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.snake, toSnakeCase(tt.key))
+			assert.Equal(t, tt.camel, toCamelCase(tt.key))
+		})
+	}
+
+	_, err := ParseJSONCase("kebab")
+	require.Error(t, err)
+}
+
+func TestGetUserTransactions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stored := []models.FiatTransaction{{UserID: "user123", TransactionID: "01H6DQWMRC8FA9MBM0HS5NABCD", Status: "COMPLETED"}}
+	tests := []struct {
+		name           string
+		query          string
+		expectedLimit  int
+		dbErr          error
+		expectedStatus int
+	}{
+		{name: "default limit", expectedLimit: defaultUserTransactionsLimit, expectedStatus: http.StatusOK},
+		{name: "custom limit", query: "?limit=10", expectedLimit: 10, expectedStatus: http.StatusOK},
+		{name: "limit too large", query: "?limit=1000", expectedStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=abc", expectedStatus: http.StatusBadRequest},
+		{name: "database failure", expectedLimit: defaultUserTransactionsLimit, dbErr: errors.New("db down"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockQueryClient)
+			if tt.expectedLimit > 0 {
+				mockDB.On("GetTransactionsByUser", mock.Anything, "user123", tt.expectedLimit).Return(stored, tt.dbErr)
+			}
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "user_id", Value: "user123"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/users/user123/transactions"+tt.query, nil)
+
+			manager.GetUserTransactions(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), "01H6DQWMRC8FA9MBM0HS5NABCD")
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetWalletTransactions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const wallet = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	stored := []models.FiatTransaction{
+		{UserID: "user123", TransactionID: "01H6DQWMRC8FA9MBM0HS5NABCD", Status: "COMPLETED", WalletAddress: wallet},
+		{UserID: "user456", TransactionID: "01H6DQWMRC8FA9MBM0HS5NWXYZ", Status: "PENDING", WalletAddress: wallet},
+	}
+	tests := []struct {
+		name           string
+		wallet         string
+		transactions   []models.FiatTransaction
+		dbErr          error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "found", wallet: wallet, transactions: stored, expectedStatus: http.StatusOK, expectedBody: "01H6DQWMRC8FA9MBM0HS5NWXYZ"},
+		{name: "none", wallet: wallet, expectedStatus: http.StatusOK, expectedBody: `{"transactions":[]}`},
+		{name: "database failure", wallet: wallet, dbErr: errors.New("db down"), expectedStatus: http.StatusInternalServerError},
+		{name: "blank wallet", wallet: " ", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockQueryClient)
+			if tt.expectedStatus != http.StatusBadRequest {
+				mockDB.On("GetTransactionsByWallet", mock.Anything, wallet).Return(tt.transactions, tt.dbErr)
+			}
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "wallet_address", Value: tt.wallet}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/wallets/"+wallet+"/transactions", nil)
+
+			manager.GetWalletTransactions(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, ok := rmp.NewClient("https://api.onramper.com", "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+	router, err := SetupRouter(client, nil, "test-secret")
+	require.NoError(t, err)
+
+	for _, path := range []string{"/checkout/intent", "/transactions/confirm/tx_123", "/webhook/onramper"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusNotImplemented, w.Code, path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWebhookPathsRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, ok := rmp.NewClient("https://api.onramper.com", "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+	router, err := SetupRouter(client, database.NewGraphQLClient("http://hasura.invalid", "secret", zap.NewNop()), "test-secret",
+		WithWebhookPaths("/hooks/onramper", " hooks/onramper-eu ", ""),
+		WithPartnerRateLimit(1, 1))
+	require.NoError(t, err)
+
+	post := func(path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		req.Header.Set("X-Onramper-Webhook-Signature", "bad-signature")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	for _, path := range []string{"/hooks/onramper", "/hooks/onramper-eu", "/hooks/onramper"} {
+		assert.Equal(t, http.StatusUnauthorized, post(path), "%s reaches the webhook handler without being rate limited", path)
+	}
+	assert.Equal(t, http.StatusNotFound, post(DefaultWebhookPath), "the default path is replaced")
+}
+
+func TestConfirmSellTransactionRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var confirmedPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		confirmedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"confirmed"}`))
+	}))
+	defer upstream.Close()
+	client, ok := rmp.NewClient(upstream.URL, "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+	router, err := SetupRouter(client, database.NewGraphQLClient("http://hasura.invalid", "secret", zap.NewNop()), "test-secret")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transactions/confirm/01H9KBT5C21JY0BAX4VTW9EP3V?txHash=0xabc", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status":"confirmed"}`, w.Body.String())
+	assert.Equal(t, "/transactions/confirm/01H9KBT5C21JY0BAX4VTW9EP3V", confirmedPath)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transactions/confirm", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "the transaction ID is part of the path")
+}
+
+func TestGetSupportedCountries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name            string
+		query           string
+		expectedType    string
+		clientErr       error
+		expectedStatus  int
+		expectedPayload string
+	}{
+		{name: "defaults to buy", expectedType: "buy", expectedStatus: http.StatusOK, expectedPayload: `{"countries":["ad","au","zm"]}`},
+		{name: "sell", query: "?type=sell", expectedType: "sell", expectedStatus: http.StatusOK, expectedPayload: `{"countries":["ad","au","zm"]}`},
+		{name: "upstream failure", expectedType: "buy", clientErr: errors.New("api error"), expectedStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetSupportedCountries", mock.Anything, tt.expectedType).Return([]string{"ad", "au", "zm"}, tt.clientErr)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/countries"+tt.query, nil)
+
+			manager.GetSupportedCountries(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedPayload != "" {
+				assert.JSONEq(t, tt.expectedPayload, w.Body.String())
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInitiateTransactionContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "form encoded", contentType: "application/x-www-form-urlencoded", body: "wallet=0x123"},
+		{name: "plain text", contentType: "text/plain", body: `{"wallet":{"address":"0x123"}}`},
+		{name: "missing content type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			manager.InitiateTransaction(c)
+			assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+			mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUnwrappedMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	currencies := models.SupportedCurrenciesResponse{}
+	currencies.Message.Fiat = []models.FiatCurrency{{ID: "usd", Code: "USD"}}
+	defaults := models.DefaultsResponse{Message: models.DefaultsMessage{
+		Recommended: models.DefaultSetting{Source: "NGN", Target: "BTC", Amount: 30000},
+		Defaults:    models.CountryDefaults{"au": {Source: "AUD", Target: "BTC", Amount: 100}},
+	}}
+	tests := []struct {
+		name   string
+		unwrap bool
+	}{
+		{name: "envelope", unwrap: false},
+		{name: "unwrapped", unwrap: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetCurrencies", mock.Anything, "", "", "buy").Return(currencies, nil)
+			mockClient.On("GetDefaults", mock.Anything, "buy", "", "").Return(defaults, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+			WithUnwrappedMessages(tt.unwrap)(manager)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported", nil)
+			manager.GetCurrencies(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			var body map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.unwrap {
+				assert.NotContains(t, body, "message")
+				assert.Contains(t, body, "fiat")
+			} else {
+				assert.Equal(t, []string{"message"}, mapKeys(body))
+			}
+
+			w = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/defaults/all", nil)
+			manager.GetDefaults(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			body = nil
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.unwrap {
+				assert.Contains(t, body, "recommended")
+				assert.Contains(t, body, "defaults")
+			} else {
+				assert.Equal(t, []string{"message"}, mapKeys(body))
+			}
+		})
+	}
+}
+
+func mapKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestGetQuoteByID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	quote := models.QuoteResponse{Ramp: "moonpay", QuoteID: "01H985NH79FW951SKERQ45JMYXmoonpay"}
+	tests := []struct {
+		name           string
+		clientErr      error
+		expectedStatus int
+	}{
+		{name: "found", expectedStatus: http.StatusOK},
+		{name: "unknown", clientErr: rmp.ErrQuoteNotFound, expectedStatus: http.StatusNotFound},
+		{name: "expired", clientErr: rmp.ErrQuoteExpired, expectedStatus: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuoteByID", mock.Anything, quote.QuoteID).
+				Return(quote, tt.clientErr)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "quote_id", Value: quote.QuoteID}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/quote/"+quote.QuoteID, nil)
+
+			manager.GetQuoteByID(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"ramp":"moonpay"`)
+			}
+		})
+	}
+}
+
+func TestUpstreamUnavailablePassthrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name               string
+		clientErr          error
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{name: "maintenance with retry-after", clientErr: &rmp.UnavailableError{RetryAfter: "120", Message: "maintenance"}, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "120"},
+		{name: "maintenance without retry-after", clientErr: &rmp.UnavailableError{Message: "maintenance"}, expectedStatus: http.StatusServiceUnavailable},
+		{name: "gateway error", clientErr: errors.New("connection reset"), expectedStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetOnrampMetadata", mock.Anything, "buy").
+				Return(models.OnrampMetadataResponse{}, fmt.Errorf("failed to fetch: %w", tt.clientErr))
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/supported/onramps/all", nil)
+
+			manager.GetOnrampMetadata(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestPartnerClientSelection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaultClient := new(MockOnramperClient)
+	defaultClient.On("GetOnrampMetadata", mock.Anything, "buy").
+		Return(models.OnrampMetadataResponse{Message: []models.OnrampMetadata{{ID: "default"}}}, nil)
+	partnerClient := new(MockOnramperClient)
+	partnerClient.On("GetOnrampMetadata", mock.Anything, "buy").
+		Return(models.OnrampMetadataResponse{Message: []models.OnrampMetadata{{ID: "partner"}}}, nil)
+
+	registry := rmp.NewClientRegistry(defaultClient)
+	registry.Register("partner-a", partnerClient)
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: defaultClient, clients: registry}
+	router := gin.New()
+	router.Use(manager.resolvePartner)
+	router.GET("/supported/onramps/all", manager.GetOnrampMetadata)
+
+	tests := []struct {
+		name           string
+		partner        string
+		expectedStatus int
+		expectedID     string
+	}{
+		{name: "no partner header", expectedStatus: http.StatusOK, expectedID: `"default"`},
+		{name: "registered partner", partner: "partner-a", expectedStatus: http.StatusOK, expectedID: `"partner"`},
+		{name: "unknown partner", partner: "partner-b", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/supported/onramps/all", nil)
+			if tt.partner != "" {
+				req.Header.Set(PartnerHeader, tt.partner)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedID != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedID)
+			}
+		})
+	}
+}
+
+func TestInitiateTransactionStoresPartner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+	partnerClient := new(MockOnramperClient)
+	partnerClient.On("InitiateTransaction", mock.Anything, mock.Anything).Return(response, nil).Once()
+	mockDB := new(MockQueryClient)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.MatchedBy(func(tx *models.WebhookPayload) bool {
+		return tx.PartnerID == "partner-a"
+	}), "user_456").Return("user_456", nil).Once()
+
+	registry := rmp.NewClientRegistry(new(MockOnramperClient))
+	registry.Register("partner-a", partnerClient)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, clients: registry}
+	router := gin.New()
+	router.Use(manager.resolvePartner)
+	router.POST("/checkout/intent", manager.InitiateTransaction)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+		bytes.NewBufferString(`{"onramp":"fonbnk","type":"buy","country":"NG","wallet":{"address":"0x123"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(PartnerHeader, "partner-a")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	partnerClient.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTransactionHistoryRoutesRequireOperator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, ok := rmp.NewClient("https://api.onramper.com", "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+	router, err := SetupRouter(client, nil, "test-secret", WithOperatorToken("op-token"))
+	require.NoError(t, err)
+
+	for _, path := range []string{"/users/user123/transactions", "/wallets/0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed/transactions"} {
+		for authorization, expectedStatus := range map[string]int{
+			"":                http.StatusUnauthorized,
+			"Bearer nope":     http.StatusUnauthorized,
+			"Bearer op-token": http.StatusServiceUnavailable, // reaches the handler; no database here
+		} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, expectedStatus, w.Code, "%s with %q", path, authorization)
+		}
+	}
+}
+
+func TestDebugState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, ok := rmp.NewClient("https://api.onramper.com", "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "operator", path: "/debug/state", authorization: "Bearer op-token", expectedStatus: http.StatusOK},
+		{name: "wrong token", path: "/debug/state", authorization: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "verbose health", path: "/health?verbose=true", expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := SetupRouter(client, nil, "test-secret", WithOperatorToken("op-token"))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if w.Code == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"breakers":{}`)
+			}
+		})
+	}
+}
+
+func TestForwardLocale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &OnramperManager{Logger: zap.NewNop()}
+	router := gin.New()
+	router.Use(manager.forwardLocale)
+	router.GET("/supported", func(c *gin.Context) {
+		locale, _ := rmp.LocaleFromContext(c.Request.Context())
+		c.String(http.StatusOK, locale)
+	})
+
+	for query, expected := range map[string]string{"?locale=de-DE": "de-DE", "": "", "?locale=": ""} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/supported"+query, nil))
+		assert.Equal(t, expected, w.Body.String(), query)
+	}
+
+	for _, query := range []string{"?locale=de_DE", "?locale=" + strings.Repeat("x", 64), "?locale=en-US,de"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/supported"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.JSONEq(t, `{"error":"invalid locale"}`, w.Body.String(), query)
+	}
+}
+
+func TestGetCatalogueRejectsInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetCurrencies", mock.Anything, "usa", "", "buy").
+		Return(models.SupportedCurrenciesResponse{}, fmt.Errorf("%w: country %q", rmp.ErrInvalidCatalogParams, "usa"))
+	mockClient.On("GetOnrampMetadata", mock.Anything, "swap").
+		Return(models.OnrampMetadataResponse{}, fmt.Errorf("%w: type %q", rmp.ErrInvalidCatalogParams, "swap"))
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/supported?country=usa", nil)
+	manager.GetCurrencies(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "usa")
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/supported/onramps/all?type=swap", nil)
+	manager.GetOnrampMetadata(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "swap")
+	mockClient.AssertExpectations(t)
+}
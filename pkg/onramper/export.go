@@ -0,0 +1,169 @@
+package onramper
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+const (
+	// exportPageSize is the page size requested from Onramper while exporting.
+	exportPageSize = 100
+	// maxExportPages stops an export whose cursor never runs out.
+	maxExportPages = 1000
+)
+
+// exportColumns is the stable CSV column set of a transaction export.
+var exportColumns = []string{ //nolint:gochecknoglobals // Fixed CSV header.
+	"transaction_id",
+	"external_transaction_id",
+	"status",
+	"status_date",
+	"tx_type",
+	"onramp",
+	"source_currency",
+	"target_currency",
+	"in_amount",
+	"out_amount",
+	"payment_method",
+	"country",
+	"wallet",
+	"tx_hash",
+}
+
+// exportRow returns the CSV fields of tx in exportColumns order.
+func exportRow(tx models.TransactionItem) []string {
+	return []string{
+		tx.TxID,
+		tx.ExternalTransactionID,
+		tx.Status,
+		tx.StatusDate.UTC().Format(time.RFC3339),
+		tx.TxType,
+		tx.Onramp,
+		tx.SourceCurrency,
+		tx.TargetCurrency,
+		tx.InAmount.String(),
+		tx.OutAmount.String(),
+		tx.PaymentMethod,
+		tx.Country,
+		tx.Wallet,
+		tx.TxHash,
+	}
+}
+
+// ExportTransactions streams every transaction matching the list filters as CSV
+// (format=csv, the default) or as a JSON array (format=json), following Onramper's
+// cursor page by page instead of buffering the whole set.
+func (h *OnramperManager) ExportTransactions(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	var query models.TransactionListQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		h.Logger.Error("Invalid query parameters", zap.Error(err))
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+	query.Limit = exportPageSize
+
+	// Fetch the first page before writing, so an upstream failure still gets a proper status.
+	client := h.clientFor(c)
+	page, err := client.ListTransactions(c.Request.Context(), query)
+	if err != nil {
+		h.Logger.Error("Failed to export transactions", zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusBadGateway, gin.H{"error": "Failed to export transactions"})
+		return
+	}
+
+	// Every page gets a fresh write deadline, so a long export is not cut off by the
+	// server's WriteTimeout after the headers are sent.
+	h.extendWriteDeadline(c, 0)
+	flush := func() {
+		c.Writer.Flush()
+		h.extendWriteDeadline(c, 0)
+	}
+	var write func(models.TransactionItem) error
+	var finish func() error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="transactions.csv"`)
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		write = func(tx models.TransactionItem) error {
+			return w.Write(exportRow(tx))
+		}
+		finish = func() error {
+			w.Flush()
+			return w.Error()
+		}
+		err = w.Write(exportColumns)
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		first := true
+		write = func(tx models.TransactionItem) error {
+			separator := ","
+			if first {
+				separator, first = "", false
+			}
+			if _, werr := c.Writer.WriteString(separator); werr != nil {
+				return werr
+			}
+			return encoder.Encode(tx)
+		}
+		finish = func() error {
+			_, werr := c.Writer.WriteString("]\n")
+			return werr
+		}
+		_, err = c.Writer.WriteString("[")
+	}
+	if err == nil {
+		err = streamTransactionPages(c.Request.Context(), client, query, page, write, flush)
+	}
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		// Headers are already sent; a truncated body is all the client can be told.
+		h.Logger.Error("Transaction export aborted", zap.Error(err))
+	}
+}
+
+// streamTransactionPages writes page and every following page, flushing after each.
+func streamTransactionPages(
+	ctx context.Context,
+	client rmp.OnRamperClient,
+	query models.TransactionListQuery,
+	page models.TransactionListResponse,
+	write func(models.TransactionItem) error,
+	flush func(),
+) error {
+	for pages := 1; ; pages++ {
+		for _, tx := range page.Transactions {
+			if err := write(tx); err != nil {
+				return err
+			}
+		}
+		flush()
+		if page.Cursor == "" || page.Cursor == query.Cursor || pages >= maxExportPages {
+			return nil
+		}
+		query.Cursor = page.Cursor
+		var err error
+		page, err = client.ListTransactions(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+}
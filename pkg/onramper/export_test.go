@@ -0,0 +1,165 @@
+package onramper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+func exportFixture() (models.TransactionListResponse, models.TransactionListResponse) {
+	statusDate := time.Date(2023, 1, 20, 15, 15, 33, 0, time.UTC)
+	first := models.TransactionListResponse{
+		Cursor: "page-2",
+		Transactions: []models.TransactionItem{{
+			TxID:           "Wwl5Hom-CW-qCjdZIB97Xg--",
+			Status:         "pending",
+			StatusDate:     statusDate,
+			TxType:         "onramp",
+			Onramp:         "moonpay",
+			SourceCurrency: "eur",
+			TargetCurrency: "eth",
+			InAmount:       models.NewAmountFromFloat(100),
+			PaymentMethod:  "credit_debit_card",
+			Country:        "lk",
+			APIKey:         "pk_prod_secret",
+		}},
+	}
+	second := models.TransactionListResponse{
+		Transactions: []models.TransactionItem{{
+			TxID:           "Qx81-aZ",
+			Status:         "completed",
+			StatusDate:     statusDate,
+			TxType:         "onramp",
+			Onramp:         "banxa",
+			SourceCurrency: "usd",
+			TargetCurrency: "btc",
+			InAmount:       models.NewAmountFromFloat(250.5),
+			OutAmount:      models.NewAmountFromFloat(0.004),
+			PaymentMethod:  "creditcard",
+			Country:        "us",
+		}},
+	}
+	return first, second
+}
+
+func TestExportTransactions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	first, second := exportFixture()
+
+	newManager := func() (*OnramperManager, *MockOnramperClient) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("ListTransactions", mock.Anything, mock.MatchedBy(func(q models.TransactionListQuery) bool {
+			return q.Cursor == "" && q.Limit == exportPageSize
+		})).Return(first, nil)
+		mockClient.On("ListTransactions", mock.Anything, mock.MatchedBy(func(q models.TransactionListQuery) bool {
+			return q.Cursor == "page-2"
+		})).Return(second, nil)
+		return &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}, mockClient
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		manager, mockClient := newManager()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/export?format=csv", nil)
+
+		manager.ExportTransactions(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, exportColumns, records[0])
+		assert.Equal(t, []string{"Wwl5Hom-CW-qCjdZIB97Xg--", "", "pending", "2023-01-20T15:15:33Z", "onramp", "moonpay",
+			"eur", "eth", "100", "0", "credit_debit_card", "lk", "", ""}, records[1])
+		assert.Equal(t, "Qx81-aZ", records[2][0])
+		assert.Equal(t, "250.5", records[2][8])
+		assert.NotContains(t, w.Body.String(), "pk_prod_secret")
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		manager, _ := newManager()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/export?format=json", nil)
+
+		manager.ExportTransactions(c)
+		require.Equal(t, http.StatusOK, w.Code)
+		var items []models.TransactionItem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		require.Len(t, items, 2)
+		assert.Equal(t, "Qx81-aZ", items[1].TxID)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		manager, _ := newManager()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/export?format=xml", nil)
+
+		manager.ExportTransactions(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("long export outlives the server write timeout", func(t *testing.T) {
+		const pages = 6
+		mockClient := new(MockOnramperClient)
+		for i := 0; i < pages; i++ {
+			cursor, next := "", ""
+			if i > 0 {
+				cursor = fmt.Sprintf("page-%d", i)
+			}
+			if i < pages-1 {
+				next = fmt.Sprintf("page-%d", i+1)
+			}
+			mockClient.On("ListTransactions", mock.Anything, mock.MatchedBy(func(q models.TransactionListQuery) bool {
+				return q.Cursor == cursor
+			})).After(40*time.Millisecond).Return(models.TransactionListResponse{
+				Cursor:       next,
+				Transactions: []models.TransactionItem{{TxID: fmt.Sprintf("tx-%d", i)}},
+			}, nil)
+		}
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+		router := gin.New()
+		router.GET("/transactions/export", manager.ExportTransactions)
+		server := httptest.NewUnstartedServer(router)
+		server.Config.WriteTimeout = 100 * time.Millisecond
+		server.Start()
+		defer server.Close()
+
+		resp, err := server.Client().Get(server.URL + "/transactions/export")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		rows, err := csv.NewReader(resp.Body).ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, rows, pages+1, "header and one row per page")
+	})
+
+	t.Run("first page failure", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("ListTransactions", mock.Anything, mock.Anything).
+			Return(models.TransactionListResponse{}, errors.New("api error"))
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/transactions/export", nil)
+
+		manager.ExportTransactions(c)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
@@ -0,0 +1,93 @@
+package onramper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// FeatureFlagsHeader carries request-scoped feature flags, e.g. "preferred_ramp=moonpay,beta".
+const FeatureFlagsHeader = "X-Feature-Flags"
+
+// FlagPreferredRamp recommends the named ramp's quote whenever it is usable.
+const FlagPreferredRamp = "preferred_ramp"
+
+// FeatureFlags maps lowercased flag names to their values. Bare flags have the value "true".
+type FeatureFlags map[string]string
+
+// Get returns the value of flag name and whether it was set.
+func (f FeatureFlags) Get(name string) (string, bool) {
+	value, ok := f[name]
+	return value, ok
+}
+
+// ParseFeatureFlags parses a comma-separated list of name or name=value pairs.
+// Empty entries are ignored and later duplicates win.
+func ParseFeatureFlags(header string) FeatureFlags {
+	flags := FeatureFlags{}
+	for _, entry := range strings.Split(header, ",") {
+		name, value, hasValue := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !hasValue {
+			value = "true"
+		}
+		flags[name] = strings.TrimSpace(value)
+	}
+	return flags
+}
+
+type featureFlagsKey struct{}
+
+// WithFeatureFlags returns a context carrying flags.
+func WithFeatureFlags(ctx context.Context, flags FeatureFlags) context.Context {
+	return context.WithValue(ctx, featureFlagsKey{}, flags)
+}
+
+// FeatureFlagsFromContext returns the flags stored by WithFeatureFlags, or none.
+func FeatureFlagsFromContext(ctx context.Context) FeatureFlags {
+	flags, _ := ctx.Value(featureFlagsKey{}).(FeatureFlags)
+	return flags
+}
+
+// parseFeatureFlags stores the request's feature flags in its context.
+func parseFeatureFlags(c *gin.Context) {
+	if header := c.GetHeader(FeatureFlagsHeader); header != "" {
+		c.Request = c.Request.WithContext(WithFeatureFlags(c.Request.Context(), ParseFeatureFlags(header)))
+	}
+	c.Next()
+}
+
+// recommendQuote returns the index of the quote to recommend, or -1 if none is usable.
+// The first usable quote is recommended unless a flag prefers another ramp.
+func recommendQuote(quotes []models.QuoteResponse, flags FeatureFlags) int {
+	recommended := -1
+	preferred, _ := flags.Get(FlagPreferredRamp)
+	for i, quote := range quotes {
+		if len(quote.Errors) > 0 {
+			continue
+		}
+		if preferred != "" && strings.EqualFold(quote.Ramp, preferred) {
+			return i
+		}
+		if recommended < 0 {
+			recommended = i
+		}
+	}
+	return recommended
+}
+
+// promoteQuote moves the quote at index to the front, keeping the others in order.
+func promoteQuote(quotes []models.QuoteResponse, index int) []models.QuoteResponse {
+	if index <= 0 {
+		return quotes
+	}
+	recommended := quotes[index]
+	copy(quotes[1:index+1], quotes[:index])
+	quotes[0] = recommended
+	return quotes
+}
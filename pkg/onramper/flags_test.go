@@ -0,0 +1,77 @@
+package onramper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestParseFeatureFlags(t *testing.T) {
+	flags := ParseFeatureFlags(" Preferred_Ramp = banxa ,beta,, empty=,beta=off")
+	assert.Equal(t, FeatureFlags{
+		FlagPreferredRamp: "banxa",
+		"beta":            "off",
+		"empty":           "",
+	}, flags)
+
+	assert.Empty(t, ParseFeatureFlags(""))
+	value, ok := ParseFeatureFlags("beta").Get("beta")
+	assert.True(t, ok)
+	assert.Equal(t, "true", value)
+}
+
+func TestGetQuotesPreferredRampFlag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	quotes := []models.QuoteResponse{
+		{Ramp: "banxa", Errors: []models.QuoteError{{Type: "LimitMismatch", Message: "Amount too low"}}},
+		{Ramp: "moonpay", Payout: models.NewAmountFromFloat(0.0021)},
+		{Ramp: "transak", Payout: models.NewAmountFromFloat(0.0020)},
+	}
+
+	tests := []struct {
+		name          string
+		flags         string
+		expectedRamps []string
+	}{
+		{name: "no flags keeps Onramper's order", expectedRamps: []string{"banxa", "moonpay", "transak"}},
+		{name: "preferred ramp", flags: "preferred_ramp=Transak", expectedRamps: []string{"transak", "banxa", "moonpay"}},
+		{name: "preferred ramp unusable", flags: "preferred_ramp=banxa", expectedRamps: []string{"moonpay", "banxa", "transak"}},
+		{name: "unknown flag", flags: "beta", expectedRamps: []string{"banxa", "moonpay", "transak"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+				Return(append([]models.QuoteResponse(nil), quotes...), nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+			router := gin.New()
+			router.Use(parseFeatureFlags)
+			router.GET("/quotes/:source/:destination", manager.GetQuotes)
+
+			req := httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC?amount=100", nil)
+			if tt.flags != "" {
+				req.Header.Set(FeatureFlagsHeader, tt.flags)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var got []models.QuoteResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			ramps := make([]string, 0, len(got))
+			for _, quote := range got {
+				ramps = append(ramps, quote.Ramp)
+			}
+			assert.Equal(t, tt.expectedRamps, ramps)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package onramper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// kycRetryBatchSize is how many failed KYC updates one retry run processes.
+const kycRetryBatchSize = 100
+
+// kycRetryMaxAttempts is how many times a KYC update is tried before it is left in the
+// queue for an operator, so updates that can never be applied do not hold up newer ones.
+const kycRetryMaxAttempts = 10
+
+// KYC statuses stored by the default mapping.
+const (
+	KYCStatusApproved = "APPROVED"
+	KYCStatusRejected = "REJECTED"
+	KYCStatusPending  = "PENDING"
+)
+
+// KYCStatusMapping maps transaction statuses onto the KYC status stored for the user.
+type KYCStatusMapping map[models.TransactionStatus]string
+
+// DefaultKYCStatusMapping approves completed transactions, rejects failed or canceled ones
+// and marks pending ones, and sells awaiting their payout, as pending.
+func DefaultKYCStatusMapping() KYCStatusMapping {
+	return KYCStatusMapping{
+		models.TransactionStatusCompleted:        KYCStatusApproved,
+		models.TransactionStatusFailed:           KYCStatusRejected,
+		models.TransactionStatusCanceled:         KYCStatusRejected,
+		models.TransactionStatusPending:          KYCStatusPending,
+		models.TransactionStatusProcessingPayout: KYCStatusPending,
+	}
+}
+
+// ParseKYCStatusMapping parses a mapping in the form "completed=APPROVED,failed=REJECTED".
+// Transaction statuses are lowercased; KYC statuses are kept as given.
+func ParseKYCStatusMapping(input string) (mapping KYCStatusMapping, err error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, err
+	}
+	mapping = make(KYCStatusMapping)
+	for _, pair := range strings.Split(input, ",") {
+		txStatus, kycStatus, found := strings.Cut(strings.TrimSpace(pair), "=")
+		txStatus, kycStatus = strings.ToLower(strings.TrimSpace(txStatus)), strings.TrimSpace(kycStatus)
+		if !found || txStatus == "" || kycStatus == "" {
+			err = fmt.Errorf("invalid KYC status mapping %q", pair)
+			return nil, err
+		}
+		mapping[models.TransactionStatus(txStatus)] = kycStatus
+	}
+	return mapping, err
+}
+
+// WithKYCStatusMapping replaces the default KYC mapping. With skipUnmapped, webhooks whose
+// status is not in mapping leave KYC untouched instead of failing.
+func WithKYCStatusMapping(mapping KYCStatusMapping, skipUnmapped bool) ManagerOption {
+	return func(h *OnramperManager) {
+		h.KYCStatusMapping = mapping
+		h.SkipUnmappedKYCStatuses = skipUnmapped
+	}
+}
+
+// kycStatusFor returns the KYC status for a raw transaction status and whether one is mapped.
+func (h *OnramperManager) kycStatusFor(rawStatus string) (kycStatus string, ok bool) {
+	mapping := h.KYCStatusMapping
+	if mapping == nil {
+		mapping = DefaultKYCStatusMapping()
+	}
+	kycStatus, ok = mapping[models.TransactionStatus(strings.ToLower(rawStatus))]
+	return kycStatus, ok
+}
+
+// enqueueFailedKYCUpdate stores a KYC update for RetryFailedKYCUpdates. If even that
+// fails, the update is only logged.
+func (h *OnramperManager) enqueueFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) {
+	err := h.dbClient.EnqueueFailedKYCUpdate(ctx, update)
+	if err != nil {
+		h.Logger.Error("Failed to enqueue KYC update for retry; update lost",
+			zap.String("userID", update.UserID),
+			zap.String("status", update.KYCStatus),
+			zap.Error(err))
+		return
+	}
+	h.Logger.Info("KYC update queued for retry",
+		zap.String("userID", update.UserID),
+		zap.String("status", update.KYCStatus))
+}
+
+// RetryFailedKYCUpdates re-applies queued KYC updates, removing the ones that succeed
+// and counting another attempt on the rest. An update queued before the user's KYC
+// status was last updated is stale: it is removed without being applied, so it cannot
+// overwrite the newer status. Updates tried kycRetryMaxAttempts times are no longer
+// retried.
+func (h *OnramperManager) RetryFailedKYCUpdates(ctx context.Context) (retried int, err error) {
+	if h.dbClient == nil {
+		err = errors.New("database client is not configured")
+		return retried, err
+	}
+	updates, err := h.dbClient.GetFailedKYCUpdates(ctx, kycRetryBatchSize, kycRetryMaxAttempts)
+	if err != nil {
+		err = fmt.Errorf("failed to load failed KYC updates: %w", err)
+		return retried, err
+	}
+
+	for _, update := range updates {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("KYC retry cancelled: %w", ctx.Err())
+			return retried, err
+		}
+		resultStatus, applied, updateErr := h.dbClient.ApplyFailedKYCUpdate(ctx, update)
+		if updateErr != nil {
+			h.recordFailedKYCRetry(ctx, update, updateErr)
+			continue
+		}
+		if !applied {
+			h.Logger.Info("Stale KYC update dropped; a newer status is stored",
+				zap.String("userID", update.UserID),
+				zap.String("status", update.KYCStatus),
+				zap.Time("queuedAt", update.CreatedAt))
+		} else if h.kycMetrics != nil {
+			h.kycMetrics.ObserveTransition(update.UserID, update.TransactionStatus, resultStatus)
+		}
+		resolveErr := h.dbClient.ResolveFailedKYCUpdate(ctx, update.ID)
+		if resolveErr != nil {
+			// The update is stored with the time it was queued, so the next run finds it
+			// stale and removes it without applying it again.
+			h.Logger.Error("Failed to remove retried KYC update", zap.String("id", update.ID), zap.Error(resolveErr))
+		}
+		if applied {
+			retried++
+		}
+	}
+	return retried, nil
+}
+
+// recordFailedKYCRetry counts another failed attempt at update, and reports it as given
+// up once it reaches kycRetryMaxAttempts.
+func (h *OnramperManager) recordFailedKYCRetry(ctx context.Context, update models.FailedKYCUpdate, updateErr error) {
+	attempts := update.Attempts + 1
+	if attempts >= kycRetryMaxAttempts {
+		h.Logger.Error("KYC update given up; left queued for an operator",
+			zap.String("id", update.ID),
+			zap.String("userID", update.UserID),
+			zap.String("status", update.KYCStatus),
+			zap.Int("attempts", attempts),
+			zap.Error(updateErr))
+	} else {
+		h.Logger.Warn("KYC update retry failed",
+			zap.String("userID", update.UserID),
+			zap.Int("attempts", attempts),
+			zap.Error(updateErr))
+	}
+	recordErr := h.dbClient.RecordFailedKYCRetry(ctx, update.ID, updateErr.Error())
+	if recordErr != nil {
+		h.Logger.Error("Failed to record KYC retry", zap.String("id", update.ID), zap.Error(recordErr))
+	}
+}
@@ -0,0 +1,167 @@
+package onramper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseKYCStatusMapping(t *testing.T) {
+	mapping, err := ParseKYCStatusMapping(" Completed=VERIFIED , refunded=REVIEW")
+	require.NoError(t, err)
+	assert.Equal(t, KYCStatusMapping{"completed": "VERIFIED", "refunded": "REVIEW"}, mapping)
+
+	mapping, err = ParseKYCStatusMapping("")
+	require.NoError(t, err)
+	assert.Nil(t, mapping)
+
+	_, err = ParseKYCStatusMapping("completed")
+	require.Error(t, err)
+	_, err = ParseKYCStatusMapping("completed=")
+	require.Error(t, err)
+}
+
+func TestHandleKYCWebhookMapping(t *testing.T) {
+	custom := KYCStatusMapping{
+		models.TransactionStatusCompleted: "VERIFIED",
+		"refunded":                        "REVIEW",
+	}
+	tests := []struct {
+		name         string
+		mapping      KYCStatusMapping
+		skipUnmapped bool
+		status       string
+		expected     string
+		expectErr    bool
+	}{
+		{name: "default mapping", status: "canceled", expected: KYCStatusRejected},
+		{name: "custom mapping", mapping: custom, status: "Completed", expected: "VERIFIED"},
+		{name: "custom only status", mapping: custom, status: "refunded", expected: "REVIEW"},
+		{name: "unmapped errors", mapping: custom, status: "failed", expectErr: true},
+		{name: "unmapped skipped", mapping: custom, skipUnmapped: true, status: "failed"},
+		{name: "unknown errors by default", status: "exploded", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockQueryClient)
+			if tt.expected != "" {
+				mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_123", "", "").Return("user123", nil)
+				mockDB.On("UpdateKYCStatus", mock.Anything, "user123", tt.expected).Return(tt.expected, nil)
+			}
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+			WithKYCStatusMapping(tt.mapping, tt.skipUnmapped)(manager)
+
+			status, err := manager.HandleKYCWebhook(context.Background(), &models.WebhookPayload{TransactionID: "tx_123", Status: tt.status})
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, status)
+			// Unmapped statuses never reach the database.
+			mockDB.AssertExpectations(t)
+			if tt.expected == "" {
+				mockDB.AssertNotCalled(t, "UpdateKYCStatus", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandleKYCWebhookEnqueuesFailedUpdate(t *testing.T) {
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_123", "", "").Return("user123", nil)
+	mockDB.On("UpdateKYCStatus", mock.Anything, "user123", KYCStatusApproved).Return("", errors.New("hasura unavailable"))
+	mockDB.On("EnqueueFailedKYCUpdate", mock.Anything, models.FailedKYCUpdate{
+		UserID:            "user123",
+		KYCStatus:         KYCStatusApproved,
+		TransactionID:     "tx_123",
+		TransactionStatus: "completed",
+		Attempts:          1,
+		LastError:         "hasura unavailable",
+	}).Return(nil)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+	_, err := manager.HandleKYCWebhook(context.Background(), &models.WebhookPayload{TransactionID: "tx_123", Status: "Completed"})
+	require.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleKYCWebhookUsesRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() != nil })
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetUserIDFromTransaction", cancelled, "tx_123", "", "").Return("user123", nil)
+	mockDB.On("UpdateKYCStatus", cancelled, "user123", KYCStatusApproved).Return("", context.Canceled)
+	mockDB.On("EnqueueFailedKYCUpdate", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), mock.Anything).
+		Return(nil)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+	_, err := manager.HandleKYCWebhook(ctx, &models.WebhookPayload{TransactionID: "tx_123", Status: "Completed"})
+	require.ErrorIs(t, err, context.Canceled)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRetryFailedKYCUpdates(t *testing.T) {
+	queuedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	queued := []models.FailedKYCUpdate{
+		{ID: "retry-1", UserID: "user123", KYCStatus: KYCStatusApproved, TransactionStatus: "completed", Attempts: 1, CreatedAt: queuedAt},
+		{ID: "retry-2", UserID: "user456", KYCStatus: KYCStatusRejected, TransactionStatus: "failed", Attempts: 3, CreatedAt: queuedAt},
+		{ID: "retry-3", UserID: "user789", KYCStatus: KYCStatusPending, TransactionStatus: "pending", Attempts: 0, CreatedAt: queuedAt},
+	}
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetFailedKYCUpdates", mock.Anything, kycRetryBatchSize, kycRetryMaxAttempts).Return(queued, nil)
+	mockDB.On("ApplyFailedKYCUpdate", mock.Anything, queued[0]).Return(KYCStatusApproved, true, nil)
+	mockDB.On("ResolveFailedKYCUpdate", mock.Anything, "retry-1").Return(nil)
+	mockDB.On("ApplyFailedKYCUpdate", mock.Anything, queued[1]).Return("", false, errors.New("still down"))
+	mockDB.On("RecordFailedKYCRetry", mock.Anything, "retry-2", "still down").Return(nil)
+	// user789's status changed after retry-3 was queued, so it is dropped, not applied.
+	mockDB.On("ApplyFailedKYCUpdate", mock.Anything, queued[2]).Return("", false, nil)
+	mockDB.On("ResolveFailedKYCUpdate", mock.Anything, "retry-3").Return(nil)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+	retried, err := manager.RetryFailedKYCUpdates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "ResolveFailedKYCUpdate", mock.Anything, "retry-2")
+	mockDB.AssertNotCalled(t, "UpdateKYCStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRetryFailedKYCUpdatesGivesUp(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	update := models.FailedKYCUpdate{ID: "retry-1", UserID: "user123", KYCStatus: KYCStatusApproved, Attempts: kycRetryMaxAttempts - 1}
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetFailedKYCUpdates", mock.Anything, kycRetryBatchSize, kycRetryMaxAttempts).
+		Return([]models.FailedKYCUpdate{update}, nil)
+	mockDB.On("ApplyFailedKYCUpdate", mock.Anything, update).Return("", false, errors.New("user not found"))
+	mockDB.On("RecordFailedKYCRetry", mock.Anything, "retry-1", "user not found").Return(nil)
+	manager := &OnramperManager{Logger: zap.New(core), dbClient: mockDB}
+
+	retried, err := manager.RetryFailedKYCUpdates(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, retried)
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, 1, logs.FilterMessage("KYC update given up; left queued for an operator").Len())
+}
+
+func TestRetryFailedKYCUpdatesLoadError(t *testing.T) {
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetFailedKYCUpdates", mock.Anything, kycRetryBatchSize, kycRetryMaxAttempts).
+		Return([]models.FailedKYCUpdate(nil), errors.New("hasura unavailable"))
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB}
+
+	_, err := manager.RetryFailedKYCUpdates(context.Background())
+	require.Error(t, err)
+
+	_, err = (&OnramperManager{Logger: zap.NewNop()}).RetryFailedKYCUpdates(context.Background())
+	require.Error(t, err)
+}
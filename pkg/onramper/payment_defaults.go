@@ -0,0 +1,57 @@
+package onramper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultPaymentMethods maps lowercased country codes onto the payment method buy quotes
+// and initiations use when the caller does not pick one.
+type DefaultPaymentMethods map[string]string
+
+// ParseDefaultPaymentMethods parses defaults in the form "us=creditcard,ng=mobilemoney".
+// Country codes are lowercased; payment method IDs are kept as given.
+func ParseDefaultPaymentMethods(input string) (defaults DefaultPaymentMethods, err error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, err
+	}
+	defaults = make(DefaultPaymentMethods)
+	for _, pair := range strings.Split(input, ",") {
+		country, paymentMethod, found := strings.Cut(strings.TrimSpace(pair), "=")
+		country, paymentMethod = strings.ToLower(strings.TrimSpace(country)), strings.TrimSpace(paymentMethod)
+		if !found || country == "" || paymentMethod == "" {
+			err = fmt.Errorf("invalid default payment method %q", pair)
+			return nil, err
+		}
+		defaults[country] = paymentMethod
+	}
+	return defaults, err
+}
+
+// WithDefaultPaymentMethods sets the payment method used per country when a buy quote or
+// initiation omits one, instead of leaving the choice to Onramper.
+func WithDefaultPaymentMethods(defaults DefaultPaymentMethods) ManagerOption {
+	return func(h *OnramperManager) {
+		h.DefaultPaymentMethods = defaults
+	}
+}
+
+// paymentMethodOrDefault returns requested, or the default for country when requested is
+// empty and transactionType is a buy. Sells pick a payout method, which the defaults
+// are not meant for.
+func (h *OnramperManager) paymentMethodOrDefault(country, transactionType, requested string) string {
+	if requested != "" || strings.EqualFold(transactionType, string(models.SellTransaction)) {
+		return requested
+	}
+	paymentMethod, ok := h.DefaultPaymentMethods[strings.ToLower(country)]
+	if !ok {
+		return requested
+	}
+	h.Logger.Info("Using default payment method",
+		zap.String("country", country),
+		zap.String("payment_method", paymentMethod))
+	return paymentMethod
+}
@@ -0,0 +1,101 @@
+package onramper
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseDefaultPaymentMethods(t *testing.T) {
+	defaults, err := ParseDefaultPaymentMethods(" US=creditcard, ng=mobilemoney ")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPaymentMethods{"us": "creditcard", "ng": "mobilemoney"}, defaults)
+
+	defaults, err = ParseDefaultPaymentMethods("")
+	require.NoError(t, err)
+	assert.Nil(t, defaults)
+
+	_, err = ParseDefaultPaymentMethods("us=")
+	require.Error(t, err)
+}
+
+func TestGetQuotesDefaultPaymentMethod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name            string
+		query           string
+		expectedMethod  string
+		expectedInjects int
+	}{
+		{name: "injected when omitted", query: "?amount=100&country=US", expectedMethod: "creditcard", expectedInjects: 1},
+		{name: "caller's choice is kept", query: "?amount=100&country=US&paymentMethod=applepay", expectedMethod: "applepay"},
+		{name: "country without a default", query: "?amount=100&country=DE", expectedMethod: ""},
+		{name: "sells are left alone", query: "?amount=100&country=US&type=sell", expectedMethod: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(params *models.QuoteQueryParams) bool {
+				return params.PaymentMethod == tt.expectedMethod
+			})).Return([]models.QuoteResponse{}, nil)
+			core, logs := observer.New(zap.InfoLevel)
+			manager := &OnramperManager{
+				Logger:                zap.New(core),
+				onramperClient:        mockClient,
+				DefaultPaymentMethods: DefaultPaymentMethods{"us": "creditcard"},
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC"+tt.query, nil)
+			c.Params = gin.Params{
+				{Key: "source", Value: "USD"},
+				{Key: "destination", Value: "BTC"},
+			}
+
+			manager.GetQuotes(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			mockClient.AssertExpectations(t)
+			assert.Equal(t, tt.expectedInjects, logs.FilterMessage("Using default payment method").Len())
+		})
+	}
+}
+
+func TestInitiateTransactionDefaultPaymentMethod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var response models.InitiateTransactionResponse
+	response.Message.Status = "in_progress"
+	response.Message.TransactionInformation.TransactionID = "01H9KBT5C21JY0BAX4VTW9EP3V"
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("InitiateTransaction", mock.Anything, mock.MatchedBy(func(payload models.InitiateTransactionRequest) bool {
+		return payload.PaymentMethod == "mobilemoney"
+	})).Return(response, nil)
+	mockDB := new(MockQueryClient)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user_456").Return("user_456", nil)
+	manager := &OnramperManager{
+		Logger:                zap.NewNop(),
+		onramperClient:        mockClient,
+		dbClient:              mockDB,
+		DefaultPaymentMethods: DefaultPaymentMethods{"ng": "mobilemoney"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/checkout/intent?user_id=user_456",
+		bytes.NewBufferString(`{"onramp":"fonbnk","type":"buy","country":"NG","wallet":{"address":"0x123"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	manager.InitiateTransaction(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package onramper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+)
+
+// AmountPrecision maps lowercased currency codes onto the decimals their amounts are
+// rounded to in responses. Amounts are kept at full precision until they are written
+// out, and currencies missing from the map are written unrounded.
+type AmountPrecision map[string]int
+
+// floatNoiseDigits is how many digits past a currency's decimals are taken for float
+// noise and rounded away before an amount is rounded down.
+const floatNoiseDigits = 4
+
+// ParseAmountPrecision parses precisions in the form "btc=8,eth=18,usd=2".
+func ParseAmountPrecision(input string) (precision AmountPrecision, err error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, err
+	}
+	precision = make(AmountPrecision)
+	for _, pair := range strings.Split(input, ",") {
+		currency, rawDecimals, found := strings.Cut(strings.TrimSpace(pair), "=")
+		currency = strings.ToLower(strings.TrimSpace(currency))
+		decimals, convErr := strconv.Atoi(strings.TrimSpace(rawDecimals))
+		if !found || currency == "" || convErr != nil || decimals < 0 || decimals > models.MaxCryptoDecimals {
+			err = fmt.Errorf("invalid amount precision %q", pair)
+			return nil, err
+		}
+		precision[currency] = decimals
+	}
+	return precision, err
+}
+
+// WithAmountPrecision rounds quote and transaction amounts in responses to the decimals
+// of their currency, so float noise such as 0.0020208699999 reads 0.00202087. Payouts
+// and transaction amounts are rounded down, so a response never promises more than is
+// paid out; fees are rounded half up.
+func WithAmountPrecision(precision AmountPrecision) ManagerOption {
+	return func(h *OnramperManager) {
+		h.AmountPrecision = precision
+	}
+}
+
+// roundDown returns amount rounded down to currency's decimals, or amount when none are
+// set. Float noise is rounded away first, so 0.0020208699999 BTC reads 0.00202087 and
+// not 0.00202086.
+func (p AmountPrecision) roundDown(amount models.Amount, currency string) models.Amount {
+	decimals, ok := p[strings.ToLower(currency)]
+	if !ok {
+		return amount
+	}
+	places := int32(decimals)
+	return models.Amount{Decimal: amount.Round(places + floatNoiseDigits).RoundDown(places)}
+}
+
+// roundFee returns the float64 fee amount rounded half up to currency's decimals, or
+// amount when none are set.
+func (p AmountPrecision) roundFee(amount float64, currency string) float64 {
+	decimals, ok := p[strings.ToLower(currency)]
+	if !ok {
+		return amount
+	}
+	return decimal.NewFromFloat(amount).Round(int32(decimals)).InexactFloat64()
+}
+
+// roundQuotes returns copies of quotes with display amounts rounded. Fees are in fiat;
+// the payout is in crypto for buys and in fiat for sells.
+func (p AmountPrecision) roundQuotes(quotes []models.QuoteResponse, fiat, crypto, transactionType string) []models.QuoteResponse {
+	if len(p) == 0 {
+		return quotes
+	}
+	payoutCurrency := crypto
+	if strings.EqualFold(transactionType, string(models.SellTransaction)) {
+		payoutCurrency = fiat
+	}
+	rounded := make([]models.QuoteResponse, len(quotes))
+	for i, quote := range quotes {
+		quote.Payout = p.roundDown(quote.Payout, payoutCurrency)
+		quote.NetworkFee = p.roundFee(quote.NetworkFee, fiat)
+		quote.TransactionFee = p.roundFee(quote.TransactionFee, fiat)
+		rounded[i] = quote
+	}
+	return rounded
+}
+
+// roundTransaction returns tx with its in and out amounts rounded down.
+func (p AmountPrecision) roundTransaction(tx models.TransactionResponse) models.TransactionResponse {
+	tx.InAmount = p.roundDown(tx.InAmount, tx.SourceCurrency)
+	tx.OutAmount = p.roundDown(tx.OutAmount, tx.TargetCurrency)
+	return tx
+}
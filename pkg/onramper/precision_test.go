@@ -0,0 +1,101 @@
+package onramper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestParseAmountPrecision(t *testing.T) {
+	precision, err := ParseAmountPrecision(" BTC=8, usd=2 ")
+	require.NoError(t, err)
+	assert.Equal(t, AmountPrecision{"btc": 8, "usd": 2}, precision)
+
+	precision, err = ParseAmountPrecision("")
+	require.NoError(t, err)
+	assert.Nil(t, precision)
+
+	for _, invalid := range []string{"btc", "btc=", "btc=-1", "btc=eight", "=8"} {
+		_, err = ParseAmountPrecision(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestGetQuotesAmountPrecision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	payout, err := models.NewAmount("0.0020208699999")
+	require.NoError(t, err)
+	fiatPayout, err := models.NewAmount("68.456")
+	require.NoError(t, err)
+	overPayout, err := models.NewAmount("0.002020879")
+	require.NoError(t, err)
+	precision := AmountPrecision{"btc": 8, "usd": 2}
+
+	tests := []struct {
+		name           string
+		source         string
+		destination    string
+		query          string
+		precision      AmountPrecision
+		quote          models.QuoteResponse
+		expectedPayout string
+		expectedFee    float64
+	}{
+		{name: "buy payout rounded to the crypto's decimals", source: "USD", destination: "BTC", query: "?amount=100", precision: precision,
+			quote: models.QuoteResponse{Ramp: "moonpay", Payout: payout, NetworkFee: 1.2345}, expectedPayout: "0.00202087", expectedFee: 1.23},
+		{name: "buy payout rounded down, fee half up", source: "USD", destination: "BTC", query: "?amount=100", precision: precision,
+			quote: models.QuoteResponse{Ramp: "moonpay", Payout: overPayout, NetworkFee: 1.235}, expectedPayout: "0.00202087", expectedFee: 1.24},
+		{name: "sell payout rounded down to the fiat's decimals", source: "BTC", destination: "USD", query: "?amount=0.001&type=sell", precision: precision,
+			quote: models.QuoteResponse{Ramp: "moonpay", Payout: fiatPayout, NetworkFee: 0.456}, expectedPayout: "68.45", expectedFee: 0.46},
+		{name: "no precision keeps full amounts", source: "USD", destination: "BTC", query: "?amount=100",
+			quote: models.QuoteResponse{Ramp: "moonpay", Payout: payout, NetworkFee: 1.2345}, expectedPayout: "0.0020208699999", expectedFee: 1.2345},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := []models.QuoteResponse{tt.quote}
+			mockClient := new(MockOnramperClient)
+			mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).Return(quotes, nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+			WithAmountPrecision(tt.precision)(manager)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/quotes/"+tt.source+"/"+tt.destination+tt.query, nil)
+			c.Params = gin.Params{{Key: "source", Value: tt.source}, {Key: "destination", Value: tt.destination}}
+
+			manager.GetQuotes(c)
+			require.Equal(t, http.StatusOK, w.Code)
+			var got []struct {
+				Payout     json.Number `json:"payout"`
+				NetworkFee float64     `json:"networkFee"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.expectedPayout, got[0].Payout.String())
+			assert.Equal(t, tt.expectedFee, got[0].NetworkFee)
+			assert.Equal(t, "0.0020208699999", payout.String(), "the client's quotes are not modified")
+		})
+	}
+}
+
+func TestTransactionAmountPrecision(t *testing.T) {
+	outAmount, err := models.NewAmount("0.0020208699999")
+	require.NoError(t, err)
+	manager := &OnramperManager{Logger: zap.NewNop(), AmountPrecision: AmountPrecision{"btc": 8}}
+
+	details := manager.withStoredCheckoutDetails(context.Background(), models.TransactionResponse{
+		InAmount: models.NewAmountFromFloat(68.123), SourceCurrency: "usd",
+		OutAmount: outAmount, TargetCurrency: "BTC",
+	})
+	assert.Equal(t, "0.00202087", details.OutAmount.String())
+	assert.Equal(t, "68.123", details.InAmount.String(), "currencies without a precision are unrounded")
+}
@@ -0,0 +1,121 @@
+package onramper
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tokenBuckets is a keyed token-bucket store: each key may make burst requests at once
+// and regains rate tokens per second. Buckets idle long enough to be full again are
+// dropped, as a new bucket behaves the same.
+type tokenBuckets struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newTokenBuckets(rate float64, burst int) *tokenBuckets {
+	return &tokenBuckets{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// take removes a token from key's bucket. When the bucket is empty it returns false
+// and how long until the next token is available.
+func (b *tokenBuckets) take(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now)
+	bucket, found := b.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: b.burst, updated: now}
+		b.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(b.burst, bucket.tokens+elapsed*b.rate)
+		bucket.updated = now
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops the buckets that have refilled completely. It runs at most once per
+// refill period, so its cost is spread over the requests in between.
+func (b *tokenBuckets) sweep(now time.Time) {
+	refill := time.Duration(b.burst / b.rate * float64(time.Second))
+	if now.Sub(b.lastSweep) < refill {
+		return
+	}
+	for key, bucket := range b.buckets {
+		if now.Sub(bucket.updated) >= refill {
+			delete(b.buckets, key)
+		}
+	}
+	b.lastSweep = now
+}
+
+// size returns the number of buckets held.
+func (b *tokenBuckets) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buckets)
+}
+
+// WithPartnerRateLimit limits each partner to rate requests per second with bursts of
+// up to burst, so one tenant cannot use up the shared Onramper quota. Requests without
+// a partner are limited per client IP. A rate or burst of zero disables the limit.
+func WithPartnerRateLimit(rate float64, burst int) ManagerOption {
+	return func(h *OnramperManager) {
+		if rate <= 0 || burst <= 0 {
+			h.rateLimits = nil
+			return
+		}
+		h.rateLimits = newTokenBuckets(rate, burst)
+	}
+}
+
+// limitPartnerRate rejects requests over their partner's rate limit with 429 and a
+// Retry-After in whole seconds. It runs after resolvePartner and keys buckets on the
+// partner it resolved, so only registered partners get a bucket of their own; other
+// requests are limited per client IP. Onramper's webhook deliveries are not limited.
+func (h *OnramperManager) limitPartnerRate(c *gin.Context) {
+	if h.rateLimits == nil || h.isWebhookPath(c.FullPath()) {
+		c.Next()
+		return
+	}
+	partnerID := partnerFor(c)
+	key := "partner:" + partnerID
+	if partnerID == "" {
+		key = "ip:" + c.ClientIP()
+	}
+	ok, retryAfter := h.rateLimits.take(key, h.clock())
+	if !ok {
+		h.Logger.Warn("Partner rate limit exceeded",
+			zap.String("partner_id", partnerID),
+			zap.String("client_ip", c.ClientIP()))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		h.respond(c, http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
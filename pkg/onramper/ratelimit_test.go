@@ -0,0 +1,98 @@
+package onramper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"github.com/subdialia/fiat-ramp-service/pkg/onrampclient/onrampclienttest"
+	"go.uber.org/zap"
+)
+
+func TestPartnerRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	registry := rmp.NewClientRegistry(&onrampclienttest.FakeOnRamperClient{})
+	registry.Register("partnerA", &onrampclienttest.FakeOnRamperClient{})
+	registry.Register("partnerB", &onrampclienttest.FakeOnRamperClient{})
+	manager := &OnramperManager{Logger: zap.NewNop(), clients: registry, now: func() time.Time { return now }}
+	WithPartnerRateLimit(0.5, 2)(manager)
+
+	router := gin.New()
+	router.Use(manager.resolvePartner, manager.limitPartnerRate)
+	router.GET("/supported", func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(partnerID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/supported", nil)
+		if partnerID != "" {
+			req.Header.Set(PartnerHeader, partnerID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("partnerA").Code)
+	assert.Equal(t, http.StatusOK, request("partnerA").Code)
+	limited := request("partnerA")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "2", limited.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Rate limit exceeded"}`, limited.Body.String())
+
+	assert.Equal(t, http.StatusOK, request("partnerB").Code, "partnerA's limit does not affect partnerB")
+	assert.Equal(t, http.StatusOK, request("").Code, "requests without a partner have their own bucket")
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, http.StatusOK, request("partnerA").Code, "a token is regained after 1/rate seconds")
+	assert.Equal(t, http.StatusTooManyRequests, request("partnerA").Code)
+}
+
+func TestPartnerRateLimitWithoutPartner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	manager := &OnramperManager{Logger: zap.NewNop(), now: func() time.Time { return now }}
+	WithPartnerRateLimit(0.5, 1)(manager)
+
+	router := gin.New()
+	router.Use(manager.resolvePartner, manager.limitPartnerRate)
+	router.GET("/supported", func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(remoteAddr, partnerID string) int {
+		req := httptest.NewRequest(http.MethodGet, "/supported", nil)
+		req.RemoteAddr = remoteAddr
+		if partnerID != "" {
+			req.Header.Set(PartnerHeader, partnerID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1234", ""))
+	assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.1:1234", "made-up"),
+		"an unresolved partner header does not buy a fresh bucket")
+	assert.Equal(t, http.StatusOK, request("192.0.2.2:1234", ""), "each client IP has its own bucket")
+	assert.Equal(t, 2, manager.rateLimits.size())
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, http.StatusOK, request("192.0.2.3:1234", ""))
+	assert.Equal(t, 1, manager.rateLimits.size(), "refilled buckets are dropped")
+}
+
+func TestPartnerRateLimitDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &OnramperManager{Logger: zap.NewNop()}
+	WithPartnerRateLimit(0, 0)(manager)
+
+	router := gin.New()
+	router.Use(manager.limitPartnerRate)
+	router.GET("/supported", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/supported", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package onramper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"github.com/subdialia/fiat-ramp-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// DefaultPendingReconcileAge is how long a transaction may stay pending before it is reconciled.
+const DefaultPendingReconcileAge = 15 * time.Minute
+
+// ReconcilePendingTransactions re-fetches stale pending transactions, and sells whose
+// payout is processing, from Onramper and stores any status change that a missed webhook
+// failed to deliver.
+func (h *OnramperManager) ReconcilePendingTransactions(ctx context.Context) (reconciled int, err error) {
+	if h.dbClient == nil {
+		err = errors.New("database client is not configured")
+		return reconciled, err
+	}
+	age := h.PendingReconcileAge
+	if age <= 0 {
+		age = DefaultPendingReconcileAge
+	}
+
+	pending, err := h.dbClient.GetPendingTransactions(ctx, h.clock().Add(-age))
+	if err != nil {
+		err = fmt.Errorf("failed to load pending transactions: %w", err)
+		return reconciled, err
+	}
+	h.Logger.Info("Reconciling pending transactions", zap.Int("count", len(pending)))
+
+	for _, stored := range pending {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("reconciliation cancelled: %w", ctx.Err())
+			return reconciled, err
+		}
+		changed, txErr := h.reconcileTransaction(ctx, stored)
+		if txErr != nil {
+			// One bad transaction must not block the rest of the batch.
+			h.Logger.Error("Failed to reconcile transaction",
+				zap.String("transaction_id", stored.TransactionID),
+				zap.Error(txErr))
+			continue
+		}
+		if changed {
+			reconciled++
+		}
+	}
+
+	h.Logger.Info("Reconciliation finished",
+		zap.Int("pending", len(pending)),
+		zap.Int("reconciled", reconciled))
+	return reconciled, nil
+}
+
+// reconcileTransaction updates a single stored transaction if Onramper reports a new status,
+// asking through the client of the partner that initiated it. A final status also marks
+// the transaction reconciled, so it is never processed again.
+func (h *OnramperManager) reconcileTransaction(ctx context.Context, stored models.FiatTransaction) (changed bool, err error) {
+	if stored.ReconciledAt != nil {
+		h.Logger.Debug("Skipping reconciled transaction", zap.String("transaction_id", stored.TransactionID))
+		return changed, err
+	}
+	client, err := h.partnerClient(stored.PartnerID)
+	if err != nil {
+		return changed, err
+	}
+	tx, err := client.GetTransactionByID(ctx, stored.TransactionID)
+	if err != nil {
+		return changed, err
+	}
+	newStatus := reconciledStatus(stored, tx)
+	if newStatus == stored.Status {
+		return changed, err
+	}
+
+	onrampTx := &models.WebhookPayload{
+		Country:             tx.Country,
+		InAmount:            tx.InAmount,
+		Onramp:              tx.Onramp,
+		OnrampTransactionID: tx.OnrampTransactionID,
+		OutAmount:           tx.OutAmount,
+		PaymentMethod:       tx.PaymentMethod,
+		SourceCurrency:      tx.SourceCurrency,
+		Status:              newStatus,
+		StatusDate:          tx.StatusDate,
+		TargetCurrency:      tx.TargetCurrency,
+		TransactionID:       stored.TransactionID,
+		TransactionType:     strings.ToUpper(tx.TransactionType),
+		TransactionHash:     tx.TransactionHash,
+		WalletAddress:       tx.WalletAddress,
+	}
+	_, err = h.dbClient.UpsertOnramperTransaction(ctx, onrampTx, stored.UserID)
+	if err != nil {
+		return changed, fmt.Errorf("failed to store reconciled status: %w", err)
+	}
+	if models.TransactionStatus(newStatus).IsFinal() {
+		err = h.dbClient.MarkTransactionReconciled(ctx, stored.TransactionID, newStatus)
+		if errors.Is(err, database.ErrAlreadyReconciled) {
+			h.Logger.Info("Transaction already reconciled", zap.String("transaction_id", stored.TransactionID))
+		} else if err != nil {
+			return changed, fmt.Errorf("failed to mark transaction reconciled: %w", err)
+		}
+	}
+	h.Logger.Info("Transaction reconciled",
+		zap.String("transaction_id", stored.TransactionID),
+		zap.String("old_status", stored.Status),
+		zap.String("new_status", newStatus))
+	return true, nil
+}
+
+// reconciledStatus maps the status Onramper reports for tx onto the status to store
+// for stored. A sell whose crypto was received is stored as processing_payout, as its
+// webhook is, and is not moved back to pending while Onramper reports the payout as
+// still processing.
+func reconciledStatus(stored models.FiatTransaction, tx models.TransactionResponse) string {
+	if isSellTransactionType(tx.TransactionType) || isSellTransactionType(stored.TransactionType) {
+		if status := models.SellStatus(tx.Status); status != tx.Status {
+			return status
+		}
+	}
+	status := utils.MapTransactionStatus(tx.Status)
+	if stored.Status == string(models.TransactionStatusProcessingPayout) && status == string(models.TransactionStatusPending) {
+		return stored.Status
+	}
+	return status
+}
+
+// RunReconciler calls ReconcilePendingTransactions and RetryFailedKYCUpdates every
+// interval until ctx is done.
+func (h *OnramperManager) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := h.ReconcilePendingTransactions(ctx)
+			if err != nil {
+				h.Logger.Error("Reconciliation run failed", zap.Error(err))
+			}
+			_, err = h.RetryFailedKYCUpdates(ctx)
+			if err != nil {
+				h.Logger.Error("KYC retry run failed", zap.Error(err))
+			}
+		}
+	}
+}
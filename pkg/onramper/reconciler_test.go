@@ -0,0 +1,138 @@
+package onramper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+func TestReconcilePendingTransactions(t *testing.T) {
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetPendingTransactions", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.FiatTransaction{
+		{UserID: "user123", TransactionID: "tx_completed", Status: "pending"},
+		{UserID: "user456", TransactionID: "tx_still_pending", Status: "pending"},
+	}, nil)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.MatchedBy(func(tx *models.WebhookPayload) bool {
+		return tx.TransactionID == "tx_completed" && tx.Status == "completed"
+	}), "user123").Return("user123", nil).Once()
+	mockDB.On("MarkTransactionReconciled", mock.Anything, "tx_completed", "completed").Return(nil).Once()
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_completed").Return(models.TransactionResponse{
+		TransactionID:   "tx_completed",
+		Status:          "completed",
+		TransactionType: "buy",
+		TransactionHash: "0xabc",
+	}, nil)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_still_pending").Return(models.TransactionResponse{
+		TransactionID: "tx_still_pending",
+		Status:        "pending",
+	}, nil)
+
+	manager := &OnramperManager{
+		Logger:              zap.NewNop(),
+		dbClient:            mockDB,
+		onramperClient:      mockClient,
+		PendingReconcileAge: 10 * time.Minute,
+	}
+
+	reconciled, err := manager.ReconcilePendingTransactions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled)
+	mockDB.AssertExpectations(t)
+	mockClient.AssertExpectations(t)
+}
+
+func TestReconcileSellsAwaitingPayout(t *testing.T) {
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetPendingTransactions", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.FiatTransaction{
+		{UserID: "user123", TransactionID: "tx_paid_out", Status: "processing_payout", TransactionType: "SELL"},
+		{UserID: "user456", TransactionID: "tx_processing", Status: "processing_payout", TransactionType: "SELL"},
+		{UserID: "user789", TransactionID: "tx_received", Status: "processing_payout", TransactionType: "SELL"},
+	}, nil)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.MatchedBy(func(tx *models.WebhookPayload) bool {
+		return tx.TransactionID == "tx_paid_out" && tx.Status == "completed"
+	}), "user123").Return("user123", nil).Once()
+	mockDB.On("MarkTransactionReconciled", mock.Anything, "tx_paid_out", "completed").Return(nil).Once()
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_paid_out").Return(models.TransactionResponse{
+		TransactionID: "tx_paid_out", Status: "completed", TransactionType: "sell",
+	}, nil)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_processing").Return(models.TransactionResponse{
+		TransactionID: "tx_processing", Status: "processing", TransactionType: "sell",
+	}, nil)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_received").Return(models.TransactionResponse{
+		TransactionID: "tx_received", Status: "crypto_received", TransactionType: "sell",
+	}, nil)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient}
+
+	reconciled, err := manager.ReconcilePendingTransactions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled, "only the completed payout changes; the others are still processing")
+	mockDB.AssertExpectations(t)
+	mockClient.AssertExpectations(t)
+}
+
+func TestReconcileSkipsReconciledTransactions(t *testing.T) {
+	reconciledAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetPendingTransactions", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.FiatTransaction{
+		{UserID: "user123", TransactionID: "tx_reconciled", Status: "pending", ReconciledAt: &reconciledAt},
+		{UserID: "user456", TransactionID: "tx_raced", Status: "pending"},
+	}, nil)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user456").Return("user456", nil).Once()
+	mockDB.On("MarkTransactionReconciled", mock.Anything, "tx_raced", "failed").
+		Return(fmt.Errorf("%w: tx_raced", database.ErrAlreadyReconciled)).Once()
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetTransactionByID", mock.Anything, "tx_raced").Return(models.TransactionResponse{
+		TransactionID: "tx_raced",
+		Status:        "failed",
+	}, nil)
+
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient}
+
+	reconciled, err := manager.ReconcilePendingTransactions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled, "a transaction reconciled concurrently still counts as changed")
+	mockClient.AssertNotCalled(t, "GetTransactionByID", mock.Anything, "tx_reconciled")
+	mockDB.AssertExpectations(t)
+}
+
+func TestReconcileUsesInitiatingPartnerClient(t *testing.T) {
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetPendingTransactions", mock.Anything, mock.AnythingOfType("time.Time")).Return([]models.FiatTransaction{
+		{UserID: "user123", TransactionID: "tx_partner", Status: "pending", PartnerID: "acme"},
+		{UserID: "user456", TransactionID: "tx_default", Status: "pending"},
+		{UserID: "user789", TransactionID: "tx_removed_partner", Status: "pending", PartnerID: "gone"},
+	}, nil)
+
+	partnerClient := new(MockOnramperClient)
+	partnerClient.On("GetTransactionByID", mock.Anything, "tx_partner").Return(models.TransactionResponse{
+		TransactionID: "tx_partner", Status: "pending",
+	}, nil).Once()
+	defaultClient := new(MockOnramperClient)
+	defaultClient.On("GetTransactionByID", mock.Anything, "tx_default").Return(models.TransactionResponse{
+		TransactionID: "tx_default", Status: "pending",
+	}, nil).Once()
+	registry := rmp.NewClientRegistry(defaultClient)
+	registry.Register("acme", partnerClient)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: defaultClient}
+	WithClientRegistry(registry)(manager)
+
+	reconciled, err := manager.ReconcilePendingTransactions(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, reconciled)
+	partnerClient.AssertExpectations(t)
+	defaultClient.AssertExpectations(t)
+}
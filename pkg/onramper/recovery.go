@@ -0,0 +1,61 @@
+package onramper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader carries the request ID. A caller-supplied ID is kept so requests can
+// be traced across services; otherwise one is generated. It is echoed on the response.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the request ID stored by assignRequestID, if any.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// assignRequestID stores the request's ID in its context and response headers.
+func assignRequestID(c *gin.Context) {
+	id := strings.TrimSpace(c.GetHeader(RequestIDHeader))
+	if id == "" {
+		id = newRequestID()
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+	c.Writer.Header().Set(RequestIDHeader, id)
+	c.Next()
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// recoverPanic turns a handler panic into a 500 with the standard error body and logs
+// it with the stack trace and request ID. Broken connections are left to gin, which
+// aborts them without a response.
+func (h *OnramperManager) recoverPanic() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		h.Logger.Error("Handler panicked",
+			zap.String("request_id", requestIDFromContext(c.Request.Context())),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("panic", fmt.Sprint(recovered)),
+			zap.ByteString("stack", debug.Stack()),
+		)
+		h.respond(c, http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Abort()
+	})
+}
@@ -0,0 +1,58 @@
+package onramper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.ErrorLevel)
+	manager := &OnramperManager{Logger: zap.New(core)}
+	router := gin.New()
+	router.Use(assignRequestID, manager.recoverPanic())
+	router.GET("/boom", func(c *gin.Context) {
+		var quotes map[string]int
+		quotes["moonpay"]++
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error":"Internal server error"}`, w.Body.String())
+	assert.Equal(t, "req-123", w.Header().Get(RequestIDHeader))
+
+	entries := logs.FilterMessage("Handler panicked").AllUntimed()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "req-123", fields["request_id"])
+	assert.Equal(t, http.MethodGet, fields["method"])
+	assert.Equal(t, "/boom", fields["path"])
+	assert.Contains(t, fields["panic"], "assignment to entry in nil map")
+	assert.Contains(t, fields["stack"], "TestRecoverPanic")
+}
+
+func TestAssignRequestIDGeneratesID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(assignRequestID)
+	var seen string
+	router.GET("/", func(c *gin.Context) {
+		seen = requestIDFromContext(c.Request.Context())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
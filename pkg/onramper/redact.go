@@ -0,0 +1,63 @@
+package onramper
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// sensitiveQueryKeys are query parameters identifying a user or their funds; they are
+// masked before a query is logged. Keys are compared case-insensitively.
+var sensitiveQueryKeys = map[string]bool{
+	"walletaddress": true,
+	"uuid":          true,
+}
+
+// maskValue keeps the first and last four characters of value so log lines can still be
+// correlated, and replaces the rest. Short values are masked entirely.
+func maskValue(value string) string {
+	const keep = 4
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 2*keep {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:keep] + "..." + value[len(value)-keep:]
+}
+
+// redactQuery returns rawQuery with the values of sensitive parameters masked. A query
+// that cannot be parsed is dropped rather than logged as is.
+func redactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "[unparseable]"
+	}
+	for key, vals := range values {
+		if !sensitiveQueryKeys[strings.ToLower(key)] {
+			continue
+		}
+		for i, v := range vals {
+			vals[i] = maskValue(v)
+		}
+	}
+	masked, err := url.QueryUnescape(values.Encode())
+	if err != nil {
+		return values.Encode()
+	}
+	return masked
+}
+
+// rawQueryField logs the request's raw query with sensitive parameters masked.
+func rawQueryField(rawQuery string) zap.Field {
+	return zap.String("query", redactQuery(rawQuery))
+}
+
+// quoteParamsField logs quote parameters with the wallet address and UUID masked.
+func quoteParamsField(params models.QuoteQueryParams) zap.Field {
+	params.WalletAddress = maskValue(params.WalletAddress)
+	params.UUID = maskValue(params.UUID)
+	return zap.Any("params", params)
+}
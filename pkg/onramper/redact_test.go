@@ -0,0 +1,56 @@
+package onramper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGetQuotesMasksSensitiveParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const wallet = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	const uuid = "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).Return([]models.QuoteResponse{}, nil)
+	core, logs := observer.New(zap.InfoLevel)
+	manager := &OnramperManager{Logger: zap.New(core), onramperClient: mockClient}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet,
+		fmt.Sprintf("/quotes/USD/BTC?amount=100&walletAddress=%s&uuid=%s", wallet, uuid), nil)
+	c.Params = gin.Params{
+		{Key: "source", Value: "USD"},
+		{Key: "destination", Value: "BTC"},
+	}
+
+	manager.GetQuotes(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries := logs.FilterMessage("Quote query parameters").AllUntimed()
+	require.Len(t, entries, 1)
+	params, ok := entries[0].ContextMap()["params"].(models.QuoteQueryParams)
+	require.True(t, ok)
+	assert.Equal(t, "0x5a...eAed", params.WalletAddress)
+	assert.Equal(t, "0f8f...950e", params.UUID)
+	for _, entry := range logs.AllUntimed() {
+		assert.NotContains(t, fmt.Sprint(entry.ContextMap()), wallet, entry.Message)
+		assert.NotContains(t, fmt.Sprint(entry.ContextMap()), uuid, entry.Message)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	assert.Equal(t, "amount=100&uuid=0f8f...950e&walletAddress=****",
+		redactQuery("amount=100&walletAddress=abcd&uuid=0f8fad5b-d9cb-469f-a165-70867728950e"))
+	assert.Equal(t, "type=buy", redactQuery("type=buy"))
+}
@@ -0,0 +1,142 @@
+package onramper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JSONCase selects the casing of object keys in API responses.
+type JSONCase string
+
+const (
+	// CaseUnchanged returns payloads as built, mixing Onramper's camelCase with our snake_case wrappers.
+	CaseUnchanged JSONCase = ""
+	// CaseCamel rewrites every object key to camelCase.
+	CaseCamel JSONCase = "camel"
+	// CaseSnake rewrites every object key to snake_case.
+	CaseSnake JSONCase = "snake"
+)
+
+// ParseJSONCase validates a configured casing name.
+func ParseJSONCase(value string) (JSONCase, error) {
+	switch casing := JSONCase(strings.ToLower(strings.TrimSpace(value))); casing {
+	case CaseUnchanged, CaseCamel, CaseSnake:
+		return casing, nil
+	default:
+		return CaseUnchanged, fmt.Errorf("unknown response case %q: want camel or snake", value)
+	}
+}
+
+// respond writes body as JSON using the manager's configured key casing.
+func (h *OnramperManager) respond(c *gin.Context, status int, body interface{}) {
+	if h.ResponseCase == CaseUnchanged {
+		c.JSON(status, body)
+		return
+	}
+	shaped, err := reshapeKeys(body, h.ResponseCase)
+	if err != nil {
+		// Fall back to the unshaped body rather than failing the request.
+		h.Logger.Error("Failed to reshape response keys", zap.Error(err))
+		c.JSON(status, body)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", shaped)
+}
+
+// respondMessage writes a 200 with an Onramper {"message": ...} envelope, or only its
+// message when the manager unwraps envelopes.
+func (h *OnramperManager) respondMessage(c *gin.Context, envelope interface{}, message interface{}) {
+	if h.UnwrapMessage {
+		h.respond(c, http.StatusOK, message)
+		return
+	}
+	h.respond(c, http.StatusOK, envelope)
+}
+
+// reshapeKeys marshals body and rewrites its object keys to casing. Numbers are kept
+// verbatim so decimal amounts do not lose precision. Map keys that are data rather than
+// field names (payment type IDs, country codes) are lowercase in Onramper's API and are
+// left as they are by both conversions.
+func reshapeKeys(body interface{}, casing JSONCase) ([]byte, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	err = decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	convert := toSnakeCase
+	if casing == CaseCamel {
+		convert = toCamelCase
+	}
+	return json.Marshal(rewriteKeys(value, convert))
+}
+
+func rewriteKeys(value interface{}, convert func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[convert(key)] = rewriteKeys(item, convert)
+		}
+		return out
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rewriteKeys(item, convert)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// toSnakeCase converts "paymentTypeId", "TxId" or "redirectURL" to snake_case.
+func toSnakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toCamelCase converts "transaction_id" or "TxId" to camelCase. All-caps keys such as
+// currency codes are left untouched.
+func toCamelCase(key string) string {
+	parts := strings.Split(key, "_")
+	var b strings.Builder
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if i > 0 && b.Len() > 0 {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+		b.WriteString(part)
+	}
+	runes := []rune(b.String())
+	if len(runes) > 1 && unicode.IsUpper(runes[0]) && unicode.IsLower(runes[1]) {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
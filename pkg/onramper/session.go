@@ -0,0 +1,181 @@
+package onramper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+// ErrSessionNotRefreshable is returned when an expired checkout session cannot be
+// initiated again: the transaction is no longer pending, was stored without the request
+// that opened it, or its chain of replacements cannot be followed.
+var ErrSessionNotRefreshable = errors.New("checkout session cannot be refreshed")
+
+// maxSupersededChain bounds how many replacements RefreshSession follows from the
+// transaction it is asked for.
+const maxSupersededChain = 10
+
+// RefreshSession returns the checkout session of transactionID. A session that is still
+// valid is returned as stored. An expired session of a pending transaction is initiated
+// again through client, the Onramper client of the partner that opened it, with the
+// stored request; Onramper opens it as a new transaction, which is stored for the same
+// user and returned in its place. The expired transaction is marked superseded by the
+// new one, and refreshing it later refreshes its replacement. When two refreshes of one
+// session race, the first to mark it superseded wins and both return its session.
+func (h *OnramperManager) RefreshSession(
+	ctx context.Context,
+	client rmp.OnRamperClient,
+	transactionID string,
+) (session models.FiatTransaction, err error) {
+	if h.dbClient == nil {
+		err = errors.New("database client is not configured")
+		return session, err
+	}
+	stored, err := h.latestTransaction(ctx, transactionID)
+	if err != nil {
+		return session, err
+	}
+	if stored.SessionExpiresAt == nil || h.clock().Before(*stored.SessionExpiresAt) {
+		return stored, nil
+	}
+	// Only a checkout still waiting for the user is worth opening again; a paid or final
+	// transaction must not get a second session.
+	if !strings.EqualFold(stored.Status, string(models.TransactionStatusPending)) {
+		err = fmt.Errorf("%w: %s is %s", ErrSessionNotRefreshable, stored.TransactionID, stored.Status)
+		return session, err
+	}
+	if stored.InitiateParams == nil {
+		err = fmt.Errorf("%w: %s was stored without its initiate request", ErrSessionNotRefreshable, stored.TransactionID)
+		return session, err
+	}
+
+	response, err := client.InitiateTransaction(ctx, *stored.InitiateParams)
+	if err != nil {
+		err = fmt.Errorf("failed to initiate transaction: %w", err)
+		return session, err
+	}
+	if response.Message.TransactionInformation.TransactionID == "" {
+		err = errors.New("missing transaction ID in Onramper response")
+		return session, err
+	}
+	onrampTx := initiatedTransaction(*stored.InitiateParams, response)
+	onrampTx.PartnerID = stored.PartnerID
+	_, err = h.dbClient.UpsertOnramperTransaction(ctx, onrampTx, stored.UserID)
+	if err != nil {
+		err = fmt.Errorf("failed to save refreshed transaction: %w", err)
+		return session, err
+	}
+	h.audit(ctx, initiatedAuditEntry(stored.UserID, onrampTx))
+	err = h.dbClient.SupersedeTransaction(ctx, stored.TransactionID, onrampTx.TransactionID)
+	switch {
+	case errors.Is(err, database.ErrAlreadySuperseded):
+		return h.yieldRefresh(ctx, stored.TransactionID, onrampTx.TransactionID)
+	case err != nil:
+		// The new session is usable either way; a row left pending is only polled needlessly.
+		h.Logger.Error("Failed to mark refreshed transaction superseded",
+			zap.String("transaction_id", stored.TransactionID), zap.Error(err))
+	}
+	h.Logger.Info("Checkout session refreshed",
+		zap.String("expired_transaction_id", stored.TransactionID),
+		zap.String("transaction_id", onrampTx.TransactionID),
+		zap.String("user_id", stored.UserID))
+
+	return models.FiatTransaction{
+		UserID:              stored.UserID,
+		TransactionID:       onrampTx.TransactionID,
+		OnrampTransactionID: onrampTx.OnrampTransactionID,
+		Status:              onrampTx.Status,
+		UpdatedAt:           onrampTx.StatusDate,
+		RedirectURL:         onrampTx.RedirectURL,
+		SessionExpiresAt:    onrampTx.SessionExpiresAt,
+		TransactionType:     onrampTx.TransactionType,
+		SourceCurrency:      onrampTx.SourceCurrency,
+		TargetCurrency:      onrampTx.TargetCurrency,
+		Onramp:              onrampTx.Onramp,
+		PaymentMethod:       onrampTx.PaymentMethod,
+		WalletAddress:       onrampTx.WalletAddress,
+		InitiateParams:      onrampTx.InitiateParams,
+		PartnerID:           onrampTx.PartnerID,
+	}, nil
+}
+
+// latestTransaction returns the stored row of transactionID or, once it was superseded,
+// of the transaction that replaced it last. A chain that loops or is longer than
+// maxSupersededChain is not followed.
+func (h *OnramperManager) latestTransaction(ctx context.Context, transactionID string) (models.FiatTransaction, error) {
+	visited := make(map[string]bool)
+	for {
+		if visited[transactionID] {
+			return models.FiatTransaction{}, fmt.Errorf("%w: the replacements of %s loop", ErrSessionNotRefreshable, transactionID)
+		}
+		if len(visited) == maxSupersededChain {
+			return models.FiatTransaction{}, fmt.Errorf("%w: more than %d replacements lead to %s",
+				ErrSessionNotRefreshable, maxSupersededChain, transactionID)
+		}
+		visited[transactionID] = true
+		stored, err := h.dbClient.GetTransactionByID(ctx, transactionID)
+		if err != nil || stored.SupersededBy == "" {
+			return stored, err
+		}
+		transactionID = stored.SupersededBy
+	}
+}
+
+// yieldRefresh returns the session of the refresh that superseded expiredID first and
+// marks the transaction opened by this refresh, lostID, superseded by it, so the
+// session nobody uses is not reconciled as pending.
+func (h *OnramperManager) yieldRefresh(ctx context.Context, expiredID, lostID string) (models.FiatTransaction, error) {
+	winner, err := h.latestTransaction(ctx, expiredID)
+	if err != nil {
+		return winner, err
+	}
+	h.Logger.Info("Checkout session refreshed concurrently",
+		zap.String("expired_transaction_id", expiredID),
+		zap.String("transaction_id", winner.TransactionID),
+		zap.String("discarded_transaction_id", lostID))
+	if err := h.dbClient.SupersedeTransaction(ctx, lostID, winner.TransactionID); err != nil {
+		h.Logger.Error("Failed to mark discarded refresh superseded",
+			zap.String("transaction_id", lostID), zap.Error(err))
+	}
+	return winner, nil
+}
+
+// RefreshCheckoutSession returns a usable checkout URL for a transaction, re-initiating
+// its session if it has expired.
+func (h *OnramperManager) RefreshCheckoutSession(c *gin.Context) {
+	transactionID := c.Param("transaction_id")
+	if transactionID == "" {
+		h.Logger.Error("Missing transaction ID")
+		h.respond(c, http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+		return
+	}
+
+	session, err := h.RefreshSession(c.Request.Context(), h.clientFor(c), transactionID)
+	switch {
+	case errors.Is(err, database.ErrTransactionNotFound):
+		h.respond(c, http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	case errors.Is(err, ErrSessionNotRefreshable):
+		h.Logger.Warn("Checkout session not refreshable", zap.String("transaction_id", transactionID))
+		h.respond(c, http.StatusConflict, gin.H{"error": "Checkout session cannot be refreshed"})
+		return
+	case err != nil:
+		h.Logger.Error("Failed to refresh checkout session", zap.String("transaction_id", transactionID), zap.Error(err))
+		h.respondUpstreamError(c, err, http.StatusInternalServerError, gin.H{"error": "Failed to refresh checkout session"})
+		return
+	}
+	h.respond(c, http.StatusOK, gin.H{
+		"transaction_id":     session.TransactionID,
+		"redirect_url":       session.RedirectURL,
+		"session_expires_at": session.SessionExpiresAt,
+		"refreshed":          session.TransactionID != transactionID,
+	})
+}
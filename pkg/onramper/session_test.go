@@ -0,0 +1,219 @@
+package onramper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+func TestRefreshSession(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	params := &models.InitiateTransactionRequest{
+		Onramp: "moonpay", Source: "usd", Destination: "btc", Amount: 100, Type: "buy", PaymentMethod: "creditcard",
+	}
+	var initiated models.InitiateTransactionResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"message":{"status":"in_progress",
+		"sessionInformation":{"onramp":"moonpay","source":"usd","destination":"btc","amount":100,"type":"buy",
+			"paymentMethod":"creditcard","expiringTime":1714568400},
+		"transactionInformation":{"transactionId":"tx_new","url":"https://buy.moonpay.com/new"}}}`), &initiated))
+
+	t.Run("still valid session is returned as stored", func(t *testing.T) {
+		expiresAt := now.Add(10 * time.Minute)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", RedirectURL: "https://buy.moonpay.com/old",
+			SessionExpiresAt: &expiresAt, InitiateParams: params,
+		}, nil)
+		mockClient := new(MockOnramperClient)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient, now: func() time.Time { return now }}
+
+		session, err := manager.RefreshSession(context.Background(), mockClient, "tx_old")
+		require.NoError(t, err)
+		assert.Equal(t, "tx_old", session.TransactionID)
+		assert.Equal(t, "https://buy.moonpay.com/old", session.RedirectURL)
+		mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+		mockDB.AssertNotCalled(t, "UpsertOnramperTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired session is initiated again", func(t *testing.T) {
+		expiredAt := now.Add(-time.Minute)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", Status: "pending", RedirectURL: "https://buy.moonpay.com/old",
+			SessionExpiresAt: &expiredAt, InitiateParams: params,
+		}, nil)
+		var stored *models.WebhookPayload
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.WebhookPayload)
+		}).Return("user123", nil)
+		mockDB.On("SupersedeTransaction", mock.Anything, "tx_old", "tx_new").Return(nil)
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, *params).Return(initiated, nil)
+		defaultClient := new(MockOnramperClient)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: defaultClient, now: func() time.Time { return now }}
+
+		session, err := manager.RefreshSession(context.Background(), mockClient, "tx_old")
+		require.NoError(t, err)
+		assert.Equal(t, "tx_new", session.TransactionID)
+		assert.Equal(t, "https://buy.moonpay.com/new", session.RedirectURL)
+		require.NotNil(t, session.SessionExpiresAt)
+		assert.True(t, session.SessionExpiresAt.After(now))
+
+		require.NotNil(t, stored)
+		assert.Equal(t, "tx_new", stored.TransactionID)
+		assert.Equal(t, "https://buy.moonpay.com/new", stored.RedirectURL)
+		assert.Equal(t, params, stored.InitiateParams)
+		defaultClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("superseded transaction refreshes its replacement", func(t *testing.T) {
+		expiresAt := now.Add(10 * time.Minute)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", Status: string(models.TransactionStatusSuperseded), SupersededBy: "tx_new",
+		}, nil)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_new").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_new", RedirectURL: "https://buy.moonpay.com/new",
+			SessionExpiresAt: &expiresAt, InitiateParams: params,
+		}, nil)
+		mockClient := new(MockOnramperClient)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient, now: func() time.Time { return now }}
+
+		session, err := manager.RefreshSession(context.Background(), mockClient, "tx_old")
+		require.NoError(t, err)
+		assert.Equal(t, "tx_new", session.TransactionID)
+		assert.Equal(t, "https://buy.moonpay.com/new", session.RedirectURL)
+		mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired session of a settled transaction is not refreshed", func(t *testing.T) {
+		expiredAt := now.Add(-time.Minute)
+		for _, status := range []string{"completed", "FAILED", "canceled", "paid", "processing_payout"} {
+			mockDB := new(MockQueryClient)
+			mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+				UserID: "user123", TransactionID: "tx_old", Status: status, SessionExpiresAt: &expiredAt, InitiateParams: params,
+			}, nil)
+			mockClient := new(MockOnramperClient)
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient, now: func() time.Time { return now }}
+
+			_, err := manager.RefreshSession(context.Background(), mockClient, "tx_old")
+			require.ErrorIs(t, err, ErrSessionNotRefreshable, status)
+			mockClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+			mockDB.AssertNotCalled(t, "SupersedeTransaction", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("looping replacements are not followed", func(t *testing.T) {
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_a").Return(models.FiatTransaction{TransactionID: "tx_a", SupersededBy: "tx_b"}, nil)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_b").Return(models.FiatTransaction{TransactionID: "tx_b", SupersededBy: "tx_a"}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: new(MockOnramperClient), now: func() time.Time { return now }}
+
+		_, err := manager.RefreshSession(context.Background(), new(MockOnramperClient), "tx_a")
+		require.ErrorIs(t, err, ErrSessionNotRefreshable)
+		mockDB.AssertNumberOfCalls(t, "GetTransactionByID", 2)
+	})
+
+	t.Run("long chain of replacements is not followed", func(t *testing.T) {
+		mockDB := new(MockQueryClient)
+		for i := 0; i < 2*maxSupersededChain; i++ {
+			mockDB.On("GetTransactionByID", mock.Anything, fmt.Sprintf("tx_%d", i)).Return(models.FiatTransaction{
+				TransactionID: fmt.Sprintf("tx_%d", i), SupersededBy: fmt.Sprintf("tx_%d", i+1),
+			}, nil)
+		}
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: new(MockOnramperClient), now: func() time.Time { return now }}
+
+		_, err := manager.RefreshSession(context.Background(), new(MockOnramperClient), "tx_0")
+		require.ErrorIs(t, err, ErrSessionNotRefreshable)
+		mockDB.AssertNumberOfCalls(t, "GetTransactionByID", maxSupersededChain)
+	})
+
+	t.Run("refresh that loses a race returns the winner's session", func(t *testing.T) {
+		expiredAt := now.Add(-time.Minute)
+		expiresAt := now.Add(10 * time.Minute)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", Status: "pending", SessionExpiresAt: &expiredAt, InitiateParams: params,
+		}, nil).Once()
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Return("user123", nil)
+		// Another refresh superseded tx_old between the read and this refresh's update.
+		mockDB.On("SupersedeTransaction", mock.Anything, "tx_old", "tx_new").
+			Return(fmt.Errorf("%w: tx_old", database.ErrAlreadySuperseded))
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", Status: string(models.TransactionStatusSuperseded), SupersededBy: "tx_winner",
+		}, nil)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_winner").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_winner", Status: "pending", RedirectURL: "https://buy.moonpay.com/winner",
+			SessionExpiresAt: &expiresAt, InitiateParams: params,
+		}, nil)
+		mockDB.On("SupersedeTransaction", mock.Anything, "tx_new", "tx_winner").Return(nil)
+		mockClient := new(MockOnramperClient)
+		mockClient.On("InitiateTransaction", mock.Anything, *params).Return(initiated, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: mockClient, now: func() time.Time { return now }}
+
+		session, err := manager.RefreshSession(context.Background(), mockClient, "tx_old")
+		require.NoError(t, err)
+		assert.Equal(t, "tx_winner", session.TransactionID)
+		assert.Equal(t, "https://buy.moonpay.com/winner", session.RedirectURL)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("expired session without stored request", func(t *testing.T) {
+		expiredAt := now.Add(-time.Minute)
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+			UserID: "user123", TransactionID: "tx_old", Status: "pending", SessionExpiresAt: &expiredAt,
+		}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: new(MockOnramperClient), now: func() time.Time { return now }}
+
+		_, err := manager.RefreshSession(context.Background(), new(MockOnramperClient), "tx_old")
+		require.ErrorIs(t, err, ErrSessionNotRefreshable)
+	})
+}
+
+func TestRefreshCheckoutSessionUsesPartnerClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiredAt := now.Add(-time.Minute)
+	params := &models.InitiateTransactionRequest{Onramp: "moonpay", Source: "usd", Destination: "btc", Amount: 100, Type: "buy"}
+	var initiated models.InitiateTransactionResponse
+	initiated.Message.TransactionInformation.TransactionID = "tx_new"
+	initiated.Message.TransactionInformation.URL = "https://buy.moonpay.com/new"
+
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetTransactionByID", mock.Anything, "tx_old").Return(models.FiatTransaction{
+		UserID: "user123", TransactionID: "tx_old", Status: "pending", SessionExpiresAt: &expiredAt, InitiateParams: params,
+	}, nil)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Return("user123", nil)
+	mockDB.On("SupersedeTransaction", mock.Anything, "tx_old", "tx_new").Return(nil)
+	partnerClient := new(MockOnramperClient)
+	partnerClient.On("InitiateTransaction", mock.Anything, *params).Return(initiated, nil)
+	defaultClient := new(MockOnramperClient)
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, onramperClient: defaultClient, now: func() time.Time { return now }}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/transactions/tx_old/refresh", nil)
+	c.Params = gin.Params{{Key: "transaction_id", Value: "tx_old"}}
+	c.Set(partnerClientKey, rmp.OnRamperClient(partnerClient))
+
+	manager.RefreshCheckoutSession(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"transaction_id":"tx_new"`)
+	partnerClient.AssertExpectations(t)
+	defaultClient.AssertNotCalled(t, "InitiateTransaction", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,170 @@
+package onramper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+// DefaultQuoteStreamInterval is the pause between quote refreshes on the quote stream.
+const DefaultQuoteStreamInterval = 5 * time.Second
+
+// DefaultQuoteStreamMaxDuration is how long a quote stream runs before it is closed;
+// EventSource clients reconnect on their own.
+const DefaultQuoteStreamMaxDuration = 10 * time.Minute
+
+// streamWriteTimeout is the time a long-lived response gets to produce and write its next
+// event or page before the connection's write deadline passes.
+const streamWriteTimeout = 30 * time.Second
+
+// Server-sent event names used by StreamQuotes.
+const (
+	quoteStreamEvent      = "quotes"
+	quoteStreamErrorEvent = "error"
+)
+
+// WithQuoteStreamInterval sets how often the quote stream refreshes quotes.
+func WithQuoteStreamInterval(interval time.Duration) ManagerOption {
+	return func(h *OnramperManager) {
+		h.QuoteStreamInterval = interval
+	}
+}
+
+// quoteStreamInterval returns the configured refresh interval, or the default.
+func (h *OnramperManager) quoteStreamInterval() time.Duration {
+	if h.QuoteStreamInterval > 0 {
+		return h.QuoteStreamInterval
+	}
+	return DefaultQuoteStreamInterval
+}
+
+// WithQuoteStreamMaxDuration sets how long a quote stream runs before it is closed.
+func WithQuoteStreamMaxDuration(maxDuration time.Duration) ManagerOption {
+	return func(h *OnramperManager) {
+		h.QuoteStreamMaxDuration = maxDuration
+	}
+}
+
+// quoteStreamMaxDuration returns the configured stream lifetime, or the default.
+func (h *OnramperManager) quoteStreamMaxDuration() time.Duration {
+	if h.QuoteStreamMaxDuration > 0 {
+		return h.QuoteStreamMaxDuration
+	}
+	return DefaultQuoteStreamMaxDuration
+}
+
+// StreamQuotes pushes fresh quotes as server-sent events, accepting the same parameters
+// as GetQuotes. The first quotes are fetched before the stream starts, so a request that
+// cannot be quoted gets the error response GetQuotes would send. Each later refresh sends
+// a "quotes" event, or an "error" event when Onramper fails, until the client
+// disconnects, the stream has run for its maximum duration, or a refresh fails in a way
+// that repeating the same request cannot fix.
+func (h *OnramperManager) StreamQuotes(c *gin.Context) {
+	fiat, crypto, queryParams, ok := h.bindQuoteRequest(c)
+	if !ok {
+		return
+	}
+	ctx := rmp.WithClientIP(c.Request.Context(), c.ClientIP())
+	client := h.clientFor(c)
+	interval := h.quoteStreamInterval()
+
+	h.extendWriteDeadline(c, interval)
+	params := queryParams
+	quotes, err := client.GetQuotes(ctx, fiat, crypto, &params)
+	if err != nil {
+		h.Logger.Error("Failed to fetch quotes", zap.Error(err))
+		h.respondQuoteError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lifetime := time.NewTimer(h.quoteStreamMaxDuration())
+	defer lifetime.Stop()
+	for {
+		if err != nil {
+			h.Logger.Error("Failed to refresh streamed quotes", zap.Error(err))
+			h.sendEvent(c, quoteStreamErrorEvent, gin.H{"error": "Failed to fetch quotes"})
+			if permanentQuoteError(err) {
+				h.Logger.Info("Quote stream closed after a permanent error")
+				return
+			}
+		} else {
+			h.sendEvent(c, quoteStreamEvent, h.AmountPrecision.roundQuotes(h.presentQuotes(c, quotes), fiat, crypto, queryParams.Type))
+		}
+
+		select {
+		case <-ctx.Done():
+			h.Logger.Info("Quote stream closed by client")
+			return
+		case <-lifetime.C:
+			h.Logger.Info("Quote stream reached its maximum duration")
+			return
+		case <-ticker.C:
+		}
+		h.extendWriteDeadline(c, interval)
+		params = queryParams
+		quotes, err = client.GetQuotes(ctx, fiat, crypto, &params)
+		if ctx.Err() != nil {
+			h.Logger.Info("Quote stream closed by client")
+			return
+		}
+	}
+}
+
+// permanentQuoteError reports whether err would fail every refresh of the same quote
+// request: the request is invalid, or Onramper rejects it or the API key.
+func permanentQuoteError(err error) bool {
+	for _, permanent := range []error{
+		rmp.ErrUnknownPaymentMethod,
+		rmp.ErrAssetTypeMismatch,
+		rmp.ErrInvalidTransactionType,
+		rmp.ErrInvalidQuoteInput,
+		rmp.ErrUnknownDecimals,
+		rmp.ErrPaymentMethodUnsupported,
+		rmp.ErrQuoteRejected,
+		rmp.ErrUnauthorized,
+	} {
+		if errors.Is(err, permanent) {
+			return true
+		}
+	}
+	return false
+}
+
+// extendWriteDeadline moves the connection's write deadline past the next write, due
+// after wait, so a stream outlives the server's WriteTimeout while it keeps writing.
+func (h *OnramperManager) extendWriteDeadline(c *gin.Context, wait time.Duration) {
+	err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(wait + streamWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.Logger.Warn("Failed to extend write deadline", zap.Error(err))
+	}
+}
+
+// sendEvent writes one server-sent event in the manager's response casing and flushes it.
+func (h *OnramperManager) sendEvent(c *gin.Context, event string, body interface{}) {
+	var (
+		data []byte
+		err  error
+	)
+	if h.ResponseCase == CaseUnchanged {
+		data, err = json.Marshal(body)
+	} else {
+		data, err = reshapeKeys(body, h.ResponseCase)
+	}
+	if err != nil {
+		h.Logger.Error("Failed to encode stream event", zap.Error(err))
+		return
+	}
+	c.SSEvent(event, string(data))
+	c.Writer.Flush()
+}
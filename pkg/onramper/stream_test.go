@@ -0,0 +1,203 @@
+package onramper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
+	"go.uber.org/zap"
+)
+
+// readEvent reads one server-sent event, returning its name and data.
+func readEvent(t *testing.T, reader *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event != "" {
+				return event, data
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimPrefix(line, "data:")
+		}
+	}
+}
+
+func TestStreamQuotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+		Return([]models.QuoteResponse{{Ramp: "moonpay", Rate: 1.5}}, nil).Once()
+	mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+		Return([]models.QuoteResponse(nil), errors.New("upstream down"))
+	manager := &OnramperManager{
+		Logger:              zap.NewNop(),
+		onramperClient:      mockClient,
+		QuoteStreamInterval: 10 * time.Millisecond,
+	}
+
+	router := gin.New()
+	router.GET("/quotes/:source/:destination/stream", manager.StreamQuotes)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/quotes/USD/BTC/stream?amount=100", nil)
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"))
+
+	reader := bufio.NewReader(resp.Body)
+	event, data := readEvent(t, reader)
+	assert.Equal(t, quoteStreamEvent, event)
+	assert.JSONEq(t, `[{"rate":1.5,"networkFee":0,"transactionFee":0,"payout":0,
+		"availablePaymentMethods":null,"ramp":"moonpay","paymentMethod":"","quoteId":"","recommendations":null}]`, data)
+
+	// Upstream failures are reported on the stream without closing it.
+	event, data = readEvent(t, reader)
+	assert.Equal(t, quoteStreamErrorEvent, event)
+	assert.JSONEq(t, `{"error":"Failed to fetch quotes"}`, data)
+
+	// Disconnecting stops the polling loop.
+	cancel()
+	require.Eventually(t, func() bool {
+		calls := len(mockClient.Calls)
+		time.Sleep(30 * time.Millisecond)
+		return len(mockClient.Calls) == calls
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamQuotesOutlivesWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+		Return([]models.QuoteResponse{{Ramp: "moonpay", Rate: 1.5}}, nil)
+	manager := &OnramperManager{
+		Logger:              zap.NewNop(),
+		onramperClient:      mockClient,
+		QuoteStreamInterval: 40 * time.Millisecond,
+	}
+
+	router := gin.New()
+	router.GET("/quotes/:source/:destination/stream", manager.StreamQuotes)
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/quotes/USD/BTC/stream?amount=100")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	start := time.Now()
+	for time.Since(start) < 3*server.Config.WriteTimeout {
+		event, _ := readEvent(t, reader)
+		assert.Equal(t, quoteStreamEvent, event)
+	}
+}
+
+func TestStreamQuotesFirstFailureIsAnHTTPError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockOnramperClient)
+	mockClient.On("GetQuotes", mock.Anything, "BTC", "USD", mock.Anything).
+		Return([]models.QuoteResponse(nil), fmt.Errorf("wrapped: %w", rmp.ErrAssetTypeMismatch)).Once()
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/quotes/BTC/USD/stream?amount=100", nil)
+	c.Params = gin.Params{{Key: "source", Value: "BTC"}, {Key: "destination", Value: "USD"}}
+
+	manager.StreamQuotes(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"fiat and crypto appear to be swapped"}`, w.Body.String())
+	assert.NotContains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	mockClient.AssertExpectations(t)
+}
+
+func TestStreamQuotesStops(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stream := func(t *testing.T, manager *OnramperManager) *bufio.Reader {
+		t.Helper()
+		router := gin.New()
+		router.GET("/quotes/:source/:destination/stream", manager.StreamQuotes)
+		server := httptest.NewServer(router)
+		t.Cleanup(server.Close)
+		resp, err := server.Client().Get(server.URL + "/quotes/USD/BTC/stream?amount=100")
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return bufio.NewReader(resp.Body)
+	}
+	// closed reports whether the server ended the stream after the events read so far.
+	closed := func(reader *bufio.Reader) bool {
+		_, err := io.ReadAll(reader)
+		return err == nil
+	}
+
+	t.Run("after a permanent error", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+			Return([]models.QuoteResponse{{Ramp: "moonpay", Rate: 1.5}}, nil).Once()
+		mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+			Return([]models.QuoteResponse(nil), fmt.Errorf("wrapped: %w", rmp.ErrQuoteRejected)).Once()
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient, QuoteStreamInterval: 10 * time.Millisecond}
+
+		reader := stream(t, manager)
+		event, _ := readEvent(t, reader)
+		assert.Equal(t, quoteStreamEvent, event)
+		event, _ = readEvent(t, reader)
+		assert.Equal(t, quoteStreamErrorEvent, event)
+		assert.True(t, closed(reader))
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("after its maximum duration", func(t *testing.T) {
+		mockClient := new(MockOnramperClient)
+		mockClient.On("GetQuotes", mock.Anything, "USD", "BTC", mock.Anything).
+			Return([]models.QuoteResponse{{Ramp: "moonpay", Rate: 1.5}}, nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: mockClient,
+			QuoteStreamInterval: 10 * time.Millisecond, QuoteStreamMaxDuration: 50 * time.Millisecond}
+
+		start := time.Now()
+		assert.True(t, closed(stream(t, manager)))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestStreamQuotesRejectsInvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &OnramperManager{Logger: zap.NewNop(), onramperClient: new(MockOnramperClient)}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/quotes/USD/BTC/stream", nil)
+	c.Params = gin.Params{{Key: "source", Value: "USD"}, {Key: "destination", Value: "BTC"}}
+
+	manager.StreamQuotes(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -6,7 +6,6 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,10 +13,51 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
 	"go.uber.org/zap"
 )
 
+// DefaultWebhookPath receives Onramper's webhooks unless WithWebhookPaths says otherwise.
+const DefaultWebhookPath = "/webhook/onramper"
+
+// WithWebhookPaths serves WebhookHandler on each of paths instead of DefaultWebhookPath,
+// e.g. to match the path configured in Onramper's dashboard or to keep an old path
+// working while the dashboard moves to a new one. Empty paths are ignored.
+func WithWebhookPaths(paths ...string) ManagerOption {
+	return func(h *OnramperManager) {
+		h.webhookPaths = nil
+		for _, path := range paths {
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+			h.webhookPaths = append(h.webhookPaths, path)
+		}
+	}
+}
+
+// WebhookPaths returns the paths WebhookHandler is served on.
+func (w *OnramperManager) WebhookPaths() []string {
+	if len(w.webhookPaths) == 0 {
+		return []string{DefaultWebhookPath}
+	}
+	return w.webhookPaths
+}
+
+// isWebhookPath reports whether the route path receives Onramper's webhooks.
+func (w *OnramperManager) isWebhookPath(path string) bool {
+	for _, webhookPath := range w.WebhookPaths() {
+		if path == webhookPath {
+			return true
+		}
+	}
+	return false
+}
+
 // WebhookHandler processes incoming webhooks from Onramper.
 func (w *OnramperManager) WebhookHandler(c *gin.Context) {
 	// Read request body
@@ -35,25 +75,127 @@ func (w *OnramperManager) WebhookHandler(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
 		return
 	}
+	// The body is read for signature validation first, but only JSON is accepted
+	if !isJSONRequest(c) {
+		w.Logger.Error("Unsupported webhook content type", zap.String("content_type", c.ContentType()))
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/json"})
+		return
+	}
 	// Parse the webhook payload
-	var payload models.WebhookPayload
-	err = json.Unmarshal(body, &payload)
+	payload, err := models.DecodeWebhookPayload(body)
+	if errors.Is(err, models.ErrUnsupportedWebhookVersion) {
+		w.Logger.Error("Unsupported webhook version", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported webhook version"})
+		return
+	}
 	if err != nil {
-		w.Logger.Error("Failed to store webhook payload in Database", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		w.Logger.Error("Failed to parse webhook payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse webhook data"})
+		return
+	}
+	payload.RawPayload = body
+	w.normalizeSellStatus(&payload)
+	// A redelivered event gets the ack stored the first time, without running the pipeline again
+	eventID := webhookEventID(body)
+	if ack, found := w.storedWebhookAck(c.Request.Context(), eventID); found {
+		w.Logger.Info("Webhook already processed",
+			zap.String("transactionID", payload.TransactionID),
+			zap.String("eventID", eventID))
+		c.JSON(http.StatusOK, webhookAckResponse(ack))
+		return
 	}
+	// Run the processing pipeline (store, KYC, custom processors)
+	ack := &models.WebhookAck{EventID: eventID}
+	err = w.processWebhook(withWebhookAck(c.Request.Context(), ack), &payload)
 	if err != nil {
-		w.Logger.Error("Failed to update KYC status", zap.Error(err))
+		w.Logger.Error("Failed to process webhook",
+			zap.String("transactionID", payload.TransactionID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
 	}
+	w.storeWebhookAck(c.Request.Context(), *ack)
 	// Respond to Onramper
-	c.JSON(http.StatusOK, gin.H{"message": "Webhook received"})
+	c.JSON(http.StatusOK, webhookAckResponse(*ack))
+}
+
+// normalizeSellStatus turns the crypto-received event of a sell into the
+// processing_payout status, so the processors store and map it like any other status.
+func (w *OnramperManager) normalizeSellStatus(payload *models.WebhookPayload) {
+	if !isSellTransactionType(payload.TransactionType) {
+		return
+	}
+	status := models.SellStatus(payload.Status)
+	if status == payload.Status {
+		return
+	}
+	w.Logger.Info("Sell crypto received, payout processing",
+		zap.String("transactionID", payload.TransactionID),
+		zap.String("status", status))
+	payload.Status = status
+}
+
+// isSellTransactionType reports whether a webhook transactionType names a sell, which
+// Onramper sends as "sell" or "offramp".
+func isSellTransactionType(transactionType string) bool {
+	return strings.EqualFold(transactionType, string(models.SellTransaction)) || strings.EqualFold(transactionType, "offramp")
+}
+
+// webhookEventID identifies a webhook delivery by the SHA-256 of its body, which
+// Onramper sends unchanged when it redelivers an event.
+func webhookEventID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// webhookAckResponse is the body acknowledging a processed webhook.
+func webhookAckResponse(ack models.WebhookAck) gin.H {
+	return gin.H{"message": "Webhook received", "userId": ack.UserID, "kycStatus": ack.KYCStatus}
+}
+
+// storedWebhookAck returns the ack stored for eventID. Lookup failures are logged and
+// treated as not found, so the webhook is processed rather than dropped.
+func (w *OnramperManager) storedWebhookAck(ctx context.Context, eventID string) (ack models.WebhookAck, found bool) {
+	if w.dbClient == nil {
+		return ack, false
+	}
+	ack, err := w.dbClient.GetWebhookAck(ctx, eventID)
+	if err != nil {
+		if !errors.Is(err, database.ErrWebhookAckNotFound) {
+			w.Logger.Warn("Failed to look up webhook ack", zap.String("eventID", eventID), zap.Error(err))
+		}
+		return ack, false
+	}
+	return ack, true
 }
 
-// UpdateTransaction saves webhook data in the database.
-func (w *OnramperManager) UpdateTransaction(payload models.WebhookPayload) (returnedUserID string, err error) {
-	ctx := context.Background()
+// storeWebhookAck stores ack for redeliveries. The webhook has been processed either
+// way, so a failure is only logged.
+func (w *OnramperManager) storeWebhookAck(ctx context.Context, ack models.WebhookAck) {
+	if w.dbClient == nil {
+		return
+	}
+	err := w.dbClient.StoreWebhookAck(ctx, ack)
+	if err != nil {
+		w.Logger.Error("Failed to store webhook ack", zap.String("eventID", ack.EventID), zap.Error(err))
+	}
+}
 
-	var userID string
+// UpdateTransaction saves webhook data in the database for the user who owns the transaction.
+func (w *OnramperManager) UpdateTransaction(ctx context.Context, payload models.WebhookPayload) (returnedUserID string, err error) {
+	if w.dbClient == nil {
+		err = errors.New("database client is not configured")
+		return returnedUserID, err
+	}
+	// Resolve the owner of the transaction
+	userID, err := w.dbClient.GetUserIDFromTransaction(ctx,
+		strings.TrimSpace(payload.TransactionID),
+		strings.TrimSpace(payload.OnrampTransactionID),
+		strings.TrimSpace(payload.WalletAddress))
+	if err != nil {
+		err = fmt.Errorf("user resolution failed: %w", err)
+		return returnedUserID, err
+	}
 	// Convert webhook payload struct
 	onrampTx := &models.WebhookPayload{
 		Country:             payload.Country,
@@ -71,13 +213,14 @@ func (w *OnramperManager) UpdateTransaction(payload models.WebhookPayload) (retu
 		TransactionID:       payload.TransactionID,
 		TransactionType:     payload.TransactionType,
 		WalletAddress:       payload.WalletAddress,
+		RawPayload:          payload.RawPayload,
 	}
 	if userID == "" {
 		err = errors.New("user ID is required")
 		return returnedUserID, err
 	}
 	if onrampTx.TransactionID == "" {
-		err = errors.New("user ID is required")
+		err = errors.New("transaction ID is required")
 		return returnedUserID, err
 	}
 	if onrampTx.Status == "" {
@@ -101,7 +244,10 @@ func (w *OnramperManager) UpdateTransaction(payload models.WebhookPayload) (retu
 
 	return returnedUserID, err
 }
-func (w *OnramperManager) HandleKYCWebhook(payload *models.WebhookPayload) (kycStatus string, err error) {
+
+// HandleKYCWebhook maps the payload's transaction status onto its owner's KYC status.
+// The lookups and update run under ctx, normally the webhook request's context.
+func (w *OnramperManager) HandleKYCWebhook(ctx context.Context, payload *models.WebhookPayload) (kycStatus string, err error) {
 	// Validate payload
 	if payload == nil {
 		err = errors.New("webhook payload cannot be nil")
@@ -119,35 +265,28 @@ func (w *OnramperManager) HandleKYCWebhook(payload *models.WebhookPayload) (kycS
 		err = errors.New("transaction identifiers required")
 		return kycStatus, err
 	}
-	// Get context with timeout
-	ctx := context.Background()
+	// Map transaction status to KYC status
+	newStatus, mapped := w.kycStatusFor(rawStatus)
+	if !mapped {
+		if w.SkipUnmappedKYCStatuses {
+			w.Logger.Info("Skipping KYC update for unmapped status", zap.String("status", rawStatus))
+			return kycStatus, err
+		}
+		w.Logger.Warn("Unhandled transaction status", zap.String("status", rawStatus))
+		err = fmt.Errorf("invalid status: %s", rawStatus)
+		return kycStatus, err
+	}
 	// Resolve userID from transaction data
 	userID, err := w.dbClient.GetUserIDFromTransaction(ctx, transactionID, onrampTxID, walletAddress)
 	if err != nil {
 		w.Logger.Error("User resolution failed",
 			zap.String("transactionID", transactionID),
 			zap.String("onrampTxID", onrampTxID),
-			zap.String("walletAddress", walletAddress),
+			zap.String("walletAddress", maskValue(walletAddress)),
 			zap.Error(err))
 		err = fmt.Errorf("user resolution failed: %w", err)
 		return kycStatus, err
 	}
-	// Map transaction status to KYC status
-	var newStatus string
-	switch strings.ToLower(rawStatus) {
-	case "completed":
-		newStatus = "APPROVED"
-	case "failed", "canceled":
-		newStatus = "REJECTED"
-	case "pending":
-		newStatus = "PENDING"
-	default:
-		w.Logger.Warn("Unhandled transaction status",
-			zap.String("status", rawStatus),
-			zap.String("userID", userID))
-		err = fmt.Errorf("invalid status: %s", rawStatus)
-		return kycStatus, err
-	}
 	// Update KYC status via GraphQL
 	resultStatus, err := w.dbClient.UpdateKYCStatus(ctx, userID, newStatus)
 	if err != nil {
@@ -155,9 +294,21 @@ func (w *OnramperManager) HandleKYCWebhook(payload *models.WebhookPayload) (kycS
 			zap.String("userID", userID),
 			zap.String("status", newStatus),
 			zap.Error(err))
+		// Queue the retry even when the update failed because the webhook request was cancelled.
+		w.enqueueFailedKYCUpdate(context.WithoutCancel(ctx), models.FailedKYCUpdate{
+			UserID:            userID,
+			KYCStatus:         newStatus,
+			TransactionID:     transactionID,
+			TransactionStatus: strings.ToLower(rawStatus),
+			Attempts:          1,
+			LastError:         err.Error(),
+		})
 		err = fmt.Errorf("kyc update failed: %w", err)
 		return kycStatus, err
 	}
+	if w.kycMetrics != nil {
+		w.kycMetrics.ObserveTransition(userID, strings.ToLower(rawStatus), resultStatus)
+	}
 	w.Logger.Info("KYC status updated",
 		zap.String("userID", userID),
 		zap.String("originalStatus", rawStatus),
@@ -166,6 +317,26 @@ func (w *OnramperManager) HandleKYCWebhook(payload *models.WebhookPayload) (kycS
 	return resultStatus, err
 }
 
+// ValidateWebhook is an operator debugging tool: it reports whether the raw request body
+// and its X-Onramper-Webhook-Signature header validate against the configured webhook
+// secret, without parsing or storing anything.
+func (w *OnramperManager) ValidateWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		w.Logger.Error("Failed to read webhook body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	signature := c.Request.Header.Get("X-Onramper-Webhook-Signature")
+	if signature == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Onramper-Webhook-Signature header is required"})
+		return
+	}
+	valid := w.ValidateSignature(signature, body, w.WebhookSecret)
+	w.Logger.Info("Webhook signature checked", zap.Bool("valid", valid), zap.Int("body_bytes", len(body)))
+	c.JSON(http.StatusOK, gin.H{"valid": valid})
+}
+
 // ValidateSignature verifies the HMAC signature of the webhook payload.
 func (w *OnramperManager) ValidateSignature(receivedSignature string, payload []byte, secret string) bool {
 	if secret == "" {
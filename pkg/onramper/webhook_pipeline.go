@@ -0,0 +1,103 @@
+package onramper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	"go.uber.org/zap"
+)
+
+// WebhookProcessor is one step of the webhook pipeline. Processors run in order;
+// the first error stops the pipeline and fails the webhook.
+type WebhookProcessor func(ctx context.Context, payload *models.WebhookPayload) error
+
+// DefaultWebhookProcessors returns the built-in pipeline: store the transaction, then update KYC.
+func (w *OnramperManager) DefaultWebhookProcessors() []WebhookProcessor {
+	return []WebhookProcessor{w.storeTransaction, w.updateKYC}
+}
+
+// UseWebhookProcessor appends processors to the pipeline after the ones already registered.
+func (w *OnramperManager) UseWebhookProcessor(processors ...WebhookProcessor) {
+	if w.webhookProcessors == nil {
+		w.webhookProcessors = w.DefaultWebhookProcessors()
+	}
+	w.webhookProcessors = append(w.webhookProcessors, processors...)
+}
+
+// SetWebhookProcessors replaces the whole pipeline, including the default processors.
+func (w *OnramperManager) SetWebhookProcessors(processors ...WebhookProcessor) {
+	w.webhookProcessors = append([]WebhookProcessor{}, processors...)
+}
+
+// webhookAckKey is the context key of the ack the default processors fill in.
+type webhookAckKey struct{}
+
+// withWebhookAck returns ctx carrying ack for the processors of one webhook.
+func withWebhookAck(ctx context.Context, ack *models.WebhookAck) context.Context {
+	return context.WithValue(ctx, webhookAckKey{}, ack)
+}
+
+// webhookAckFromContext returns the ack of the webhook being processed, or nil.
+func webhookAckFromContext(ctx context.Context) *models.WebhookAck {
+	ack, _ := ctx.Value(webhookAckKey{}).(*models.WebhookAck)
+	return ack
+}
+
+// processWebhook runs payload through the registered pipeline. The default processors
+// record the resolved user and KYC status in the ack carried by ctx, if any.
+func (w *OnramperManager) processWebhook(ctx context.Context, payload *models.WebhookPayload) error {
+	processors := w.webhookProcessors
+	if processors == nil {
+		processors = w.DefaultWebhookProcessors()
+	}
+	for i, process := range processors {
+		if ctx.Err() != nil {
+			return fmt.Errorf("webhook processing cancelled: %w", ctx.Err())
+		}
+		err := process(ctx, payload)
+		if err != nil {
+			return fmt.Errorf("webhook processor %d failed: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// storeTransaction is the default processor that upserts the transaction.
+func (w *OnramperManager) storeTransaction(ctx context.Context, payload *models.WebhookPayload) error {
+	userID, err := w.UpdateTransaction(ctx, *payload)
+	if err != nil {
+		return err
+	}
+	if ack := webhookAckFromContext(ctx); ack != nil {
+		ack.UserID = userID
+	}
+	w.audit(ctx, AuditEntry{
+		Action:        AuditActionStatusChange,
+		Actor:         AuditActorWebhook,
+		UserID:        userID,
+		TransactionID: payload.TransactionID,
+		Onramp:        payload.Onramp,
+		Type:          payload.TransactionType,
+		Amount:        payload.InAmount,
+		Currency:      payload.SourceCurrency,
+		Status:        payload.Status,
+	})
+	return nil
+}
+
+// updateKYC is the default processor that maps the transaction status onto the user's KYC status.
+// KYC failures are logged but do not fail the webhook, so Onramper does not redeliver it.
+func (w *OnramperManager) updateKYC(ctx context.Context, payload *models.WebhookPayload) error {
+	kycStatus, err := w.HandleKYCWebhook(ctx, payload)
+	if err != nil {
+		w.Logger.Error("Failed to update KYC status",
+			zap.String("transactionID", payload.TransactionID),
+			zap.Error(err))
+		return nil
+	}
+	if ack := webhookAckFromContext(ctx); ack != nil {
+		ack.KYCStatus = kycStatus
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package onramper
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -12,10 +13,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/subdialia/fiat-ramp-service/pkg/database"
+	"github.com/subdialia/fiat-ramp-service/pkg/metrics"
 	"github.com/subdialia/fiat-ramp-service/pkg/models"
+	rmp "github.com/subdialia/fiat-ramp-service/pkg/onrampclient"
 	"go.uber.org/zap"
 )
 
@@ -229,3 +238,376 @@ func generateHMACSignature(payload, secret string) string {
 	h.Write([]byte(payload))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// MockQueryClient is a mock implementation of database.QueryClient.
+type MockQueryClient struct {
+	mock.Mock
+}
+
+func (m *MockQueryClient) UpsertOnramperTransaction(ctx context.Context, onrampTx *models.WebhookPayload, userID string) (string, error) {
+	args := m.Called(ctx, onrampTx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQueryClient) UpdateKYCStatus(ctx context.Context, userID, transactionStatus string) (string, error) {
+	args := m.Called(ctx, userID, transactionStatus)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQueryClient) GetUserIDFromTransaction(ctx context.Context, transactionID, onrampTxID, walletAddress string) (string, error) {
+	args := m.Called(ctx, transactionID, onrampTxID, walletAddress)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQueryClient) GetPendingTransactions(ctx context.Context, olderThan time.Time) ([]models.FiatTransaction, error) {
+	args := m.Called(ctx, olderThan)
+	return args.Get(0).([]models.FiatTransaction), args.Error(1)
+}
+
+func (m *MockQueryClient) GetTransactionIDByOnrampID(ctx context.Context, onrampTxID string) (string, error) {
+	args := m.Called(ctx, onrampTxID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQueryClient) GetTransactionByID(ctx context.Context, transactionID string) (models.FiatTransaction, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Get(0).(models.FiatTransaction), args.Error(1)
+}
+
+func (m *MockQueryClient) GetTransactionsByUser(ctx context.Context, userID string, limit int) ([]models.FiatTransaction, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]models.FiatTransaction), args.Error(1)
+}
+
+func (m *MockQueryClient) GetTransactionsByWallet(ctx context.Context, walletAddress string) ([]models.FiatTransaction, error) {
+	args := m.Called(ctx, walletAddress)
+	return args.Get(0).([]models.FiatTransaction), args.Error(1)
+}
+
+func (m *MockQueryClient) EnqueueFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) error {
+	args := m.Called(ctx, update)
+	return args.Error(0)
+}
+
+func (m *MockQueryClient) GetFailedKYCUpdates(ctx context.Context, limit, maxAttempts int) ([]models.FailedKYCUpdate, error) {
+	args := m.Called(ctx, limit, maxAttempts)
+	return args.Get(0).([]models.FailedKYCUpdate), args.Error(1)
+}
+
+func (m *MockQueryClient) ApplyFailedKYCUpdate(ctx context.Context, update models.FailedKYCUpdate) (string, bool, error) {
+	args := m.Called(ctx, update)
+	return args.String(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockQueryClient) ResolveFailedKYCUpdate(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQueryClient) RecordFailedKYCRetry(ctx context.Context, id string, lastError string) error {
+	args := m.Called(ctx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *MockQueryClient) MarkTransactionReconciled(ctx context.Context, transactionID string, status string) error {
+	args := m.Called(ctx, transactionID, status)
+	return args.Error(0)
+}
+
+func (m *MockQueryClient) SupersedeTransaction(ctx context.Context, transactionID, supersededBy string) error {
+	args := m.Called(ctx, transactionID, supersededBy)
+	return args.Error(0)
+}
+
+func (m *MockQueryClient) GetWebhookAck(ctx context.Context, eventID string) (models.WebhookAck, error) {
+	args := m.Called(ctx, eventID)
+	return args.Get(0).(models.WebhookAck), args.Error(1)
+}
+
+func (m *MockQueryClient) StoreWebhookAck(ctx context.Context, ack models.WebhookAck) error {
+	args := m.Called(ctx, ack)
+	return args.Error(0)
+}
+
+func TestHandleKYCWebhookMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	kycMetrics := metrics.NewKYCMetrics(registry)
+
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_123", "", "").Return("user123", nil)
+	mockDB.On("UpdateKYCStatus", mock.Anything, "user123", "APPROVED").Return("APPROVED", nil)
+
+	manager := &OnramperManager{
+		Logger:     zap.NewNop(),
+		dbClient:   mockDB,
+		kycMetrics: kycMetrics,
+	}
+
+	status, err := manager.HandleKYCWebhook(context.Background(), &models.WebhookPayload{
+		TransactionID: "tx_123",
+		Status:        "completed",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "APPROVED", status)
+	assert.InDelta(t, 1, testutil.ToFloat64(kycMetrics.Transitions.WithLabelValues("completed", "APPROVED")), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(kycMetrics.PendingVerifications), 0)
+	mockDB.AssertExpectations(t)
+}
+
+func TestWebhookProcessorPipeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"transactionId":"tx_123","status":"failed","onramp":"moonpay"}`
+
+	newRequest := func(body string) (*httptest.ResponseRecorder, *gin.Context) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/webhook/onramper", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("X-Onramper-Webhook-Signature", generateHMACSignature(body, "test-secret"))
+		return w, c
+	}
+
+	t.Run("custom processor runs after defaults", func(t *testing.T) {
+		mockDB := new(MockQueryClient)
+		mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_123", "", "").Return("user123", nil)
+		mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Return("user123", nil)
+		mockDB.On("UpdateKYCStatus", mock.Anything, "user123", "REJECTED").Return("REJECTED", nil)
+		mockDB.On("GetWebhookAck", mock.Anything, mock.Anything).Return(models.WebhookAck{}, database.ErrWebhookAckNotFound)
+		mockDB.On("StoreWebhookAck", mock.Anything, mock.Anything).Return(nil)
+		manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, WebhookSecret: "test-secret"}
+
+		var notified []string
+		manager.UseWebhookProcessor(func(ctx context.Context, payload *models.WebhookPayload) error {
+			if payload.Status == "failed" {
+				notified = append(notified, payload.TransactionID)
+			}
+			return nil
+		})
+
+		w, c := newRequest(body)
+		manager.WebhookHandler(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"tx_123"}, notified)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("processor error fails the webhook and stops the pipeline", func(t *testing.T) {
+		manager := &OnramperManager{Logger: zap.NewNop(), WebhookSecret: "test-secret"}
+		var calls int
+		manager.SetWebhookProcessors(
+			func(ctx context.Context, payload *models.WebhookPayload) error {
+				calls++
+				return errors.New("notification failed")
+			},
+			func(ctx context.Context, payload *models.WebhookPayload) error {
+				calls++
+				return nil
+			},
+		)
+
+		w, c := newRequest(body)
+		manager.WebhookHandler(c)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("processors see the raw payload", func(t *testing.T) {
+		manager := &OnramperManager{Logger: zap.NewNop(), WebhookSecret: "test-secret"}
+		var raw string
+		manager.SetWebhookProcessors(func(ctx context.Context, payload *models.WebhookPayload) error {
+			raw = string(payload.RawPayload)
+			return nil
+		})
+
+		w, c := newRequest(body)
+		manager.WebhookHandler(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, body, raw)
+	})
+
+	t.Run("malformed payload is rejected before processing", func(t *testing.T) {
+		manager := &OnramperManager{Logger: zap.NewNop(), WebhookSecret: "test-secret"}
+		manager.SetWebhookProcessors(func(ctx context.Context, payload *models.WebhookPayload) error {
+			t.Fatal("processor must not run")
+			return nil
+		})
+
+		w, c := newRequest(`{"transactionId":`)
+		manager.WebhookHandler(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestSellCryptoReceivedWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	deliver := func(manager *OnramperManager, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/webhook/onramper", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("X-Onramper-Webhook-Signature", generateHMACSignature(body, "test-secret"))
+		manager.WebhookHandler(c)
+		return w
+	}
+
+	for _, transactionType := range []string{"sell", "offramp"} {
+		t.Run(transactionType+" is stored as processing payout", func(t *testing.T) {
+			body := `{"transactionId":"tx_sell","status":"crypto_received","transactionType":"` + transactionType +
+				`","onramp":"moonpay","inAmount":0.001,"sourceCurrency":"btc","targetCurrency":"usd"}`
+			mockDB := new(MockQueryClient)
+			mockDB.On("GetWebhookAck", mock.Anything, mock.Anything).Return(models.WebhookAck{}, database.ErrWebhookAckNotFound)
+			mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_sell", "", "").Return("user123", nil)
+			var stored *models.WebhookPayload
+			mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Run(func(args mock.Arguments) {
+				stored = args.Get(1).(*models.WebhookPayload)
+			}).Return("user123", nil)
+			mockDB.On("UpdateKYCStatus", mock.Anything, "user123", KYCStatusPending).Return(KYCStatusPending, nil)
+			mockDB.On("StoreWebhookAck", mock.Anything, mock.Anything).Return(nil)
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, WebhookSecret: "test-secret"}
+
+			w := deliver(manager, body)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"message":"Webhook received","userId":"user123","kycStatus":"PENDING"}`, w.Body.String())
+			require.NotNil(t, stored)
+			assert.Equal(t, string(models.TransactionStatusProcessingPayout), stored.Status)
+			assert.Equal(t, body, string(stored.RawPayload), "the raw payload keeps the original event")
+			mockDB.AssertExpectations(t)
+		})
+	}
+
+	t.Run("buy keeps its status", func(t *testing.T) {
+		manager := &OnramperManager{Logger: zap.NewNop(), WebhookSecret: "test-secret"}
+		var status string
+		manager.SetWebhookProcessors(func(ctx context.Context, payload *models.WebhookPayload) error {
+			status = payload.Status
+			return nil
+		})
+
+		w := deliver(manager, `{"transactionId":"tx_buy","status":"crypto_received","transactionType":"buy"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "crypto_received", status)
+	})
+}
+
+func TestWebhookRedeliveryReturnsStoredAck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"transactionId":"tx_123","status":"completed","onramp":"moonpay"}`
+	deliver := func(manager *OnramperManager) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/webhook/onramper", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("X-Onramper-Webhook-Signature", generateHMACSignature(body, "test-secret"))
+		manager.WebhookHandler(c)
+		return w
+	}
+
+	mockDB := new(MockQueryClient)
+	mockDB.On("GetUserIDFromTransaction", mock.Anything, "tx_123", "", "").Return("user123", nil)
+	mockDB.On("UpsertOnramperTransaction", mock.Anything, mock.Anything, "user123").Return("user123", nil).Once()
+	mockDB.On("UpdateKYCStatus", mock.Anything, "user123", "APPROVED").Return("APPROVED", nil).Once()
+	var stored models.WebhookAck
+	mockDB.On("StoreWebhookAck", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.WebhookAck)
+	}).Return(nil).Once()
+	mockDB.On("GetWebhookAck", mock.Anything, mock.Anything).Return(models.WebhookAck{}, database.ErrWebhookAckNotFound).Once()
+	manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, WebhookSecret: "test-secret"}
+
+	first := deliver(manager)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `{"message":"Webhook received","userId":"user123","kycStatus":"APPROVED"}`, first.Body.String())
+	assert.Equal(t, "user123", stored.UserID)
+	assert.Equal(t, "APPROVED", stored.KYCStatus)
+	require.NotEmpty(t, stored.EventID)
+
+	mockDB.On("GetWebhookAck", mock.Anything, stored.EventID).Return(stored, nil).Once()
+	second := deliver(manager)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.JSONEq(t, first.Body.String(), second.Body.String())
+	mockDB.AssertNumberOfCalls(t, "UpsertOnramperTransaction", 1)
+	mockDB.AssertNumberOfCalls(t, "UpdateKYCStatus", 1)
+	mockDB.AssertNumberOfCalls(t, "StoreWebhookAck", 1)
+}
+
+func TestWebhookContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"transactionId":"tx_123","status":"completed"}`
+	tests := []struct {
+		name           string
+		contentType    string
+		signature      string
+		expectedStatus int
+	}{
+		{name: "form encoded", contentType: "application/x-www-form-urlencoded", signature: generateHMACSignature(body, "test-secret"), expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "missing content type", signature: generateHMACSignature(body, "test-secret"), expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "bad signature checked first", contentType: "text/plain", signature: "invalid", expectedStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockQueryClient)
+			manager := &OnramperManager{Logger: zap.NewNop(), dbClient: mockDB, WebhookSecret: "test-secret"}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/webhook/onramper", bytes.NewBufferString(body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+			c.Request.Header.Set("X-Onramper-Webhook-Signature", tt.signature)
+
+			manager.WebhookHandler(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockDB.AssertNotCalled(t, "GetUserIDFromTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestValidateWebhookEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"transactionId":"tx_123","status":"completed"}`
+	client, ok := rmp.NewClient("https://api.onramper.com", "test-key", "test-secret", zap.NewNop()).(*rmp.Client)
+	require.True(t, ok)
+
+	tests := []struct {
+		name           string
+		operatorToken  string
+		authorization  string
+		signature      string
+		expectedStatus int
+		expectedValid  string
+	}{
+		{name: "matching signature", operatorToken: "op-token", authorization: "Bearer op-token",
+			signature: generateHMACSignature(body, "test-secret"), expectedStatus: http.StatusOK, expectedValid: "true"},
+		{name: "signature from another secret", operatorToken: "op-token", authorization: "Bearer op-token",
+			signature: generateHMACSignature(body, "old-secret"), expectedStatus: http.StatusOK, expectedValid: "false"},
+		{name: "missing signature", operatorToken: "op-token", authorization: "Bearer op-token",
+			expectedStatus: http.StatusBadRequest},
+		{name: "wrong operator token", operatorToken: "op-token", authorization: "Bearer nope",
+			signature: generateHMACSignature(body, "test-secret"), expectedStatus: http.StatusUnauthorized},
+		{name: "disabled without operator token", authorization: "Bearer ",
+			signature: generateHMACSignature(body, "test-secret"), expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := SetupRouter(client, nil, "test-secret", WithOperatorToken(tt.operatorToken))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/validate", bytes.NewBufferString(body))
+			req.Header.Set("Authorization", tt.authorization)
+			if tt.signature != "" {
+				req.Header.Set("X-Onramper-Webhook-Signature", tt.signature)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedValid != "" {
+				assert.JSONEq(t, `{"valid":`+tt.expectedValid+`}`, w.Body.String())
+			}
+		})
+	}
+}
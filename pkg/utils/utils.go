@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -35,6 +37,25 @@ func ParseBoolOrDefault(logger *zap.Logger, input string, defaultVal bool) bool
 	return parsed
 }
 
+// ParseFloatOrDefault parses input as a float64, returning defaultVal when it is empty or invalid.
+func ParseFloatOrDefault(logger *zap.Logger, input string, defaultVal float64) float64 {
+	if input == "" {
+		return defaultVal
+	}
+
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		if logger != nil {
+			logger.Warn("Invalid float parameter; defaulting to defaultVal",
+				zap.String("paramValue", input),
+				zap.Error(err),
+			)
+		}
+		return defaultVal
+	}
+	return parsed
+}
+
 func MapTransactionStatus(input string) string {
 	input = strings.ToLower(input)
 	switch {
@@ -54,3 +75,11 @@ func MapTransactionStatus(input string) string {
 		return "new" // Fallback to default
 	}
 }
+
+//nolint:gochecknoglobals // Compiled once and reused for every color check.
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// IsHexColor reports whether input is a 3, 6 or 8 digit hex color, with or without a leading '#'.
+func IsHexColor(input string) bool {
+	return hexColorPattern.MatchString(input)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseFloatOrDefault(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		defaultVal float64
+		expected   float64
+	}{
+		{name: "empty uses default", input: "", defaultVal: 50, expected: 50},
+		{name: "integer", input: "100", defaultVal: 0, expected: 100},
+		{name: "decimal", input: "0.00202087", defaultVal: 0, expected: 0.00202087},
+		{name: "surrounding spaces", input: " 25.5 ", defaultVal: 0, expected: 25.5},
+		{name: "invalid uses default", input: "abc", defaultVal: 10, expected: 10},
+		{name: "trailing garbage uses default", input: "100usd", defaultVal: 10, expected: 10},
+		{name: "NaN uses default", input: "NaN", defaultVal: 1, expected: 1},
+		{name: "infinity uses default", input: "Inf", defaultVal: 1, expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, ParseFloatOrDefault(zap.NewNop(), tt.input, tt.defaultVal), 1e-12)
+		})
+	}
+}
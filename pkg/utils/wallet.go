@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Address families with checksum validation.
+const (
+	WalletFamilyEVM     = "evm"
+	WalletFamilyBitcoin = "bitcoin"
+)
+
+//nolint:gochecknoglobals // read-only lookup tables and compiled patterns.
+var (
+	evmNetworks = map[string]bool{
+		"ethereum": true, "polygon": true, "bsc": true, "arbitrum": true, "optimism": true,
+		"base": true, "avaxc": true, "avalanche": true, "fantom": true, "linea": true,
+		"zksync": true, "celo": true, "gnosis": true, "scroll": true, "mantle": true,
+	}
+	bitcoinNetworks = map[string]bool{"bitcoin": true, "btc": true}
+
+	evmAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// WalletFamily returns the address family for a network name or Onramper crypto ID
+// ("usdc_polygon", "btc"), or "" when addresses on it are not validated.
+func WalletFamily(network, cryptoID string) string {
+	candidates := []string{strings.ToLower(strings.TrimSpace(network))}
+	cryptoID = strings.ToLower(strings.TrimSpace(cryptoID))
+	if i := strings.LastIndex(cryptoID, "_"); i >= 0 {
+		candidates = append(candidates, cryptoID[i+1:])
+	} else if cryptoID == "eth" {
+		candidates = append(candidates, "ethereum")
+	} else {
+		candidates = append(candidates, cryptoID)
+	}
+	for _, candidate := range candidates {
+		switch {
+		case evmNetworks[candidate]:
+			return WalletFamilyEVM
+		case bitcoinNetworks[candidate]:
+			return WalletFamilyBitcoin
+		}
+	}
+	return ""
+}
+
+// ValidateWalletAddress checks address against the checksum rules of its network.
+// Addresses on networks without a known family are only checked for being non-empty.
+func ValidateWalletAddress(network, cryptoID, address string) error {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return errors.New("wallet address required")
+	}
+	switch WalletFamily(network, cryptoID) {
+	case WalletFamilyEVM:
+		return validateEVMAddress(address)
+	case WalletFamilyBitcoin:
+		return validateBitcoinAddress(address)
+	default:
+		return nil
+	}
+}
+
+// validateEVMAddress enforces the EIP-55 checksum on mixed-case addresses.
+// All-lowercase and all-uppercase addresses carry no checksum and are accepted.
+func validateEVMAddress(address string) error {
+	if !evmAddressPattern.MatchString(address) {
+		return fmt.Errorf("invalid EVM address %q: want 0x followed by 40 hex characters", address)
+	}
+	hexPart := address[2:]
+	if hexPart == strings.ToLower(hexPart) || hexPart == strings.ToUpper(hexPart) {
+		return nil
+	}
+	if address != toChecksumAddress(hexPart) {
+		return fmt.Errorf("invalid EIP-55 checksum for EVM address %q", address)
+	}
+	return nil
+}
+
+// toChecksumAddress returns the EIP-55 form of a 40 character hex address.
+func toChecksumAddress(hexPart string) string {
+	lower := strings.ToLower(hexPart)
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(lower))
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// validateBitcoinAddress checks bech32/bech32m (bc1, tb1) or base58check (1, 3, m, n, 2) addresses.
+func validateBitcoinAddress(address string) error {
+	lower := strings.ToLower(address)
+	if strings.HasPrefix(lower, "bc1") || strings.HasPrefix(lower, "tb1") {
+		return validateBech32Address(address)
+	}
+	return validateBase58CheckAddress(address)
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// validateBech32Address verifies a segwit address checksum (BIP-173 for v0, BIP-350 for v1+).
+func validateBech32Address(address string) error {
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return fmt.Errorf("invalid bech32 address %q: mixed case", address)
+	}
+	address = strings.ToLower(address)
+	sep := strings.LastIndexByte(address, '1')
+	if sep < 1 || sep+7 > len(address) || len(address) > 90 {
+		return fmt.Errorf("invalid bech32 address %q: bad length", address)
+	}
+	hrp, data := address[:sep], address[sep+1:]
+	values := make([]int, len(data))
+	for i := range data {
+		v := strings.IndexByte(bech32Charset, data[i])
+		if v < 0 {
+			return fmt.Errorf("invalid bech32 address %q: bad character %q", address, data[i])
+		}
+		values[i] = v
+	}
+	want := bech32Const
+	if values[0] > 0 {
+		want = bech32mConst
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != want {
+		return fmt.Errorf("invalid bech32 checksum for bitcoin address %q", address)
+	}
+	return nil
+}
+
+func bech32Polymod(values []int) int {
+	generator := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := 1
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ v
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []int {
+	out := make([]int, 0, len(hrp)*2+1)
+	for i := range hrp {
+		out = append(out, int(hrp[i]>>5))
+	}
+	out = append(out, 0)
+	for i := range hrp {
+		out = append(out, int(hrp[i]&31))
+	}
+	return out
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// validateBase58CheckAddress verifies the double-SHA256 checksum of a legacy address.
+func validateBase58CheckAddress(address string) error {
+	if len(address) < 26 || len(address) > 35 {
+		return fmt.Errorf("invalid bitcoin address %q: bad length", address)
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := range address {
+		v := strings.IndexByte(base58Alphabet, address[i])
+		if v < 0 {
+			return fmt.Errorf("invalid bitcoin address %q: bad character %q", address, address[i])
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+	decoded := n.Bytes()
+	// Leading '1' characters encode leading zero bytes.
+	for i := 0; i < len(address) && address[i] == '1'; i++ {
+		decoded = append([]byte{0}, decoded...)
+	}
+	if len(decoded) != 25 {
+		return fmt.Errorf("invalid bitcoin address %q: bad payload length", address)
+	}
+	first := sha256.Sum256(decoded[:21])
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], decoded[21:]) {
+		return fmt.Errorf("invalid base58 checksum for bitcoin address %q", address)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWalletAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		network  string
+		cryptoID string
+		address  string
+		wantErr  string
+	}{
+		{name: "valid EIP-55", network: "ethereum", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{name: "valid EIP-55 from crypto id", cryptoID: "usdc_polygon", address: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+		{name: "all lowercase has no checksum", network: "ethereum", address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{name: "bad EIP-55 checksum", network: "ethereum", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", wantErr: "EIP-55 checksum"},
+		{name: "bad EVM length", cryptoID: "eth", address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", wantErr: "40 hex characters"},
+		{name: "valid bech32", cryptoID: "btc", address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{name: "valid bech32m taproot", network: "bitcoin", address: "bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297"},
+		{name: "bad bech32 checksum", cryptoID: "btc", address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", wantErr: "bech32 checksum"},
+		{name: "valid legacy", cryptoID: "btc", address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"},
+		{name: "bad legacy checksum", cryptoID: "btc", address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", wantErr: "base58 checksum"},
+		{name: "unknown network only needs a value", network: "solana", address: "7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV"},
+		{name: "empty address", network: "ethereum", address: "", wantErr: "wallet address required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWalletAddress(tt.network, tt.cryptoID, tt.address)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}